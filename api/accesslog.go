@@ -0,0 +1,214 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chlovec/rest-pack/utils"
+)
+
+// AccessLogFormat is the line format written by the AccessLog middleware.
+type AccessLogFormat string
+
+const (
+	// AccessLogCommon is the Apache Common Log Format.
+	AccessLogCommon AccessLogFormat = "common"
+	// AccessLogCombined is the Apache Combined Log Format, which adds the
+	// referer and user agent to the Common Log Format.
+	AccessLogCombined AccessLogFormat = "combined"
+	// AccessLogJSON writes one JSON object per request.
+	AccessLogJSON AccessLogFormat = "json"
+)
+
+const clfTimeFormat = "02/Jan/2006:15:04:05 -0700"
+
+type AccessLogOption func(*accessLog)
+
+// WithAccessLogFormat sets the line format. It defaults to AccessLogCombined.
+func WithAccessLogFormat(format AccessLogFormat) AccessLogOption {
+	return func(l *accessLog) {
+		l.format = format
+	}
+}
+
+// WithAccessLogSampling logs only the given fraction of requests, between 0
+// and 1. Requests that end in a server error are always logged. It defaults
+// to 1, which logs every request.
+func WithAccessLogSampling(rate float64) AccessLogOption {
+	return func(l *accessLog) {
+		l.sampleRate = rate
+	}
+}
+
+// WithAccessLogExclude skips requests for the given paths, such as health
+// checks. Paths are compared with the request path exactly.
+func WithAccessLogExclude(paths ...string) AccessLogOption {
+	return func(l *accessLog) {
+		for _, path := range paths {
+			l.excluded[path] = struct{}{}
+		}
+	}
+}
+
+type accessLog struct {
+	mu         sync.Mutex
+	w          io.Writer
+	format     AccessLogFormat
+	sampleRate float64
+	excluded   map[string]struct{}
+	now        func() time.Time
+}
+
+// accessLogEntry is what the AccessLog middleware records for one request.
+// It is also the shape of AccessLogJSON lines.
+type accessLogEntry struct {
+	Time       string  `json:"time"`
+	RemoteAddr string  `json:"remoteAddr"`
+	Method     string  `json:"method"`
+	Path       string  `json:"path"`
+	Route      string  `json:"route,omitempty"`
+	Proto      string  `json:"proto"`
+	Status     int     `json:"status"`
+	Bytes      int64   `json:"bytes"`
+	LatencyMs  float64 `json:"latencyMs"`
+	Referer    string  `json:"referer,omitempty"`
+	UserAgent  string  `json:"userAgent,omitempty"`
+	RequestID  string  `json:"requestId,omitempty"`
+
+	start time.Time
+	user  string
+	uri   string
+}
+
+// AccessLog returns middleware that writes a line to w for every request it
+// wraps, recording the method, route template, status, bytes written,
+// latency, remote address and user agent. Used with APIServer.Use it also
+// sees requests that match no route, which are logged without a route.
+func AccessLog(w io.Writer, opts ...AccessLogOption) Middleware {
+	l := &accessLog{
+		w:          w,
+		format:     AccessLogCombined,
+		sampleRate: 1,
+		excluded:   make(map[string]struct{}),
+		now:        time.Now,
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, ok := l.excluded[r.URL.Path]; ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			start := l.now()
+			rw := newResponseWriter(w)
+			next.ServeHTTP(rw, r)
+
+			if rw.status < http.StatusInternalServerError && !l.sampled() {
+				return
+			}
+			l.write(newAccessLogEntry(r, rw, start, l.now()))
+		})
+	}
+}
+
+func (l *accessLog) sampled() bool {
+	return l.sampleRate >= 1 || (l.sampleRate > 0 && rand.Float64() < l.sampleRate)
+}
+
+func newAccessLogEntry(r *http.Request, rw *responseWriter, start, end time.Time) *accessLogEntry {
+	remoteAddr := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(remoteAddr); err == nil {
+		remoteAddr = host
+	}
+
+	user := ""
+	if r.URL.User != nil {
+		user = r.URL.User.Username()
+	} else if username, _, ok := r.BasicAuth(); ok {
+		user = username
+	}
+
+	uri := r.RequestURI
+	if uri == "" {
+		uri = r.URL.RequestURI()
+	}
+
+	return &accessLogEntry{
+		RemoteAddr: remoteAddr,
+		Method:     r.Method,
+		Path:       r.URL.Path,
+		Route:      RouteTemplate(r),
+		Proto:      r.Proto,
+		Status:     rw.status,
+		Bytes:      rw.bytes,
+		LatencyMs:  float64(end.Sub(start).Microseconds()) / 1000,
+		Referer:    r.Referer(),
+		UserAgent:  r.UserAgent(),
+		RequestID:  utils.RequestIDFromContext(r.Context()),
+		start:      start,
+		user:       user,
+		uri:        uri,
+	}
+}
+
+func (l *accessLog) write(entry *accessLogEntry) {
+	var line []byte
+	switch l.format {
+	case AccessLogJSON:
+		entry.Time = entry.start.Format(time.RFC3339Nano)
+		var err error
+		if line, err = json.Marshal(entry); err != nil {
+			return
+		}
+	case AccessLogCommon:
+		line = []byte(entry.common())
+	default:
+		line = []byte(entry.common() + " " + quote(entry.Referer) + " " + quote(entry.UserAgent))
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.w.Write(line)
+}
+
+// common formats the entry in the Common Log Format:
+// host ident authuser [date] "request" status bytes
+func (e *accessLogEntry) common() string {
+	bytes := "-"
+	if e.Bytes > 0 {
+		bytes = strconv.FormatInt(e.Bytes, 10)
+	}
+
+	return strings.Join([]string{
+		dash(e.RemoteAddr),
+		"-",
+		dash(e.user),
+		"[" + e.start.Format(clfTimeFormat) + "]",
+		strconv.Quote(e.Method + " " + e.uri + " " + e.Proto),
+		strconv.Itoa(e.Status),
+		bytes,
+	}, " ")
+}
+
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func quote(s string) string {
+	return strconv.Quote(dash(s))
+}
@@ -0,0 +1,135 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func fixedClock(times ...time.Time) func() time.Time {
+	return func() time.Time {
+		t := times[0]
+		if len(times) > 1 {
+			times = times[1:]
+		}
+		return t
+	}
+}
+
+func newAccessLogTestServer(logBuffer *bytes.Buffer, opts ...AccessLogOption) *APIServer {
+	logger, _ := initLog()
+	server := NewAPIServer(":8080", "/api", logger)
+
+	start := time.Date(2024, time.March, 5, 13, 55, 36, 0, time.UTC)
+	opts = append(opts, func(l *accessLog) {
+		l.now = fixedClock(start, start.Add(1500*time.Microsecond))
+	})
+	server.Use(RequestID(), AccessLog(logBuffer, opts...))
+	server.RegisterRoute("/products/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("hello"))
+	}, http.MethodGet)
+	server.RegisterRoute("/fail", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}, http.MethodGet)
+	return server
+}
+
+func newAccessLogRequest(path string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.RemoteAddr = "192.0.2.10:51234"
+	req.Header.Set("User-Agent", "test-agent/1.0")
+	req.Header.Set("Referer", "https://example.com/")
+	req.Header.Set("X-Request-ID", "req-1")
+	return req
+}
+
+func TestAccessLog(t *testing.T) {
+	t.Run("should write the combined log format by default", func(t *testing.T) {
+		var logBuffer bytes.Buffer
+		server := newAccessLogTestServer(&logBuffer)
+
+		server.ServeHTTP(httptest.NewRecorder(), newAccessLogRequest("/api/products/1?full=true"))
+
+		assert.Equal(t,
+			`192.0.2.10 - - [05/Mar/2024:13:55:36 +0000] "GET /api/products/1?full=true HTTP/1.1" 200 5 "https://example.com/" "test-agent/1.0"`+"\n",
+			logBuffer.String())
+	})
+
+	t.Run("should write the common log format", func(t *testing.T) {
+		var logBuffer bytes.Buffer
+		server := newAccessLogTestServer(&logBuffer, WithAccessLogFormat(AccessLogCommon))
+
+		req := newAccessLogRequest("/api/missing")
+		req.SetBasicAuth("alice", "secret")
+		server.ServeHTTP(httptest.NewRecorder(), req)
+
+		assert.Equal(t,
+			`192.0.2.10 - alice [05/Mar/2024:13:55:36 +0000] "GET /api/missing HTTP/1.1" 404 19`+"\n",
+			logBuffer.String())
+	})
+
+	t.Run("should write JSON with the route template", func(t *testing.T) {
+		var logBuffer bytes.Buffer
+		server := newAccessLogTestServer(&logBuffer, WithAccessLogFormat(AccessLogJSON))
+
+		server.ServeHTTP(httptest.NewRecorder(), newAccessLogRequest("/api/products/1"))
+
+		assert.JSONEq(t, `{
+			"time": "2024-03-05T13:55:36Z",
+			"remoteAddr": "192.0.2.10",
+			"method": "GET",
+			"path": "/api/products/1",
+			"route": "/api/products/{id}",
+			"proto": "HTTP/1.1",
+			"status": 200,
+			"bytes": 5,
+			"latencyMs": 1.5,
+			"referer": "https://example.com/",
+			"userAgent": "test-agent/1.0",
+			"requestId": "req-1"
+		}`, logBuffer.String())
+	})
+
+	t.Run("should skip excluded paths", func(t *testing.T) {
+		var logBuffer bytes.Buffer
+		server := newAccessLogTestServer(&logBuffer, WithAccessLogExclude("/healthz", "/api/products/2"))
+
+		rr := httptest.NewRecorder()
+		server.ServeHTTP(rr, newAccessLogRequest("/api/products/2"))
+		server.ServeHTTP(httptest.NewRecorder(), newAccessLogRequest("/healthz"))
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Empty(t, logBuffer.String())
+	})
+
+	t.Run("should sample requests but keep server errors", func(t *testing.T) {
+		var logBuffer bytes.Buffer
+		server := newAccessLogTestServer(&logBuffer, WithAccessLogSampling(0), WithAccessLogFormat(AccessLogCommon))
+
+		server.ServeHTTP(httptest.NewRecorder(), newAccessLogRequest("/api/products/1"))
+		server.ServeHTTP(httptest.NewRecorder(), newAccessLogRequest("/api/fail"))
+
+		lines := strings.Split(strings.TrimSpace(logBuffer.String()), "\n")
+		assert.Len(t, lines, 1)
+		assert.Contains(t, lines[0], `"GET /api/fail HTTP/1.1" 500 -`)
+	})
+
+	t.Run("should log a fraction of requests", func(t *testing.T) {
+		var logBuffer bytes.Buffer
+		handler := AccessLog(&logBuffer, WithAccessLogSampling(0.5))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+		for i := 0; i < 1000; i++ {
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+		}
+
+		logged := strings.Count(logBuffer.String(), "\n")
+		assert.Greater(t, logged, 350)
+		assert.Less(t, logged, 650)
+	})
+}
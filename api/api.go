@@ -2,28 +2,91 @@ package api
 
 import (
 	"context"
-	"log"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"time"
 
+	"github.com/chlovec/rest-pack/utils"
 	"github.com/gorilla/mux"
 )
 
 type APIServerInterface interface {
-	RegisterRoute(path string, handler func(http.ResponseWriter, *http.Request), methods ...string)
+	RegisterRoute(path string, handler func(http.ResponseWriter, *http.Request), methods ...string) *Route
+	Use(middlewares ...Middleware)
+	UseSubrouter(middlewares ...Middleware)
+	Group(prefix string, opts ...GroupOption) *Group
 	Start(timeouts ...time.Duration) error
+	Run(ctx context.Context) error
+	OnStart(name string, timeout time.Duration, fn Hook)
+	OnShutdown(name string, timeout time.Duration, fn Hook)
 }
 
 type APIServer struct {
 	server       *http.Server
 	apiRouter    *mux.Router
 	apiSubrouter *mux.Router
-	logger       *log.Logger
+	root         *Group
+	logger       *slog.Logger
+	middlewares  []Middleware
+	handler      http.Handler
+
+	// instrumentation wraps the server-level middleware, so that metrics and
+	// traces cover everything the server does with a request.
+	instrumentation []Middleware
+
+	shutdownTimeout time.Duration
+	signals         []os.Signal
+	startHooks      []hook
+	shutdownHooks   []hook
+	hookSeq         int
+	health          *Health
+	tlsConfig       *TLSConfig
+	maxBodyBytes    int64
+	errorFormat     utils.ErrorFormat
+	corsEnabled     bool
+
+	listeners        []net.Listener
+	listenAddrs      []listenAddr
+	systemdListeners bool
+	mu               sync.Mutex
+	addrs            []net.Addr
+	ready            chan struct{}
+	readyOnce        sync.Once
+}
+
+// Phase is the stage of the server lifecycle in which an error occurred.
+type Phase string
+
+const (
+	PhaseStart    Phase = "start"
+	PhaseListen   Phase = "listen"
+	PhaseDrain    Phase = "drain"
+	PhaseClose    Phase = "close"
+	PhaseShutdown Phase = "shutdown"
+)
+
+// ServerError is returned by Start and Run and records the lifecycle phase
+// that failed.
+type ServerError struct {
+	Phase Phase
+	Err   error
 }
 
-func NewAPIServer(addr string, pathPrefix string, logger *log.Logger) *APIServer {
+func (e *ServerError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Phase, e.Err)
+}
+
+func (e *ServerError) Unwrap() error {
+	return e.Err
+}
+
+func NewAPIServer(addr string, pathPrefix string, logger *slog.Logger, opts ...Option) *APIServer {
 	router := mux.NewRouter()
 	subrouter := router
 
@@ -31,62 +94,201 @@ func NewAPIServer(addr string, pathPrefix string, logger *log.Logger) *APIServer
 		subrouter = router.PathPrefix(pathPrefix).Subrouter()
 	}
 
-	server := &http.Server{
-		Addr:    addr,
-		Handler: router,
-	}
-
-	return &APIServer{
+	s := &APIServer{
 		apiRouter:    router,
 		apiSubrouter: subrouter,
-		server:       server,
+		root:         newGroup(subrouter, pathPrefix, logger),
 		logger:       logger,
+
+		shutdownTimeout: defaultShutdownTimeout,
+		signals:         defaultSignals,
+		maxBodyBytes:    defaultMaxBodyBytes,
+		ready:           make(chan struct{}),
+	}
+	s.server = &http.Server{
+		Addr:              addr,
+		Handler:           s,
+		ErrorLog:          slog.NewLogLogger(logger.Handler(), slog.LevelError),
+		ReadTimeout:       defaultReadTimeout,
+		ReadHeaderTimeout: defaultReadHeaderTimeout,
+		WriteTimeout:      defaultWriteTimeout,
+		IdleTimeout:       defaultIdleTimeout,
+		MaxHeaderBytes:    defaultMaxHeaderBytes,
 	}
+
+	s.handler = http.HandlerFunc(s.serveRouter)
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
 }
 
-func (s *APIServer) RegisterRoute(path string, handler func(http.ResponseWriter, *http.Request), methods ...string) {
-	if path == "" {
-		s.logger.Println("Cannot register a route with an empty path")
-		return
+// RegisterRoute registers handler for path on the path-prefix subrouter. The
+// returned Route can be used to add route-level middleware. It returns nil if
+// the route could not be registered.
+func (s *APIServer) RegisterRoute(path string, handler func(http.ResponseWriter, *http.Request), methods ...string) *Route {
+	return s.root.RegisterRoute(path, handler, methods...)
+}
+
+// Routes returns the routes registered through RegisterRoute, including
+// those of groups, in registration order.
+func (s *APIServer) Routes() []*Route {
+	var routes []*Route
+	s.apiRouter.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		if r, ok := route.GetHandler().(*Route); ok {
+			routes = append(routes, r)
+		}
+		return nil
+	})
+	return routes
+}
+
+// Use adds server-level middleware. It wraps every request the server
+// receives, including those that do not match a registered route.
+func (s *APIServer) Use(middlewares ...Middleware) {
+	s.middlewares = append(s.middlewares, middlewares...)
+	s.buildHandler()
+}
+
+// instrument adds middleware that runs before any server-level middleware.
+func (s *APIServer) instrument(middlewares ...Middleware) {
+	s.instrumentation = append(s.instrumentation, middlewares...)
+	s.buildHandler()
+}
+
+func (s *APIServer) buildHandler() {
+	middlewares := make([]Middleware, 0, len(s.instrumentation)+len(s.middlewares))
+	middlewares = append(middlewares, s.instrumentation...)
+	middlewares = append(middlewares, s.middlewares...)
+	s.handler = chain(http.HandlerFunc(s.serveRouter), middlewares)
+}
+
+// UseSubrouter adds middleware to the path-prefix subrouter. It runs only for
+// requests that match a route registered through RegisterRoute.
+func (s *APIServer) UseSubrouter(middlewares ...Middleware) {
+	s.root.Use(middlewares...)
+}
+
+// Group creates a route group mounted under prefix on the path-prefix
+// subrouter, e.g. "/v1/products" with pathPrefix "/v1" and prefix "/products".
+func (s *APIServer) Group(prefix string, opts ...GroupOption) *Group {
+	return s.root.Group(prefix, opts...)
+}
+
+func (s *APIServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.errorFormat != utils.ErrorFormatLegacy {
+		w = utils.WithErrorFormat(w, r, s.errorFormat)
 	}
-	if handler == nil {
-		s.logger.Println("Cannot register a route with a nil handler")
-		return
+	if len(s.middlewares) > 0 || len(s.instrumentation) > 0 {
+		r = withMatchedRoute(r, s.apiRouter)
 	}
+	s.handler.ServeHTTP(w, r)
+}
 
-	s.apiSubrouter.HandleFunc(path, handler).Methods(methods...)
-	s.logger.Printf("Route registered: %s", path)
+// serveRouter caps the request body and routes the request. It runs inside
+// the server-level middleware, so that rejected requests are still logged,
+// measured and given CORS headers.
+func (s *APIServer) serveRouter(w http.ResponseWriter, r *http.Request) {
+	if s.maxBodyBytes > 0 && r.Body != nil {
+		if r.ContentLength > s.maxBodyBytes {
+			utils.WriteErrorJSON(w, http.StatusRequestEntityTooLarge, errors.New(http.StatusText(http.StatusRequestEntityTooLarge)), nil)
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, s.maxBodyBytes)
+	}
+	s.apiRouter.ServeHTTP(w, r)
 }
 
+// Start runs the server until it receives one of the configured signals.
+// A non-positive timeout falls back to the configured shutdown timeout.
 func (s *APIServer) Start(timeouts ...time.Duration) error {
-	// Default timeout if none is provided
-	timeout := 5 * time.Second
-	if len(timeouts) > 0 {
+	timeout := s.shutdownTimeout
+	if len(timeouts) > 0 && timeouts[0] > 0 {
 		timeout = timeouts[0]
 	}
 
-	s.logger.Printf("Starting server on %s...", s.server.Addr)
+	return s.run(context.Background(), timeout)
+}
+
+// Run serves requests until ctx is cancelled or one of the configured signals
+// (SIGINT, SIGTERM and SIGQUIT by default) is received, then drains in-flight
+// requests within the shutdown timeout and runs the shutdown hooks. Errors are
+// returned as *ServerError, joined when several phases fail.
+func (s *APIServer) Run(ctx context.Context) error {
+	return s.run(ctx, s.shutdownTimeout)
+}
+
+func (s *APIServer) run(ctx context.Context, timeout time.Duration) error {
+	if len(s.signals) > 0 {
+		var stop context.CancelFunc
+		ctx, stop = signal.NotifyContext(ctx, s.signals...)
+		defer stop()
+	}
+
+	if s.tlsConfig != nil {
+		reloader, err := newCertReloader(*s.tlsConfig, s.logger)
+		if err != nil {
+			s.closeListeners()
+			return &ServerError{Phase: PhaseListen, Err: err}
+		}
+		s.server.TLSConfig = reloader.tlsConfig()
+	}
+
+	s.checkCORS()
+
+	if seq, err := s.runStartHooks(ctx); err != nil {
+		s.closeListeners()
+		return joinErrors(err, s.runShutdownHooks(seq))
+	}
 
-	stop := make(chan os.Signal, 1)
-	signal.Notify(stop, os.Interrupt)
+	s.logger.Info("Starting server", "addr", s.server.Addr)
 
-	errChan := make(chan error, 1)
+	listeners, err := s.listen()
+	if err != nil {
+		return joinErrors(&ServerError{Phase: PhaseListen, Err: err}, s.runShutdownHooks(s.hookSeq+1))
+	}
+	s.setListening(listeners)
 
-	go func() {
-		errChan <- s.server.ListenAndServe()
-	}()
+	errChan := make(chan error, len(listeners))
+	for _, l := range listeners {
+		go func(l net.Listener) {
+			if s.tlsConfig != nil {
+				errChan <- s.server.ServeTLS(l, "", "")
+				return
+			}
+			errChan <- s.server.Serve(l)
+		}(l)
+	}
 
 	select {
-	case <-stop:
-		s.logger.Println("Shutting down gracefully...")
-		ctx, cancel := context.WithTimeout(context.Background(), timeout)
-		defer cancel()
-		if err := s.server.Shutdown(ctx); err != nil {
-			return err
+	case <-ctx.Done():
+		err = s.shutdown(timeout)
+	case serveErr := <-errChan:
+		err = joinErrors(&ServerError{Phase: PhaseListen, Err: serveErr}, s.shutdown(timeout))
+	}
+
+	return joinErrors(err, s.runShutdownHooks(s.hookSeq+1))
+}
+
+func (s *APIServer) shutdown(timeout time.Duration) error {
+	s.logger.Info("Shutting down gracefully...")
+	if s.health != nil {
+		s.health.SetShuttingDown()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if err := s.server.Shutdown(ctx); err != nil {
+		s.logger.Warn("Graceful shutdown failed, closing connections", "error", err)
+		if closeErr := s.server.Close(); closeErr != nil {
+			return &ServerError{Phase: PhaseClose, Err: errors.Join(err, closeErr)}
 		}
-		s.logger.Println("Server stopped gracefully.")
-		return nil
-	case err := <-errChan:
-		return err
+		return &ServerError{Phase: PhaseDrain, Err: err}
 	}
+
+	s.logger.Info("Server stopped gracefully.")
+	return nil
 }
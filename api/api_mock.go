@@ -5,6 +5,7 @@
 package api
 
 import (
+	context "context"
 	http "net/http"
 	reflect "reflect"
 	time "time"
@@ -35,14 +36,59 @@ func (m *MockAPIServerInterface) EXPECT() *MockAPIServerInterfaceMockRecorder {
 	return m.recorder
 }
 
+// Group mocks base method.
+func (m *MockAPIServerInterface) Group(prefix string, opts ...GroupOption) *Group {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{prefix}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "Group", varargs...)
+	ret0, _ := ret[0].(*Group)
+	return ret0
+}
+
+// Group indicates an expected call of Group.
+func (mr *MockAPIServerInterfaceMockRecorder) Group(prefix interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{prefix}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Group", reflect.TypeOf((*MockAPIServerInterface)(nil).Group), varargs...)
+}
+
+// OnShutdown mocks base method.
+func (m *MockAPIServerInterface) OnShutdown(name string, timeout time.Duration, fn Hook) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "OnShutdown", name, timeout, fn)
+}
+
+// OnShutdown indicates an expected call of OnShutdown.
+func (mr *MockAPIServerInterfaceMockRecorder) OnShutdown(name, timeout, fn interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnShutdown", reflect.TypeOf((*MockAPIServerInterface)(nil).OnShutdown), name, timeout, fn)
+}
+
+// OnStart mocks base method.
+func (m *MockAPIServerInterface) OnStart(name string, timeout time.Duration, fn Hook) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "OnStart", name, timeout, fn)
+}
+
+// OnStart indicates an expected call of OnStart.
+func (mr *MockAPIServerInterfaceMockRecorder) OnStart(name, timeout, fn interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnStart", reflect.TypeOf((*MockAPIServerInterface)(nil).OnStart), name, timeout, fn)
+}
+
 // RegisterRoute mocks base method.
-func (m *MockAPIServerInterface) RegisterRoute(path string, handler func(http.ResponseWriter, *http.Request), methods ...string) {
+func (m *MockAPIServerInterface) RegisterRoute(path string, handler func(http.ResponseWriter, *http.Request), methods ...string) *Route {
 	m.ctrl.T.Helper()
 	varargs := []interface{}{path, handler}
 	for _, a := range methods {
 		varargs = append(varargs, a)
 	}
-	m.ctrl.Call(m, "RegisterRoute", varargs...)
+	ret := m.ctrl.Call(m, "RegisterRoute", varargs...)
+	ret0, _ := ret[0].(*Route)
+	return ret0
 }
 
 // RegisterRoute indicates an expected call of RegisterRoute.
@@ -52,6 +98,20 @@ func (mr *MockAPIServerInterfaceMockRecorder) RegisterRoute(path, handler interf
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RegisterRoute", reflect.TypeOf((*MockAPIServerInterface)(nil).RegisterRoute), varargs...)
 }
 
+// Run mocks base method.
+func (m *MockAPIServerInterface) Run(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Run", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Run indicates an expected call of Run.
+func (mr *MockAPIServerInterfaceMockRecorder) Run(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Run", reflect.TypeOf((*MockAPIServerInterface)(nil).Run), ctx)
+}
+
 // Start mocks base method.
 func (m *MockAPIServerInterface) Start(timeouts ...time.Duration) error {
 	m.ctrl.T.Helper()
@@ -69,3 +129,35 @@ func (mr *MockAPIServerInterfaceMockRecorder) Start(timeouts ...interface{}) *go
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Start", reflect.TypeOf((*MockAPIServerInterface)(nil).Start), timeouts...)
 }
+
+// Use mocks base method.
+func (m *MockAPIServerInterface) Use(middlewares ...Middleware) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{}
+	for _, a := range middlewares {
+		varargs = append(varargs, a)
+	}
+	m.ctrl.Call(m, "Use", varargs...)
+}
+
+// Use indicates an expected call of Use.
+func (mr *MockAPIServerInterfaceMockRecorder) Use(middlewares ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Use", reflect.TypeOf((*MockAPIServerInterface)(nil).Use), middlewares...)
+}
+
+// UseSubrouter mocks base method.
+func (m *MockAPIServerInterface) UseSubrouter(middlewares ...Middleware) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{}
+	for _, a := range middlewares {
+		varargs = append(varargs, a)
+	}
+	m.ctrl.Call(m, "UseSubrouter", varargs...)
+}
+
+// UseSubrouter indicates an expected call of UseSubrouter.
+func (mr *MockAPIServerInterfaceMockRecorder) UseSubrouter(middlewares ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseSubrouter", reflect.TypeOf((*MockAPIServerInterface)(nil).UseSubrouter), middlewares...)
+}
@@ -2,13 +2,18 @@ package api
 
 import (
 	"bytes"
+	"context"
 	"log"
+	"log/slog"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"syscall"
 	"testing"
 	"time"
 
+	"github.com/chlovec/rest-pack/utils"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -184,7 +189,7 @@ func TestRegisterRoute(t *testing.T) {
 func TestAPIServerStart(t *testing.T) {
 	// Mock logger
 	var logBuffer bytes.Buffer
-	logger := log.New(&logBuffer, "", log.LstdFlags)
+	logger := utils.FromStdLogger(log.New(&logBuffer, "", log.LstdFlags))
 
 	// Create a new API server
 	serverAddr := "127.0.0.1:0" // Use a random available port
@@ -221,8 +226,8 @@ func TestAPIServerStart(t *testing.T) {
 	}
 
 	// Check if the correct log messages were written
-	if !bytes.Contains(logBuffer.Bytes(), []byte("Starting server on")) {
-		t.Errorf("Expected log message 'Starting server on', but not found")
+	if !bytes.Contains(logBuffer.Bytes(), []byte("Starting server")) {
+		t.Errorf("Expected log message 'Starting server', but not found")
 	}
 	if !bytes.Contains(logBuffer.Bytes(), []byte("Shutting down gracefully...")) {
 		t.Errorf("Expected log message 'Shutting down gracefully...', but not found")
@@ -232,8 +237,125 @@ func TestAPIServerStart(t *testing.T) {
 	}
 }
 
-func initLog() (*log.Logger, *bytes.Buffer) {
+func TestAPIServerRun(t *testing.T) {
+	t.Run("should shut down when the context is cancelled", func(t *testing.T) {
+		logger, logBuffer := initLog()
+		addr := freeAddr(t)
+		apiServer := NewAPIServer(addr, "", logger, WithSignals())
+
+		ctx, cancel := context.WithCancel(context.Background())
+		errChan := make(chan error, 1)
+		go func() {
+			errChan <- apiServer.Run(ctx)
+		}()
+
+		waitForServer(t, addr)
+		cancel()
+
+		select {
+		case err := <-errChan:
+			assert.NoError(t, err)
+		case <-time.After(3 * time.Second):
+			t.Fatal("Test timed out waiting for server to shut down")
+		}
+		assert.Contains(t, logBuffer.String(), "Server stopped gracefully.")
+	})
+
+	t.Run("should shut down on SIGTERM", func(t *testing.T) {
+		logger, logBuffer := initLog()
+		addr := freeAddr(t)
+		apiServer := NewAPIServer(addr, "", logger)
+
+		errChan := make(chan error, 1)
+		go func() {
+			errChan <- apiServer.Run(context.Background())
+		}()
+
+		waitForServer(t, addr)
+		p, _ := os.FindProcess(os.Getpid())
+		assert.NoError(t, p.Signal(syscall.SIGTERM))
+
+		select {
+		case err := <-errChan:
+			assert.NoError(t, err)
+		case <-time.After(3 * time.Second):
+			t.Fatal("Test timed out waiting for server to shut down")
+		}
+		assert.Contains(t, logBuffer.String(), "Server stopped gracefully.")
+	})
+
+	t.Run("should report listen phase error", func(t *testing.T) {
+		logger, _ := initLog()
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		assert.NoError(t, err)
+		defer listener.Close()
+
+		apiServer := NewAPIServer(listener.Addr().String(), "", logger, WithSignals())
+		err = apiServer.Run(context.Background())
+
+		var serverErr *ServerError
+		assert.ErrorAs(t, err, &serverErr)
+		assert.Equal(t, PhaseListen, serverErr.Phase)
+	})
+
+	t.Run("should report drain phase error when requests outlive the timeout", func(t *testing.T) {
+		logger, _ := initLog()
+		addr := freeAddr(t)
+		apiServer := NewAPIServer(addr, "", logger, WithSignals(), WithShutdownTimeout(100*time.Millisecond))
+
+		started := make(chan struct{})
+		release := make(chan struct{})
+		defer close(release)
+		apiServer.RegisterRoute("/slow", func(w http.ResponseWriter, r *http.Request) {
+			close(started)
+			<-release
+		}, http.MethodGet)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		errChan := make(chan error, 1)
+		go func() {
+			errChan <- apiServer.Run(ctx)
+		}()
+
+		waitForServer(t, addr)
+		go http.Get("http://" + addr + "/slow")
+		<-started
+		cancel()
+
+		select {
+		case err := <-errChan:
+			var serverErr *ServerError
+			assert.ErrorAs(t, err, &serverErr)
+			assert.Equal(t, PhaseDrain, serverErr.Phase)
+			assert.ErrorIs(t, err, context.DeadlineExceeded)
+		case <-time.After(3 * time.Second):
+			t.Fatal("Test timed out waiting for server to shut down")
+		}
+	})
+}
+
+func freeAddr(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer listener.Close()
+	return listener.Addr().String()
+}
+
+func waitForServer(t *testing.T, addr string) {
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		conn, err := net.Dial("tcp", addr)
+		if err == nil {
+			conn.Close()
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("server at %s did not start listening", addr)
+}
+
+func initLog() (*slog.Logger, *bytes.Buffer) {
 	logBuffer := &bytes.Buffer{}
-	logger := log.New(logBuffer, "TEST: ", log.LstdFlags)
+	logger := utils.NewLogger(logBuffer, utils.LogFormatJSON, slog.LevelDebug)
 	return logger, logBuffer
 }
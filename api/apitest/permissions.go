@@ -0,0 +1,85 @@
+// Package apitest provides helpers for testing services built on api.
+package apitest
+
+import (
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/chlovec/rest-pack/api"
+	"github.com/chlovec/rest-pack/auth"
+)
+
+// Callers names the callers of a permission matrix. Anonymous callers have
+// nil claims.
+type Callers map[string]*auth.Claims
+
+// Matrix maps each route, written as "METHOD template" such as
+// "DELETE /api/products/{id}", to the names of the callers allowed to call
+// it. Routes registered without methods are written with the method "*".
+type Matrix map[string][]string
+
+// AssertPermissions checks the authorization policies of every route
+// registered on server against matrix, without calling any handler. It fails
+// if a registered route is missing from the matrix, if the matrix lists an
+// unknown route or caller, or if any caller is allowed or denied contrary to
+// the matrix.
+func AssertPermissions(t testing.TB, server *api.APIServer, callers Callers, matrix Matrix) bool {
+	t.Helper()
+
+	ok := true
+	seen := make(map[string]bool)
+	for _, route := range server.Routes() {
+		methods := route.Methods()
+		if len(methods) == 0 {
+			methods = []string{"*"}
+		}
+
+		for _, method := range methods {
+			key := method + " " + route.Template()
+			seen[key] = true
+
+			allowed, listed := matrix[key]
+			if !listed {
+				t.Errorf("route %s is missing from the permission matrix", key)
+				ok = false
+				continue
+			}
+
+			for _, name := range slices.Sorted(maps.Keys(callers)) {
+				req := httptest.NewRequest(http.MethodGet, "/", nil)
+				if method != "*" {
+					req.Method = method
+				}
+				req.URL.Path = route.Template()
+
+				err := route.Evaluate(req, callers[name])
+				want := slices.Contains(allowed, name)
+				switch {
+				case want && err != nil:
+					t.Errorf("%s: %s should be allowed but is denied: %v", key, name, err)
+					ok = false
+				case !want && err == nil:
+					t.Errorf("%s: %s should be denied but is allowed", key, name)
+					ok = false
+				}
+			}
+		}
+	}
+
+	for _, key := range slices.Sorted(maps.Keys(matrix)) {
+		if !seen[key] {
+			t.Errorf("permission matrix lists unknown route %s", key)
+			ok = false
+		}
+		for _, name := range matrix[key] {
+			if _, exists := callers[name]; !exists {
+				t.Errorf("permission matrix lists unknown caller %q for %s", name, key)
+				ok = false
+			}
+		}
+	}
+	return ok
+}
@@ -0,0 +1,85 @@
+package apitest
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"testing"
+
+	"github.com/chlovec/rest-pack/api"
+	"github.com/chlovec/rest-pack/auth"
+	"github.com/stretchr/testify/assert"
+)
+
+// recorder records the errors reported by AssertPermissions.
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func newServer() *api.APIServer {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+	server := api.NewAPIServer(":8080", "/api", logger)
+
+	handler := func(w http.ResponseWriter, r *http.Request) {}
+	server.RegisterRoute("/products", handler, http.MethodGet)
+	server.RegisterRoute("/products", handler, http.MethodPost).Authorize(auth.RequireScopes("products:write"))
+	server.Group("/admin").RegisterRoute("/products/{id}", handler, http.MethodDelete).Authorize(auth.RequireRoles("admin"))
+	return server
+}
+
+var callers = Callers{
+	"anonymous": nil,
+	"user":      {Subject: "user-1", Raw: map[string]any{"scope": "products:read"}},
+	"writer":    {Subject: "user-2", Raw: map[string]any{"scope": "products:read products:write"}},
+	"admin":     {Subject: "admin-1", Raw: map[string]any{"roles": []any{"admin"}}},
+}
+
+func TestAssertPermissions(t *testing.T) {
+	t.Run("should pass for a matching matrix", func(t *testing.T) {
+		AssertPermissions(t, newServer(), callers, Matrix{
+			"GET /api/products":               {"anonymous", "user", "writer", "admin"},
+			"POST /api/products":              {"writer"},
+			"DELETE /api/admin/products/{id}": {"admin"},
+		})
+	})
+
+	t.Run("should report every mismatch", func(t *testing.T) {
+		r := &recorder{TB: t}
+		ok := AssertPermissions(r, newServer(), callers, Matrix{
+			"GET /api/products":  {"anonymous", "user", "writer", "admin"},
+			"POST /api/products": {"writer", "admin", "guest"},
+			"PUT /api/products":  {"admin"},
+		})
+
+		assert.False(t, ok)
+		assert.Equal(t, []string{
+			"POST /api/products: admin should be allowed but is denied: missing scope products:write",
+			"route DELETE /api/admin/products/{id} is missing from the permission matrix",
+			`permission matrix lists unknown caller "guest" for POST /api/products`,
+			"permission matrix lists unknown route PUT /api/products",
+		}, r.errors)
+	})
+
+	t.Run("should report callers that are wrongly allowed", func(t *testing.T) {
+		r := &recorder{TB: t}
+		AssertPermissions(r, newServer(), callers, Matrix{
+			"GET /api/products":               {"admin"},
+			"POST /api/products":              {"writer"},
+			"DELETE /api/admin/products/{id}": {"admin"},
+		})
+
+		assert.Equal(t, []string{
+			"GET /api/products: anonymous should be denied but is allowed",
+			"GET /api/products: user should be denied but is allowed",
+			"GET /api/products: writer should be denied but is allowed",
+		}, r.errors)
+	})
+}
@@ -0,0 +1,71 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/chlovec/rest-pack/auth"
+)
+
+// SkipAuth exempts the route from the middleware returned by
+// APIServer.Authenticate, for endpoints such as login or health checks.
+func (r *Route) SkipAuth() *Route {
+	r.skipAuth = true
+	return r
+}
+
+// Authenticate returns server-level middleware that requires a valid bearer
+// token for every route registered through RegisterRoute, except routes
+// marked with SkipAuth. Requests that match no such route, including the
+// metrics endpoint, are passed on unauthenticated.
+func (s *APIServer) Authenticate(validator *auth.Validator, opts ...auth.MiddlewareOption) Middleware {
+	skip := func(r *http.Request) bool {
+		route := matchedRoute(r)
+		return route == nil || route.skipAuth
+	}
+	return auth.Middleware(validator, append([]auth.MiddlewareOption{auth.WithSkip(skip)}, opts...)...)
+}
+
+// Authorize requires callers of the route to satisfy every policy, checked
+// against the claims that authentication middleware put on the request
+// context. Requests without claims are rejected with 401 Unauthorized and
+// requests the policies deny with 403 Forbidden.
+func (r *Route) Authorize(policies ...auth.Policy) *Route {
+	r.policies = append(r.policies, policies...)
+	r.buildChain()
+	return r
+}
+
+// Evaluate checks the route's policies for a caller with claims, which may
+// be nil for anonymous callers. It returns auth.ErrUnauthenticated for
+// anonymous callers of a route with policies and the policy error for
+// callers that are denied.
+func (r *Route) Evaluate(req *http.Request, claims *auth.Claims) error {
+	if len(r.policies) == 0 {
+		return nil
+	}
+	if claims == nil {
+		return auth.ErrUnauthenticated
+	}
+	for _, policy := range r.policies {
+		if err := policy.Authorize(req, claims); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Route) authorizeHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		claims, _ := auth.ClaimsFromContext(req.Context())
+		err := r.Evaluate(req, claims)
+		switch {
+		case errors.Is(err, auth.ErrUnauthenticated):
+			auth.WriteUnauthorized(w, err)
+		case err != nil:
+			auth.WriteForbidden(w, err)
+		default:
+			next.ServeHTTP(w, req)
+		}
+	})
+}
@@ -0,0 +1,103 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/chlovec/rest-pack/auth"
+	"github.com/stretchr/testify/assert"
+)
+
+func hs256Token(secret []byte, claims string) string {
+	signed := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`)) + "." +
+		base64.RawURLEncoding.EncodeToString([]byte(claims))
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(signed))
+	return signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestAuthenticate(t *testing.T) {
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	logger, _ := initLog()
+	server := NewAPIServer(":8080", "/api", logger)
+	server.Use(server.Authenticate(auth.NewValidator(auth.StaticKeys{{Key: secret}})))
+
+	var subject string
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		subject = ""
+		if claims, ok := auth.ClaimsFromContext(r.Context()); ok {
+			subject = claims.Subject
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+	server.RegisterRoute("/products", handler, http.MethodGet)
+	server.RegisterRoute("/health", handler, http.MethodGet).SkipAuth()
+
+	serve := func(path, token string) int {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rr := httptest.NewRecorder()
+		server.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, serve("/api/products", ""))
+	assert.Equal(t, http.StatusUnauthorized, serve("/api/products", hs256Token([]byte("wrong"), `{"sub":"user-1"}`)))
+
+	assert.Equal(t, http.StatusOK, serve("/api/products", hs256Token(secret, `{"sub":"user-1"}`)))
+	assert.Equal(t, "user-1", subject)
+
+	assert.Equal(t, http.StatusOK, serve("/api/health", ""))
+	assert.Equal(t, http.StatusNotFound, serve("/api/unknown", ""))
+}
+
+func TestRouteAuthorize(t *testing.T) {
+	logger, _ := initLog()
+	server := NewAPIServer(":8080", "/api", logger)
+
+	// Route-level authentication runs before the route's authorization.
+	authenticate := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if role := r.Header.Get("X-Role"); role != "" {
+				r = r.WithContext(auth.ContextWithClaims(r.Context(), &auth.Claims{Raw: map[string]any{"roles": role}}))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}
+	route := server.RegisterRoute("/products/{id}", handler, http.MethodDelete).
+		Authorize(auth.RequireRoles("admin")).
+		Use(authenticate)
+
+	serve := func(role string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodDelete, "/api/products/1", nil)
+		if role != "" {
+			req.Header.Set("X-Role", role)
+		}
+		rr := httptest.NewRecorder()
+		server.ServeHTTP(rr, req)
+		return rr
+	}
+
+	assert.Equal(t, http.StatusNoContent, serve("admin").Code)
+
+	rr := serve("viewer")
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	assert.JSONEq(t, `{"error":"requires role admin"}`, rr.Body.String())
+
+	rr = serve("")
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	assert.Equal(t, "Bearer", rr.Header().Get("WWW-Authenticate"))
+	assert.JSONEq(t, `{"error":"authentication required"}`, rr.Body.String())
+
+	assert.Equal(t, "/api/products/{id}", route.Template())
+	assert.Equal(t, []*Route{route}, server.Routes())
+}
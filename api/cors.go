@@ -0,0 +1,294 @@
+package api
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// probeMethods are tried against the router to find the methods registered
+// for a path when answering a preflight request.
+var probeMethods = []string{
+	http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+	http.MethodDelete, http.MethodOptions, http.MethodConnect, http.MethodTrace,
+}
+
+var defaultCORSHeaders = []string{"Accept", "Content-Type", "X-Request-ID"}
+
+// CORSPolicy describes which cross-origin requests browsers may make.
+type CORSPolicy struct {
+	// AllowedOrigins lists origins such as "https://example.com". "*" allows
+	// any origin and "https://*.example.com" allows any subdomain. The
+	// wildcard matches any characters, including dots, so it also allows
+	// nested subdomains such as "https://a.b.example.com". "*" cannot be
+	// combined with AllowCredentials.
+	AllowedOrigins []string
+	// AllowedOriginPatterns allows origins matching any of the expressions.
+	AllowedOriginPatterns []*regexp.Regexp
+	// AllowedMethods defaults to the methods registered for the route.
+	AllowedMethods []string
+	// AllowedHeaders lists request headers the client may send. "*" allows
+	// any header. It defaults to Accept, Content-Type and X-Request-ID.
+	AllowedHeaders []string
+	// ExposedHeaders lists response headers the client may read.
+	ExposedHeaders []string
+	// AllowCredentials lets browsers send cookies and credentials. It
+	// requires AllowedOrigins to list the allowed origins rather than "*".
+	AllowCredentials bool
+	// MaxAge is how long browsers may cache a preflight response.
+	MaxAge time.Duration
+}
+
+type corsPolicy struct {
+	CORSPolicy
+	anyOrigin bool
+	origins   map[string]struct{}
+	wildcards [][2]string
+	anyHeader bool
+	headers   map[string]struct{}
+	methods   []string
+	exposed   string
+	maxAge    string
+}
+
+// newCORSPolicy panics if p allows any origin with credentials, which would
+// let every site make credentialed requests.
+func newCORSPolicy(p CORSPolicy) *corsPolicy {
+	c := &corsPolicy{
+		CORSPolicy: p,
+		origins:    make(map[string]struct{}),
+		headers:    make(map[string]struct{}),
+		exposed:    strings.Join(p.ExposedHeaders, ", "),
+	}
+
+	for _, origin := range p.AllowedOrigins {
+		origin = strings.ToLower(origin)
+		switch {
+		case origin == "*":
+			if p.AllowCredentials {
+				panic(`api: CORS origin "*" cannot be combined with AllowCredentials`)
+			}
+			c.anyOrigin = true
+		case strings.Count(origin, "*") == 1:
+			prefix, suffix, _ := strings.Cut(origin, "*")
+			c.wildcards = append(c.wildcards, [2]string{prefix, suffix})
+		default:
+			c.origins[origin] = struct{}{}
+		}
+	}
+
+	headers := p.AllowedHeaders
+	if headers == nil {
+		headers = defaultCORSHeaders
+	}
+	for _, header := range headers {
+		if header == "*" {
+			c.anyHeader = true
+		}
+		c.headers[strings.ToLower(header)] = struct{}{}
+	}
+
+	for _, method := range p.AllowedMethods {
+		c.methods = append(c.methods, strings.ToUpper(method))
+	}
+
+	if p.MaxAge > 0 {
+		c.maxAge = strconv.Itoa(int(p.MaxAge.Seconds()))
+	}
+	return c
+}
+
+func (c *corsPolicy) allowOrigin(origin string) bool {
+	if c.anyOrigin {
+		return true
+	}
+
+	lower := strings.ToLower(origin)
+	if _, ok := c.origins[lower]; ok {
+		return true
+	}
+	for _, w := range c.wildcards {
+		if len(lower) > len(w[0])+len(w[1]) && strings.HasPrefix(lower, w[0]) && strings.HasSuffix(lower, w[1]) {
+			return true
+		}
+	}
+	for _, pattern := range c.AllowedOriginPatterns {
+		if pattern.MatchString(origin) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *corsPolicy) allowHeaders(requested string) bool {
+	if c.anyHeader {
+		return true
+	}
+	for _, header := range strings.Split(requested, ",") {
+		header = strings.ToLower(strings.TrimSpace(header))
+		if header == "" {
+			continue
+		}
+		if _, ok := c.headers[header]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// setOrigin allows origin to read the response.
+func (c *corsPolicy) setOrigin(h http.Header, origin string) {
+	if c.anyOrigin {
+		h.Set("Access-Control-Allow-Origin", "*")
+	} else {
+		h.Set("Access-Control-Allow-Origin", origin)
+	}
+	if c.AllowCredentials {
+		h.Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+// CORS sets the CORS policy of the route, overriding the policy passed to
+// APIServer.CORS for requests to this route. It has no effect unless the
+// APIServer.CORS middleware is installed.
+func (r *Route) CORS(policy CORSPolicy) *Route {
+	r.cors = newCORSPolicy(policy)
+	return r
+}
+
+// CORS returns server-level middleware that applies policy to cross-origin
+// requests and answers preflight requests for registered routes, allowing the
+// methods registered for the path unless the policy lists its own. Routes can
+// override the policy with Route.CORS.
+func (s *APIServer) CORS(policy CORSPolicy) Middleware {
+	defaultPolicy := newCORSPolicy(policy)
+	s.corsEnabled = true
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			w.Header().Add("Vary", "Origin")
+			if origin == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			requestMethod := r.Header.Get("Access-Control-Request-Method")
+			if r.Method == http.MethodOptions && requestMethod != "" {
+				s.preflight(w, r, defaultPolicy, origin, requestMethod, next)
+				return
+			}
+
+			p := defaultPolicy
+			if route := matchedRoute(r); route != nil && route.cors != nil {
+				p = route.cors
+			}
+			if p.allowOrigin(origin) {
+				p.setOrigin(w.Header(), origin)
+				if p.exposed != "" {
+					w.Header().Set("Access-Control-Expose-Headers", p.exposed)
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// checkCORS logs the routes whose CORS policy has no effect because the
+// server-level CORS middleware is not installed.
+func (s *APIServer) checkCORS() {
+	if s.corsEnabled {
+		return
+	}
+	for _, route := range s.Routes() {
+		if route.cors != nil {
+			s.logger.Error("Route CORS policy has no effect without APIServer.CORS", "route", route.Template())
+		}
+	}
+}
+
+// preflight answers a preflight request. Preflights for unknown paths fall
+// through to next, so they get the router's 404. Disallowed preflights are
+// answered without CORS headers, which makes the browser block the request.
+func (s *APIServer) preflight(w http.ResponseWriter, r *http.Request, defaultPolicy *corsPolicy, origin, requestMethod string, next http.Handler) {
+	w.Header().Add("Vary", "Access-Control-Request-Method")
+	w.Header().Add("Vary", "Access-Control-Request-Headers")
+
+	registered := s.registeredMethods(r, requestMethod)
+	if len(registered) == 0 {
+		next.ServeHTTP(w, r)
+		return
+	}
+
+	p := defaultPolicy
+	if route, _ := s.routeFor(r, requestMethod); route != nil && route.cors != nil {
+		p = route.cors
+	}
+
+	methods := p.methods
+	if methods == nil {
+		methods = registered
+	}
+	requestHeaders := r.Header.Get("Access-Control-Request-Headers")
+	if !p.allowOrigin(origin) || !containsMethod(methods, requestMethod) || !p.allowHeaders(requestHeaders) {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	h := w.Header()
+	p.setOrigin(h, origin)
+	h.Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+	if requestHeaders != "" {
+		h.Set("Access-Control-Allow-Headers", requestHeaders)
+	}
+	if p.maxAge != "" {
+		h.Set("Access-Control-Max-Age", p.maxAge)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// registeredMethods returns the methods for which a route is registered for
+// the path of r, including method even if it is not a standard one.
+func (s *APIServer) registeredMethods(r *http.Request, method string) []string {
+	candidates := probeMethods
+	if !containsMethod(candidates, method) {
+		candidates = append(append([]string(nil), candidates...), method)
+	}
+
+	var methods []string
+	for _, m := range candidates {
+		if _, ok := s.routeFor(r, m); ok {
+			methods = append(methods, m)
+		}
+	}
+	return methods
+}
+
+// routeFor reports whether a route is registered for the path of r and
+// method. The returned *Route is nil for routes not registered through
+// RegisterRoute, such as the health checks.
+func (s *APIServer) routeFor(r *http.Request, method string) (*Route, bool) {
+	probe := new(http.Request)
+	*probe = *r
+	probe.Method = method
+
+	var match mux.RouteMatch
+	if !s.apiRouter.Match(probe, &match) || match.MatchErr != nil || match.Route == nil {
+		return nil, false
+	}
+	route, _ := match.Route.GetHandler().(*Route)
+	return route, true
+}
+
+func containsMethod(methods []string, method string) bool {
+	for _, m := range methods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
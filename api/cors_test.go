@@ -0,0 +1,193 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newCORSTestServer(policy CORSPolicy) *APIServer {
+	logger, _ := initLog()
+	server := NewAPIServer(":8080", "/api", logger)
+	server.Use(server.CORS(policy))
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}
+	server.RegisterRoute("/products", handler, http.MethodGet, http.MethodPost)
+	server.RegisterRoute("/products/{id}", handler, http.MethodPut)
+	server.RegisterRoute("/products/{id}", handler, http.MethodDelete)
+	server.RegisterRoute("/public", handler, http.MethodGet).CORS(CORSPolicy{AllowedOrigins: []string{"*"}})
+	return server
+}
+
+func preflight(server *APIServer, path, origin, method, headers string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodOptions, path, nil)
+	req.Header.Set("Origin", origin)
+	req.Header.Set("Access-Control-Request-Method", method)
+	if headers != "" {
+		req.Header.Set("Access-Control-Request-Headers", headers)
+	}
+
+	rr := httptest.NewRecorder()
+	server.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestCORS(t *testing.T) {
+	policy := CORSPolicy{
+		AllowedOrigins:        []string{"https://app.example.com", "https://*.example.org"},
+		AllowedOriginPatterns: []*regexp.Regexp{regexp.MustCompile(`^http://localhost:\d+$`)},
+		AllowedHeaders:        []string{"Content-Type", "Authorization"},
+		ExposedHeaders:        []string{"X-Request-ID"},
+		AllowCredentials:      true,
+		MaxAge:                10 * time.Minute,
+	}
+
+	t.Run("should answer preflights with the registered methods", func(t *testing.T) {
+		server := newCORSTestServer(policy)
+
+		rr := preflight(server, "/api/products/1", "https://app.example.com", http.MethodDelete, "content-type, authorization")
+
+		assert.Equal(t, http.StatusNoContent, rr.Code)
+		assert.Equal(t, "https://app.example.com", rr.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, "true", rr.Header().Get("Access-Control-Allow-Credentials"))
+		assert.Equal(t, "PUT, DELETE", rr.Header().Get("Access-Control-Allow-Methods"))
+		assert.Equal(t, "content-type, authorization", rr.Header().Get("Access-Control-Allow-Headers"))
+		assert.Equal(t, "600", rr.Header().Get("Access-Control-Max-Age"))
+		assert.Equal(t, []string{"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"}, rr.Header().Values("Vary"))
+	})
+
+	t.Run("should match wildcard and regex origins", func(t *testing.T) {
+		server := newCORSTestServer(policy)
+
+		for origin, allowed := range map[string]bool{
+			"https://shop.example.org": true,
+			"https://example.org":      false,
+			"http://shop.example.org":  false,
+			"http://localhost:3000":    true,
+			"http://localhost":         false,
+			"https://app.example.com":  true,
+			"https://evil.example.com": false,
+			"https://APP.example.com":  true,
+		} {
+			rr := preflight(server, "/api/products", origin, http.MethodPost, "")
+			assert.Equal(t, http.StatusNoContent, rr.Code, origin)
+			if allowed {
+				assert.Equal(t, origin, rr.Header().Get("Access-Control-Allow-Origin"), origin)
+			} else {
+				assert.Empty(t, rr.Header().Get("Access-Control-Allow-Origin"), origin)
+			}
+		}
+	})
+
+	t.Run("should reject preflights for unregistered methods and headers", func(t *testing.T) {
+		server := newCORSTestServer(policy)
+
+		rr := preflight(server, "/api/products", "https://app.example.com", http.MethodPatch, "")
+		assert.Equal(t, http.StatusNoContent, rr.Code)
+		assert.Empty(t, rr.Header().Get("Access-Control-Allow-Origin"))
+
+		rr = preflight(server, "/api/products", "https://app.example.com", http.MethodPost, "X-Custom")
+		assert.Empty(t, rr.Header().Get("Access-Control-Allow-Origin"))
+	})
+
+	t.Run("should let preflights for unknown paths fall through", func(t *testing.T) {
+		server := newCORSTestServer(policy)
+
+		rr := preflight(server, "/api/missing", "https://app.example.com", http.MethodGet, "")
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+		assert.Empty(t, rr.Header().Get("Access-Control-Allow-Origin"))
+	})
+
+	t.Run("should add headers to actual requests", func(t *testing.T) {
+		server := newCORSTestServer(policy)
+
+		req := httptest.NewRequest(http.MethodGet, "/api/products", nil)
+		req.Header.Set("Origin", "https://app.example.com")
+		rr := httptest.NewRecorder()
+		server.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "https://app.example.com", rr.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, "X-Request-ID", rr.Header().Get("Access-Control-Expose-Headers"))
+
+		req.Header.Set("Origin", "https://evil.example.com")
+		rr = httptest.NewRecorder()
+		server.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Empty(t, rr.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, "Origin", rr.Header().Get("Vary"))
+	})
+
+	t.Run("should apply the route policy", func(t *testing.T) {
+		server := newCORSTestServer(policy)
+
+		rr := preflight(server, "/api/public", "https://anyone.test", http.MethodGet, "accept")
+		assert.Equal(t, "*", rr.Header().Get("Access-Control-Allow-Origin"))
+		assert.Empty(t, rr.Header().Get("Access-Control-Allow-Credentials"))
+		assert.Equal(t, "GET", rr.Header().Get("Access-Control-Allow-Methods"))
+
+		req := httptest.NewRequest(http.MethodGet, "/api/public", nil)
+		req.Header.Set("Origin", "https://anyone.test")
+		rr = httptest.NewRecorder()
+		server.ServeHTTP(rr, req)
+		assert.Equal(t, "*", rr.Header().Get("Access-Control-Allow-Origin"))
+	})
+
+	t.Run("should use the policy methods when set", func(t *testing.T) {
+		server := newCORSTestServer(CORSPolicy{
+			AllowedOrigins: []string{"*"},
+			AllowedMethods: []string{"get"},
+		})
+
+		rr := preflight(server, "/api/products", "https://anyone.test", http.MethodGet, "")
+		assert.Equal(t, "GET", rr.Header().Get("Access-Control-Allow-Methods"))
+
+		rr = preflight(server, "/api/products", "https://anyone.test", http.MethodPost, "")
+		assert.Empty(t, rr.Header().Get("Access-Control-Allow-Origin"))
+	})
+
+	t.Run("should pass through requests without an origin", func(t *testing.T) {
+		server := newCORSTestServer(policy)
+
+		rr := httptest.NewRecorder()
+		server.ServeHTTP(rr, httptest.NewRequest(http.MethodOptions, "/api/products", nil))
+
+		assert.NotEqual(t, http.StatusNoContent, rr.Code)
+		assert.Empty(t, rr.Header().Get("Access-Control-Allow-Origin"))
+	})
+
+	t.Run("should reject any origin with credentials", func(t *testing.T) {
+		logger, _ := initLog()
+		server := NewAPIServer(":8080", "/api", logger)
+
+		assert.PanicsWithValue(t, `api: CORS origin "*" cannot be combined with AllowCredentials`, func() {
+			server.CORS(CORSPolicy{AllowedOrigins: []string{"*"}, AllowCredentials: true})
+		})
+		assert.Panics(t, func() {
+			server.RegisterRoute("/public", func(w http.ResponseWriter, r *http.Request) {}, http.MethodGet).
+				CORS(CORSPolicy{AllowedOrigins: []string{"https://app.example.com", "*"}, AllowCredentials: true})
+		})
+	})
+
+	t.Run("should log route policies without the server middleware", func(t *testing.T) {
+		logger, logBuffer := initLog()
+		server := NewAPIServer(":8080", "/api", logger)
+		server.RegisterRoute("/public", func(w http.ResponseWriter, r *http.Request) {}, http.MethodGet).
+			CORS(CORSPolicy{AllowedOrigins: []string{"*"}})
+
+		server.checkCORS()
+		assert.Contains(t, logBuffer.String(), `"msg":"Route CORS policy has no effect without APIServer.CORS","route":"/api/public"`)
+
+		logBuffer.Reset()
+		server.Use(server.CORS(CORSPolicy{}))
+		server.checkCORS()
+		assert.NotContains(t, logBuffer.String(), "Route CORS policy")
+	})
+}
@@ -0,0 +1,79 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+type GroupOption func(*Group)
+
+// WithGroupMiddleware adds middleware to the group when it is created.
+func WithGroupMiddleware(middlewares ...Middleware) GroupOption {
+	return func(g *Group) {
+		g.Use(middlewares...)
+	}
+}
+
+// Group is a set of routes mounted under a common path prefix with its own
+// middleware stack. Middleware of a parent group runs before that of its
+// nested groups.
+type Group struct {
+	prefix string
+	router *mux.Router
+	logger *slog.Logger
+}
+
+func newGroup(router *mux.Router, prefix string, logger *slog.Logger) *Group {
+	return &Group{
+		prefix: prefix,
+		router: router,
+		logger: logger,
+	}
+}
+
+// Prefix returns the full path prefix of the group, including the prefixes
+// of its parents.
+func (g *Group) Prefix() string {
+	return g.prefix
+}
+
+func (g *Group) RegisterRoute(path string, handler func(http.ResponseWriter, *http.Request), methods ...string) *Route {
+	if path == "" {
+		g.logger.Error("Cannot register a route with an empty path")
+		return nil
+	}
+	if handler == nil {
+		g.logger.Error("Cannot register a route with a nil handler", "path", g.prefix+path)
+		return nil
+	}
+
+	route := newRoute(path, g.prefix+path, http.HandlerFunc(handler), methods)
+	g.router.Handle(path, route).Methods(methods...)
+	g.logger.Info("Route registered", "path", g.prefix+path, "methods", methods)
+	return route
+}
+
+// Use adds middleware to the group. It runs only for requests that match a
+// route registered on the group or one of its nested groups.
+func (g *Group) Use(middlewares ...Middleware) {
+	g.router.Use(toMuxMiddleware(middlewares)...)
+}
+
+// Group creates a nested group mounted under prefix. An empty prefix creates
+// a group that shares the parent's prefix but has its own middleware.
+func (g *Group) Group(prefix string, opts ...GroupOption) *Group {
+	var subrouter *mux.Router
+	if prefix == "" {
+		subrouter = g.router.NewRoute().Subrouter()
+	} else {
+		subrouter = g.router.PathPrefix(prefix).Subrouter()
+	}
+
+	group := newGroup(subrouter, g.prefix+prefix, g.logger)
+	for _, opt := range opts {
+		opt(group)
+	}
+	return group
+}
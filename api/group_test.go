@@ -0,0 +1,92 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGroup(t *testing.T) {
+	t.Run("should mount groups with different prefixes and middleware", func(t *testing.T) {
+		logger, logBuffer := initLog()
+		server := NewAPIServer(":8080", "/v1", logger)
+
+		var calls []string
+		products := server.Group("/products", WithGroupMiddleware(recordMiddleware(&calls, "products")))
+		admin := server.Group("/admin")
+		admin.Use(recordMiddleware(&calls, "admin"))
+
+		products.RegisterRoute("/{id}", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}, http.MethodGet)
+		admin.RegisterRoute("/users", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusAccepted)
+		}, http.MethodGet)
+
+		rr := httptest.NewRecorder()
+		server.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/products/1", nil))
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, []string{"products:before", "products:after"}, calls)
+
+		calls = nil
+		rr = httptest.NewRecorder()
+		server.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/admin/users", nil))
+		assert.Equal(t, http.StatusAccepted, rr.Code)
+		assert.Equal(t, []string{"admin:before", "admin:after"}, calls)
+		assert.Contains(t, logBuffer.String(), `"msg":"Route registered","path":"/v1/admin/users"`)
+	})
+
+	t.Run("should run parent middleware before nested group middleware", func(t *testing.T) {
+		logger, _ := initLog()
+		server := NewAPIServer(":8080", "/api", logger)
+
+		var calls []string
+		server.UseSubrouter(recordMiddleware(&calls, "subrouter"))
+		v1 := server.Group("/v1", WithGroupMiddleware(recordMiddleware(&calls, "v1")))
+		admin := v1.Group("/admin", WithGroupMiddleware(recordMiddleware(&calls, "admin")))
+		admin.RegisterRoute("/users", func(w http.ResponseWriter, r *http.Request) {
+			calls = append(calls, "handler")
+		}, http.MethodGet).Use(recordMiddleware(&calls, "route"))
+
+		assert.Equal(t, "/api/v1/admin", admin.Prefix())
+
+		rr := httptest.NewRecorder()
+		server.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/admin/users", nil))
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		expected := []string{
+			"subrouter:before", "v1:before", "admin:before", "route:before",
+			"handler",
+			"route:after", "admin:after", "v1:after", "subrouter:after",
+		}
+		assert.Equal(t, expected, calls)
+	})
+
+	t.Run("should not apply group middleware to sibling routes", func(t *testing.T) {
+		logger, _ := initLog()
+		server := NewAPIServer(":8080", "", logger)
+
+		var calls []string
+		server.Group("", WithGroupMiddleware(recordMiddleware(&calls, "group"))).
+			RegisterRoute("/private", func(w http.ResponseWriter, r *http.Request) {}, http.MethodGet)
+		server.RegisterRoute("/public", func(w http.ResponseWriter, r *http.Request) {}, http.MethodGet)
+
+		server.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/public", nil))
+		assert.Empty(t, calls)
+
+		server.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/private", nil))
+		assert.Equal(t, []string{"group:before", "group:after"}, calls)
+	})
+
+	t.Run("should return not found for unknown route in group", func(t *testing.T) {
+		logger, _ := initLog()
+		server := NewAPIServer(":8080", "", logger)
+		server.Group("/admin").RegisterRoute("/users", func(w http.ResponseWriter, r *http.Request) {}, http.MethodGet)
+
+		rr := httptest.NewRecorder()
+		server.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/admin/missing", nil))
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+}
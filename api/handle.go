@@ -0,0 +1,124 @@
+package api
+
+import (
+	"context"
+	"log/slog"
+	"mime"
+	"net/http"
+	"reflect"
+
+	"github.com/chlovec/rest-pack/utils"
+)
+
+// StatusCoder is implemented by responses that choose their status code.
+type StatusCoder interface {
+	StatusCode() int
+}
+
+// Headerer is implemented by responses that set response headers, such as
+// Location.
+type Headerer interface {
+	Headers() http.Header
+}
+
+type HandleOption func(*handleConfig)
+
+// WithSuccessStatus sets the status of successful responses, which defaults
+// to 200. Responses implementing StatusCoder override it.
+func WithSuccessStatus(status int) HandleOption {
+	return func(c *handleConfig) {
+		c.status = status
+	}
+}
+
+// WithErrorLogger logs errors that map to 5xx responses. It defaults to
+// slog.Default().
+func WithErrorLogger(logger *slog.Logger) HandleOption {
+	return func(c *handleConfig) {
+		c.logger = logger
+	}
+}
+
+// WithJSONOptions configures how the request body is decoded, for example
+// to reject unknown fields.
+func WithJSONOptions(opts ...utils.JSONOption) HandleOption {
+	return func(c *handleConfig) {
+		c.decoder = utils.NewJSONDecoder(opts...)
+	}
+}
+
+type handleConfig struct {
+	status  int
+	logger  *slog.Logger
+	decoder *utils.JSONDecoder
+}
+
+// Handle adapts a typed function to a handler that can be registered with
+// RegisterRoute. A struct Req is filled from the JSON body, then bound and
+// validated with utils.Bind. Errors returned by fn are
+// written with utils.WriteError, and the response is encoded as JSON. A 204
+// response has no body.
+func Handle[Req, Resp any](fn func(ctx context.Context, req Req) (Resp, error), opts ...HandleOption) func(http.ResponseWriter, *http.Request) {
+	c := &handleConfig{status: http.StatusOK, logger: slog.Default(), decoder: utils.NewJSONDecoder()}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req Req
+		if err := decodeRequest(r, c.decoder, &req); err != nil {
+			utils.WriteError(w, err)
+			return
+		}
+
+		resp, err := fn(r.Context(), req)
+		if err != nil {
+			if utils.ProblemFromError(err).Status >= http.StatusInternalServerError {
+				c.logger.ErrorContext(r.Context(), "Handler failed", "error", err)
+			}
+			utils.WriteError(w, err)
+			return
+		}
+
+		status := c.status
+		if coder, ok := any(resp).(StatusCoder); ok {
+			status = coder.StatusCode()
+		}
+		if headerer, ok := any(resp).(Headerer); ok {
+			for name, values := range headerer.Headers() {
+				w.Header()[name] = values
+			}
+		}
+		if status == http.StatusNoContent {
+			w.WriteHeader(status)
+			return
+		}
+		utils.WriteJSON(w, status, resp)
+	}
+}
+
+// decodeRequest fills req, a pointer to the request value, and validates it.
+// Values that are not structs are only decoded from the body.
+func decodeRequest(r *http.Request, decoder *utils.JSONDecoder, req any) error {
+	if hasBody(r) && !isForm(r) {
+		if err := decoder.Decode(r, req); err != nil {
+			return err
+		}
+	}
+
+	if reflect.TypeOf(req).Elem().Kind() != reflect.Struct {
+		return nil
+	}
+	return utils.Bind(r, req)
+}
+
+func hasBody(r *http.Request) bool {
+	return r.Body != nil && r.Body != http.NoBody && r.ContentLength != 0
+}
+
+// isForm reports whether the body is a form, which is bound by form tags
+// instead of being decoded as JSON.
+func isForm(r *http.Request) bool {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return mediaType == "application/x-www-form-urlencoded" || mediaType == "multipart/form-data"
+}
@@ -0,0 +1,178 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/chlovec/rest-pack/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+type pagination struct {
+	PageSize int `query:"pagesize" validate:"omitempty,max=100"`
+}
+
+type updateProductRequest struct {
+	pagination
+	ID      int64    `path:"id" validate:"gt=0"`
+	Name    string   `json:"name" validate:"required"`
+	Price   float64  `json:"price"`
+	DryRun  bool     `query:"dryRun"`
+	Tags    []string `query:"tag"`
+	Tenant  string   `header:"X-Tenant"`
+	Version uint8    `header:"X-Version"`
+}
+
+type productResponse struct {
+	ID       int64    `json:"id"`
+	Name     string   `json:"name"`
+	PageSize int      `json:"pageSize"`
+	DryRun   bool     `json:"dryRun"`
+	Tags     []string `json:"tags"`
+	Tenant   string   `json:"tenant"`
+	Version  uint8    `json:"version"`
+}
+
+type createdResponse struct {
+	ID int64 `json:"id"`
+}
+
+func (createdResponse) StatusCode() int {
+	return http.StatusCreated
+}
+
+func (r createdResponse) Headers() http.Header {
+	return http.Header{"Location": {"/api/products/1"}}
+}
+
+func TestHandle(t *testing.T) {
+	logger, buf := initLog()
+	server := NewAPIServer(":8080", "/api", logger)
+
+	server.RegisterRoute("/products/{id}", Handle(func(ctx context.Context, req updateProductRequest) (productResponse, error) {
+		switch req.Name {
+		case "missing":
+			return productResponse{}, sql.ErrNoRows
+		case "conflict":
+			return productResponse{}, utils.NewAPIError(http.StatusConflict, "name taken")
+		case "broken":
+			return productResponse{}, errors.New("connection refused")
+		}
+		return productResponse{
+			ID:       req.ID,
+			Name:     req.Name,
+			PageSize: req.PageSize,
+			DryRun:   req.DryRun,
+			Tags:     req.Tags,
+			Tenant:   req.Tenant,
+			Version:  req.Version,
+		}, nil
+	}, WithErrorLogger(logger)), http.MethodPut)
+	server.RegisterRoute("/products", Handle(func(ctx context.Context, req struct{}) (createdResponse, error) {
+		return createdResponse{ID: 1}, nil
+	}), http.MethodPost)
+	server.RegisterRoute("/products/{id}", Handle(func(ctx context.Context, req struct {
+		ID int `path:"id"`
+	}) (*struct{}, error) {
+		return nil, nil
+	}, WithSuccessStatus(http.StatusNoContent)), http.MethodDelete)
+
+	serve := func(method, target, body string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		for name, values := range header {
+			req.Header[name] = values
+		}
+		rr := httptest.NewRecorder()
+		server.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("should bind, call and encode", func(t *testing.T) {
+		rr := serve(http.MethodPut, "/api/products/7?pagesize=20&dryRun=true&tag=a&tag=b",
+			`{"id": 99, "name": "Widget", "price": 9.5}`,
+			http.Header{"X-Tenant": {"acme"}, "X-Version": {"2"}})
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.JSONEq(t, `{"id":7,"name":"Widget","pageSize":20,"dryRun":true,"tags":["a","b"],"tenant":"acme","version":2}`, rr.Body.String())
+	})
+
+	t.Run("should use the status and headers of the response", func(t *testing.T) {
+		rr := serve(http.MethodPost, "/api/products", "", nil)
+		assert.Equal(t, http.StatusCreated, rr.Code)
+		assert.Equal(t, "/api/products/1", rr.Header().Get("Location"))
+
+		rr = serve(http.MethodDelete, "/api/products/1", "", nil)
+		assert.Equal(t, http.StatusNoContent, rr.Code)
+		assert.Empty(t, rr.Body.String())
+	})
+
+	t.Run("should reject invalid requests", func(t *testing.T) {
+		rr := serve(http.MethodPut, "/api/products/7", `{"name":`, nil)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Contains(t, rr.Body.String(), "invalid JSON")
+
+		rr = serve(http.MethodPut, "/api/products/abc", `{"name":"Widget"}`, nil)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.JSONEq(t, `{"error":"Validation Error","details":[{"code":"type","field":"id","message":"invalid path parameter 'id': \"abc\" is not a valid integer"}]}`, rr.Body.String())
+
+		rr = serve(http.MethodPut, "/api/products/7?dryRun=maybe", `{"name":"Widget"}`, nil)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+		rr = serve(http.MethodPut, "/api/products/7", `{"price": 1}`, nil)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.JSONEq(t, `{"error":"Validation Error","details":[{"code":"required","field":"name","message":"'name' is required"}]}`, rr.Body.String())
+
+		rr = serve(http.MethodPut, "/api/products/0?pagesize=1000", `{"name":"Widget"}`, nil)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Contains(t, rr.Body.String(), `"field":"pagesize"`)
+	})
+
+	t.Run("should map handler errors", func(t *testing.T) {
+		rr := serve(http.MethodPut, "/api/products/7", `{"name":"missing"}`, nil)
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+
+		rr = serve(http.MethodPut, "/api/products/7", `{"name":"conflict"}`, nil)
+		assert.Equal(t, http.StatusConflict, rr.Code)
+		assert.JSONEq(t, `{"error":"name taken"}`, rr.Body.String())
+		assert.NotContains(t, buf.String(), "Handler failed")
+
+		rr = serve(http.MethodPut, "/api/products/7", `{"name":"broken"}`, nil)
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+		assert.JSONEq(t, `{"error":"Internal Server Error"}`, rr.Body.String())
+		assert.Contains(t, buf.String(), `"msg":"Handler failed","error":"connection refused"`)
+	})
+
+	t.Run("should decode non-struct requests from the body", func(t *testing.T) {
+		handler := Handle(func(ctx context.Context, names []string) (int, error) {
+			return len(names), nil
+		})
+
+		rr := httptest.NewRecorder()
+		handler(rr, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`["a","b"]`)))
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "2\n", rr.Body.String())
+	})
+
+	t.Run("should decode with json options", func(t *testing.T) {
+		handler := Handle(func(ctx context.Context, req updateProductRequest) (string, error) {
+			return req.Name, nil
+		}, WithJSONOptions(utils.WithRequireContentType(), utils.WithDisallowUnknownFields()))
+
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name":"Widget","id":1}`))
+		rr := httptest.NewRecorder()
+		handler(rr, req)
+		assert.Equal(t, http.StatusUnsupportedMediaType, rr.Code)
+
+		req = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name":"Widget","colour":"red"}`))
+		req.Header.Set("Content-Type", "application/json")
+		rr = httptest.NewRecorder()
+		handler(rr, req)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.JSONEq(t, `{"error":"invalid JSON: unknown field \"colour\""}`, rr.Body.String())
+	})
+}
@@ -0,0 +1,233 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/chlovec/rest-pack/utils"
+)
+
+const (
+	defaultCheckTimeout  = 2 * time.Second
+	defaultCheckCacheTTL = time.Second
+
+	HealthStatusOK           = "ok"
+	HealthStatusFail         = "fail"
+	HealthStatusShuttingDown = "shutting_down"
+)
+
+// Checker reports whether a dependency is healthy.
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+type CheckerFunc func(ctx context.Context) error
+
+func (f CheckerFunc) Check(ctx context.Context) error {
+	return f(ctx)
+}
+
+// DBChecker returns a Checker that pings db, such as the one returned by
+// db.InitDB.
+func DBChecker(db *sql.DB) Checker {
+	return CheckerFunc(db.PingContext)
+}
+
+type HealthOption func(*Health)
+
+// WithCheckTimeout sets how long a single check may run before it is
+// reported as failing.
+func WithCheckTimeout(timeout time.Duration) HealthOption {
+	return func(h *Health) {
+		if timeout > 0 {
+			h.timeout = timeout
+		}
+	}
+}
+
+// WithCheckCacheTTL sets how long a check result is reused before the check
+// runs again. Zero disables caching.
+func WithCheckCacheTTL(ttl time.Duration) HealthOption {
+	return func(h *Health) {
+		if ttl >= 0 {
+			h.cacheTTL = ttl
+		}
+	}
+}
+
+// Health serves liveness and readiness endpoints backed by named checks.
+type Health struct {
+	mu           sync.Mutex
+	liveness     []*namedCheck
+	readiness    []*namedCheck
+	cache        map[*namedCheck]CheckResult
+	timeout      time.Duration
+	cacheTTL     time.Duration
+	shuttingDown atomic.Bool
+}
+
+type namedCheck struct {
+	name    string
+	checker Checker
+}
+
+// CheckResult is the outcome of a single check.
+type CheckResult struct {
+	Status    string `json:"status"`
+	Error     string `json:"error,omitempty"`
+	checkedAt time.Time
+}
+
+// HealthReport is the JSON body served by the health endpoints.
+type HealthReport struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+}
+
+func NewHealth(opts ...HealthOption) *Health {
+	h := &Health{
+		cache:    make(map[*namedCheck]CheckResult),
+		timeout:  defaultCheckTimeout,
+		cacheTTL: defaultCheckCacheTTL,
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// AddLivenessCheck registers a check served by /healthz. Liveness checks
+// should only fail when the process needs to be restarted.
+func (h *Health) AddLivenessCheck(name string, checker Checker) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.liveness = append(h.liveness, &namedCheck{name: name, checker: checker})
+}
+
+// AddReadinessCheck registers a check served by /readyz.
+func (h *Health) AddReadinessCheck(name string, checker Checker) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.readiness = append(h.readiness, &namedCheck{name: name, checker: checker})
+}
+
+// SetShuttingDown makes readiness fail without running any check. The server
+// calls it as soon as a graceful shutdown begins.
+func (h *Health) SetShuttingDown() {
+	h.shuttingDown.Store(true)
+}
+
+func (h *Health) LivenessHandler(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	checks := h.liveness
+	h.mu.Unlock()
+
+	h.writeReport(w, h.run(r.Context(), checks))
+}
+
+func (h *Health) ReadinessHandler(w http.ResponseWriter, r *http.Request) {
+	if h.shuttingDown.Load() {
+		h.writeReport(w, HealthReport{Status: HealthStatusShuttingDown})
+		return
+	}
+
+	h.mu.Lock()
+	checks := h.readiness
+	h.mu.Unlock()
+
+	h.writeReport(w, h.run(r.Context(), checks))
+}
+
+func (h *Health) writeReport(w http.ResponseWriter, report HealthReport) {
+	status := http.StatusOK
+	if report.Status != HealthStatusOK {
+		status = http.StatusServiceUnavailable
+	}
+	utils.WriteJSON(w, status, report)
+}
+
+// run executes checks concurrently, reusing results younger than the cache
+// TTL.
+func (h *Health) run(ctx context.Context, checks []*namedCheck) HealthReport {
+	report := HealthReport{Status: HealthStatusOK}
+	if len(checks) == 0 {
+		return report
+	}
+
+	results := make([]CheckResult, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		if result, ok := h.cached(check); ok {
+			results[i] = result
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = h.check(ctx, check)
+		}(i)
+	}
+	wg.Wait()
+
+	report.Checks = make(map[string]CheckResult, len(checks))
+	for i, result := range results {
+		report.Checks[checks[i].name] = result
+		if result.Status != HealthStatusOK {
+			report.Status = HealthStatusFail
+		}
+	}
+	return report
+}
+
+func (h *Health) check(parent context.Context, check *namedCheck) CheckResult {
+	ctx, cancel := context.WithTimeout(parent, h.timeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- check.checker.Check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	result := CheckResult{Status: HealthStatusOK, checkedAt: time.Now()}
+	if err != nil {
+		result.Status = HealthStatusFail
+		result.Error = err.Error()
+	}
+
+	// A failure caused by the caller giving up says nothing about the
+	// dependency, so it is not cached.
+	if err != nil && parent.Err() != nil {
+		return result
+	}
+
+	h.mu.Lock()
+	h.cache[check] = result
+	h.mu.Unlock()
+	return result
+}
+
+func (h *Health) cached(check *namedCheck) (CheckResult, bool) {
+	if h.cacheTTL == 0 {
+		return CheckResult{}, false
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	result, ok := h.cache[check]
+	if !ok || time.Since(result.checkedAt) >= h.cacheTTL {
+		return CheckResult{}, false
+	}
+	return result, true
+}
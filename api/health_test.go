@@ -0,0 +1,156 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealth(t *testing.T) {
+	t.Run("should report healthy with no checks", func(t *testing.T) {
+		logger, _ := initLog()
+		server := NewAPIServer(":8080", "/api", logger, WithHealth(NewHealth()))
+
+		for _, path := range []string{"/healthz", "/readyz"} {
+			rr := httptest.NewRecorder()
+			server.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+			assert.Equal(t, http.StatusOK, rr.Code)
+			assert.JSONEq(t, `{"status": "ok"}`, rr.Body.String())
+		}
+	})
+
+	t.Run("should report failing readiness check", func(t *testing.T) {
+		health := NewHealth()
+		health.AddReadinessCheck("cache", CheckerFunc(func(ctx context.Context) error {
+			return nil
+		}))
+		health.AddReadinessCheck("queue", CheckerFunc(func(ctx context.Context) error {
+			return errors.New("connection refused")
+		}))
+
+		rr := httptest.NewRecorder()
+		health.ReadinessHandler(rr, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+		assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+		expected := `{
+			"status": "fail",
+			"checks": {
+				"cache": {"status": "ok"},
+				"queue": {"status": "fail", "error": "connection refused"}
+			}
+		}`
+		assert.JSONEq(t, expected, rr.Body.String())
+
+		rr = httptest.NewRecorder()
+		health.LivenessHandler(rr, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("should run checks concurrently and time out slow checks", func(t *testing.T) {
+		health := NewHealth(WithCheckTimeout(100 * time.Millisecond))
+		for _, name := range []string{"a", "b", "c"} {
+			health.AddLivenessCheck(name, CheckerFunc(func(ctx context.Context) error {
+				time.Sleep(time.Second)
+				return nil
+			}))
+		}
+
+		start := time.Now()
+		rr := httptest.NewRecorder()
+		health.LivenessHandler(rr, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+		assert.Less(t, time.Since(start), 500*time.Millisecond)
+		assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+		assert.Contains(t, rr.Body.String(), context.DeadlineExceeded.Error())
+	})
+
+	t.Run("should cache check results", func(t *testing.T) {
+		var calls atomic.Int32
+		checker := CheckerFunc(func(ctx context.Context) error {
+			calls.Add(1)
+			return nil
+		})
+
+		cached := NewHealth(WithCheckCacheTTL(time.Minute))
+		cached.AddReadinessCheck("db", checker)
+		uncached := NewHealth(WithCheckCacheTTL(0))
+		uncached.AddReadinessCheck("db", checker)
+
+		for i := 0; i < 3; i++ {
+			cached.ReadinessHandler(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		}
+		assert.Equal(t, int32(1), calls.Load())
+
+		for i := 0; i < 3; i++ {
+			uncached.ReadinessHandler(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		}
+		assert.Equal(t, int32(4), calls.Load())
+	})
+
+	t.Run("should not cache failures of cancelled probes", func(t *testing.T) {
+		health := NewHealth(WithCheckCacheTTL(time.Minute))
+		health.AddReadinessCheck("db", CheckerFunc(func(ctx context.Context) error {
+			return ctx.Err()
+		}))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		health.ReadinessHandler(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/readyz", nil).WithContext(ctx))
+
+		rr := httptest.NewRecorder()
+		health.ReadinessHandler(rr, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("should fail readiness once shutdown begins", func(t *testing.T) {
+		logger, _ := initLog()
+		addr := freeAddr(t)
+		health := NewHealth()
+		apiServer := NewAPIServer(addr, "", logger, WithSignals(), WithHealth(health))
+
+		var readyStatus int
+		apiServer.OnShutdown("probe", 0, func(ctx context.Context) error {
+			rr := httptest.NewRecorder()
+			apiServer.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+			readyStatus = rr.Code
+			return nil
+		})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		errChan := make(chan error, 1)
+		go func() {
+			errChan <- apiServer.Run(ctx)
+		}()
+
+		waitForServer(t, addr)
+		resp, err := http.Get("http://" + addr + "/readyz")
+		assert.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		cancel()
+		assert.NoError(t, <-errChan)
+		assert.Equal(t, http.StatusServiceUnavailable, readyStatus)
+	})
+
+	t.Run("should ping the database", func(t *testing.T) {
+		mockDB, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		mock.ExpectPing()
+		mock.ExpectPing().WillReturnError(errors.New("ping failed"))
+
+		checker := DBChecker(mockDB)
+		assert.NoError(t, checker.Check(context.Background()))
+		assert.EqualError(t, checker.Check(context.Background()), "ping failed")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,124 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const defaultHookTimeout = 5 * time.Second
+
+// Hook is a function run by the server when it starts or shuts down.
+type Hook func(ctx context.Context) error
+
+type hook struct {
+	name    string
+	timeout time.Duration
+	fn      Hook
+	// seq orders start and shutdown hooks by registration.
+	seq int
+}
+
+// OnStart registers a hook that runs before the server starts listening.
+// Start hooks run in registration order and the first failure aborts the
+// start, running only the shutdown hooks registered before the failed hook.
+// A non-positive timeout uses the default of 5 seconds.
+func (s *APIServer) OnStart(name string, timeout time.Duration, fn Hook) {
+	s.startHooks = s.addHook(s.startHooks, name, timeout, fn)
+}
+
+// OnShutdown registers a hook that runs once the server has stopped and
+// in-flight requests have been drained. Shutdown hooks run in reverse
+// registration order, like deferred calls, and all of them run even if some
+// fail. A non-positive timeout uses the default of 5 seconds.
+func (s *APIServer) OnShutdown(name string, timeout time.Duration, fn Hook) {
+	s.shutdownHooks = s.addHook(s.shutdownHooks, name, timeout, fn)
+}
+
+func (s *APIServer) addHook(hooks []hook, name string, timeout time.Duration, fn Hook) []hook {
+	if fn == nil {
+		s.logger.Error("Cannot register a hook with a nil function", "hook", name)
+		return hooks
+	}
+	if timeout <= 0 {
+		timeout = defaultHookTimeout
+	}
+
+	s.hookSeq++
+	return append(hooks, hook{name: name, timeout: timeout, fn: fn, seq: s.hookSeq})
+}
+
+// runStartHooks returns the registration sequence of the failed hook along
+// with its error.
+func (s *APIServer) runStartHooks(ctx context.Context) (int, error) {
+	for _, h := range s.startHooks {
+		if err := runHook(ctx, h); err != nil {
+			s.logger.Error("Start hook failed", "error", err)
+			return h.seq, &ServerError{Phase: PhaseStart, Err: err}
+		}
+	}
+
+	return 0, nil
+}
+
+// runShutdownHooks runs the shutdown hooks registered before seq.
+func (s *APIServer) runShutdownHooks(seq int) error {
+	var errs []error
+	for i := len(s.shutdownHooks) - 1; i >= 0; i-- {
+		if s.shutdownHooks[i].seq >= seq {
+			continue
+		}
+		if err := runHook(context.Background(), s.shutdownHooks[i]); err != nil {
+			s.logger.Error("Shutdown hook failed", "error", err)
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return &ServerError{Phase: PhaseShutdown, Err: errors.Join(errs...)}
+}
+
+// runHook runs h and gives up once its timeout expires, even if the hook
+// ignores its context.
+func runHook(parent context.Context, h hook) error {
+	ctx, cancel := context.WithTimeout(parent, h.timeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- h.fn(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("%s: %w", h.name, err)
+		}
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("%s: %w", h.name, ctx.Err())
+	}
+}
+
+// joinErrors joins the non-nil errors, returning a lone error unchanged so
+// callers can still type-assert a single *ServerError.
+func joinErrors(errs ...error) error {
+	var nonNil []error
+	for _, err := range errs {
+		if err != nil {
+			nonNil = append(nonNil, err)
+		}
+	}
+
+	switch len(nonNil) {
+	case 0:
+		return nil
+	case 1:
+		return nonNil[0]
+	default:
+		return errors.Join(nonNil...)
+	}
+}
@@ -0,0 +1,169 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLifecycleHooks(t *testing.T) {
+	t.Run("should run start hooks in order and shutdown hooks in reverse", func(t *testing.T) {
+		logger, _ := initLog()
+		addr := freeAddr(t)
+		apiServer := NewAPIServer(addr, "", logger, WithSignals())
+
+		var mu sync.Mutex
+		var calls []string
+		record := func(name string) Hook {
+			return func(ctx context.Context) error {
+				mu.Lock()
+				defer mu.Unlock()
+				calls = append(calls, name)
+				return nil
+			}
+		}
+		apiServer.OnStart("start-1", 0, record("start-1"))
+		apiServer.OnStart("start-2", 0, record("start-2"))
+		apiServer.OnShutdown("shutdown-1", 0, record("shutdown-1"))
+		apiServer.OnShutdown("shutdown-2", 0, record("shutdown-2"))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		errChan := make(chan error, 1)
+		go func() {
+			errChan <- apiServer.Run(ctx)
+		}()
+
+		waitForServer(t, addr)
+		cancel()
+
+		assert.NoError(t, <-errChan)
+		assert.Equal(t, []string{"start-1", "start-2", "shutdown-2", "shutdown-1"}, calls)
+	})
+
+	t.Run("should run shutdown hooks after in-flight requests are drained", func(t *testing.T) {
+		logger, _ := initLog()
+		addr := freeAddr(t)
+		apiServer := NewAPIServer(addr, "", logger, WithSignals())
+
+		started := make(chan struct{})
+		var requestDone bool
+		apiServer.RegisterRoute("/slow", func(w http.ResponseWriter, r *http.Request) {
+			close(started)
+			time.Sleep(200 * time.Millisecond)
+			requestDone = true
+		}, http.MethodGet)
+
+		var doneAtShutdown bool
+		apiServer.OnShutdown("check", 0, func(ctx context.Context) error {
+			doneAtShutdown = requestDone
+			return nil
+		})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		errChan := make(chan error, 1)
+		go func() {
+			errChan <- apiServer.Run(ctx)
+		}()
+
+		waitForServer(t, addr)
+		go http.Get("http://" + addr + "/slow")
+		<-started
+		cancel()
+
+		assert.NoError(t, <-errChan)
+		assert.True(t, doneAtShutdown)
+	})
+
+	t.Run("should abort start and run earlier shutdown hooks when a start hook fails", func(t *testing.T) {
+		logger, logBuffer := initLog()
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		assert.NoError(t, err)
+		apiServer := NewAPIServer("", "", logger, WithSignals(), WithListeners(listener))
+
+		var secondCalled, shutdownCalled, cleanupCalled bool
+		apiServer.OnShutdown("pool", 0, func(ctx context.Context) error {
+			shutdownCalled = true
+			return nil
+		})
+		apiServer.OnStart("migrate", 0, func(ctx context.Context) error {
+			return errors.New("migration failed")
+		})
+		apiServer.OnShutdown("cleanup", 0, func(ctx context.Context) error {
+			cleanupCalled = true
+			return nil
+		})
+		apiServer.OnStart("second", 0, func(ctx context.Context) error {
+			secondCalled = true
+			return nil
+		})
+
+		err = apiServer.Run(context.Background())
+
+		var serverErr *ServerError
+		assert.ErrorAs(t, err, &serverErr)
+		assert.Equal(t, PhaseStart, serverErr.Phase)
+		assert.EqualError(t, err, "start: migrate: migration failed")
+		assert.False(t, secondCalled)
+		assert.True(t, shutdownCalled)
+		assert.False(t, cleanupCalled)
+		assert.NotContains(t, logBuffer.String(), "Starting server")
+
+		_, err = listener.Accept()
+		assert.ErrorIs(t, err, net.ErrClosed)
+	})
+
+	t.Run("should aggregate shutdown hook errors and timeouts", func(t *testing.T) {
+		logger, _ := initLog()
+		addr := freeAddr(t)
+		apiServer := NewAPIServer(addr, "", logger, WithSignals())
+
+		firstErr := errors.New("flush failed")
+		var lastCalled bool
+		apiServer.OnShutdown("last", 0, func(ctx context.Context) error {
+			lastCalled = true
+			return nil
+		})
+		apiServer.OnShutdown("flush", 0, func(ctx context.Context) error {
+			return firstErr
+		})
+		apiServer.OnShutdown("workers", 50*time.Millisecond, func(ctx context.Context) error {
+			time.Sleep(time.Second)
+			return nil
+		})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		errChan := make(chan error, 1)
+		go func() {
+			errChan <- apiServer.Run(ctx)
+		}()
+
+		waitForServer(t, addr)
+		cancel()
+
+		err := <-errChan
+		var serverErr *ServerError
+		assert.ErrorAs(t, err, &serverErr)
+		assert.Equal(t, PhaseShutdown, serverErr.Phase)
+		assert.ErrorIs(t, err, firstErr)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.True(t, lastCalled)
+	})
+
+	t.Run("should ignore nil hooks", func(t *testing.T) {
+		logger, logBuffer := initLog()
+		apiServer := NewAPIServer(":8080", "", logger)
+
+		apiServer.OnStart("nil", 0, nil)
+		apiServer.OnShutdown("nil", 0, nil)
+
+		assert.Empty(t, apiServer.startHooks)
+		assert.Empty(t, apiServer.shutdownHooks)
+		assert.Contains(t, logBuffer.String(), `"msg":"Cannot register a hook with a nil function","hook":"nil"`)
+	})
+}
@@ -0,0 +1,22 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/chlovec/rest-pack/utils"
+)
+
+// Localize returns middleware that negotiates the locale of each request
+// from its Accept-Language header and localizes its error messages.
+func Localize(catalog *utils.Catalog) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			trans := catalog.Negotiate(r.Header.Get("Accept-Language"))
+
+			w.Header().Add("Vary", "Accept-Language")
+			w.Header().Set("Content-Language", strings.ReplaceAll(trans.Locale(), "_", "-"))
+			next.ServeHTTP(utils.WithTranslator(w, trans), r.WithContext(utils.ContextWithTranslator(r.Context(), trans)))
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/chlovec/rest-pack/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalize(t *testing.T) {
+	catalog, err := utils.NewCatalog(utils.LocaleEnglish, utils.LocaleFrench, utils.LocaleSpanish)
+	require.NoError(t, err)
+
+	logger, _ := initLog()
+	server := NewAPIServer(":8080", "/api", logger)
+	server.Use(Localize(catalog))
+
+	var locale string
+	server.RegisterRoute("/products", Handle(func(ctx context.Context, req struct {
+		Name string `json:"name" validate:"required"`
+	}) (string, error) {
+		locale = utils.TranslatorFromContext(ctx).Locale()
+		return req.Name, nil
+	}), http.MethodPost)
+	server.RegisterRoute("/products/{id}", func(w http.ResponseWriter, r *http.Request) {
+		utils.WriteNotFound(w, "", nil)
+	}, http.MethodGet)
+
+	serve := func(method, target, body, acceptLanguage string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Accept-Language", acceptLanguage)
+		rr := httptest.NewRecorder()
+		server.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("should negotiate the locale", func(t *testing.T) {
+		rr := serve(http.MethodPost, "/api/products", `{"name":"Widget"}`, "es-MX,es;q=0.9")
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "es", locale)
+		assert.Equal(t, "es", rr.Header().Get("Content-Language"))
+		assert.Equal(t, "Accept-Language", rr.Header().Get("Vary"))
+	})
+
+	t.Run("should localize error responses", func(t *testing.T) {
+		rr := serve(http.MethodPost, "/api/products", `{}`, "fr-FR")
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.JSONEq(t, `{"error":"Erreur de validation","details":[{"code":"required","field":"name","message":"name est un champ obligatoire"}]}`, rr.Body.String())
+
+		rr = serve(http.MethodGet, "/api/products/1", "", "es")
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+		assert.JSONEq(t, `{"error":"No encontrado"}`, rr.Body.String())
+	})
+
+	t.Run("should fall back to the default locale", func(t *testing.T) {
+		rr := serve(http.MethodGet, "/api/products/1", "", "de")
+
+		assert.Equal(t, "en", rr.Header().Get("Content-Language"))
+		assert.JSONEq(t, `{"error":"Not Found"}`, rr.Body.String())
+	})
+}
@@ -0,0 +1,169 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+)
+
+// listenFdsStart is the first file descriptor passed by systemd socket
+// activation.
+var listenFdsStart = 3
+
+type listenAddr struct {
+	network string
+	address string
+}
+
+// WithListeners serves on pre-opened listeners, such as one bound to port 0
+// in a test. The server takes ownership and closes them on shutdown.
+func WithListeners(listeners ...net.Listener) Option {
+	return func(s *APIServer) {
+		s.listeners = append(s.listeners, listeners...)
+	}
+}
+
+// WithListen adds an address to listen on, e.g. ("unix", "/run/api.sock") or
+// ("tcp", ":9090"). A stale Unix socket file at address is removed first.
+func WithListen(network, address string) Option {
+	return func(s *APIServer) {
+		s.listenAddrs = append(s.listenAddrs, listenAddr{network: network, address: address})
+	}
+}
+
+// WithSystemdListeners serves on the sockets inherited through systemd socket
+// activation (LISTEN_PID and LISTEN_FDS). It is a no-op when the process was
+// not socket-activated.
+func WithSystemdListeners() Option {
+	return func(s *APIServer) {
+		s.systemdListeners = true
+	}
+}
+
+// Ready returns a channel that is closed once the server is listening on all
+// of its addresses.
+func (s *APIServer) Ready() <-chan struct{} {
+	return s.ready
+}
+
+// Addrs returns the addresses the server is listening on. It is empty until
+// Ready is closed.
+func (s *APIServer) Addrs() []net.Addr {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]net.Addr(nil), s.addrs...)
+}
+
+// closeListeners closes the listeners passed with WithListeners or
+// inherited from systemd when the server gives up before serving them.
+func (s *APIServer) closeListeners() {
+	listeners := s.listeners
+	if s.systemdListeners {
+		inherited, _ := systemdListeners()
+		listeners = append(listeners, inherited...)
+	}
+	for _, l := range listeners {
+		l.Close()
+	}
+}
+
+// listen opens every configured listener. The address passed to NewAPIServer
+// is used when it is set or when no other listener is configured.
+func (s *APIServer) listen() ([]net.Listener, error) {
+	listeners := append([]net.Listener(nil), s.listeners...)
+	closeAll := func() {
+		for _, l := range listeners {
+			l.Close()
+		}
+	}
+
+	if s.systemdListeners {
+		inherited, err := systemdListeners()
+		if err != nil {
+			closeAll()
+			return nil, err
+		}
+		listeners = append(listeners, inherited...)
+	}
+
+	addrs := s.listenAddrs
+	if s.server.Addr != "" || (len(listeners) == 0 && len(addrs) == 0) {
+		addr := s.server.Addr
+		if addr == "" {
+			addr = ":http"
+		}
+		addrs = append([]listenAddr{{network: "tcp", address: addr}}, addrs...)
+	}
+
+	for _, addr := range addrs {
+		if addr.network == "unix" {
+			removeStaleSocket(addr.address)
+		}
+		l, err := net.Listen(addr.network, addr.address)
+		if err != nil {
+			closeAll()
+			return nil, err
+		}
+		listeners = append(listeners, l)
+	}
+
+	return listeners, nil
+}
+
+func (s *APIServer) setListening(listeners []net.Listener) {
+	s.mu.Lock()
+	for _, l := range listeners {
+		s.addrs = append(s.addrs, l.Addr())
+		s.logger.Info("Listening", "network", l.Addr().Network(), "addr", l.Addr().String())
+	}
+	s.mu.Unlock()
+
+	s.readyOnce.Do(func() {
+		close(s.ready)
+	})
+}
+
+func removeStaleSocket(path string) {
+	if info, err := os.Stat(path); err == nil && info.Mode()&os.ModeSocket != 0 {
+		os.Remove(path)
+	}
+}
+
+func systemdListeners() ([]net.Listener, error) {
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, nil
+	}
+	count, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || count <= 0 {
+		return nil, nil
+	}
+
+	// Unset the variables so child processes do not inherit them.
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+
+	var listeners []net.Listener
+	var errs []error
+	for fd := listenFdsStart; fd < listenFdsStart+count; fd++ {
+		file := os.NewFile(uintptr(fd), "LISTEN_FD_"+strconv.Itoa(fd))
+		l, err := net.FileListener(file)
+		file.Close()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("inherited fd %d: %w", fd, err))
+			continue
+		}
+		listeners = append(listeners, l)
+	}
+
+	if len(errs) > 0 {
+		for _, l := range listeners {
+			l.Close()
+		}
+		return nil, errors.Join(errs...)
+	}
+	return listeners, nil
+}
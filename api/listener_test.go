@@ -0,0 +1,145 @@
+package api
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListeners(t *testing.T) {
+	t.Run("should report the bound address when listening on port 0", func(t *testing.T) {
+		apiServer := newListenerTestServer(t, "127.0.0.1:0")
+
+		addrs := runUntilCleanup(t, apiServer)
+
+		assert.Len(t, addrs, 1)
+		assert.NotEqual(t, "127.0.0.1:0", addrs[0].String())
+		assert.Equal(t, "hello", get(t, http.DefaultClient, "http://"+addrs[0].String()+"/hello"))
+	})
+
+	t.Run("should serve on a Unix socket and a TCP port at the same time", func(t *testing.T) {
+		socket := filepath.Join(t.TempDir(), "api.sock")
+		apiServer := newListenerTestServer(t, "127.0.0.1:0", WithListen("unix", socket))
+
+		addrs := runUntilCleanup(t, apiServer)
+
+		assert.Len(t, addrs, 2)
+		assert.Equal(t, "tcp", addrs[0].Network())
+		assert.Equal(t, "unix", addrs[1].Network())
+		assert.Equal(t, "hello", get(t, http.DefaultClient, "http://"+addrs[0].String()+"/hello"))
+
+		unixClient := &http.Client{Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, "unix", socket)
+			},
+		}}
+		assert.Equal(t, "hello", get(t, unixClient, "http://unix/hello"))
+	})
+
+	t.Run("should serve on a pre-opened listener", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		assert.NoError(t, err)
+		apiServer := newListenerTestServer(t, "", WithListeners(listener))
+
+		addrs := runUntilCleanup(t, apiServer)
+
+		assert.Equal(t, []net.Addr{listener.Addr()}, addrs)
+		assert.Equal(t, "hello", get(t, http.DefaultClient, "http://"+listener.Addr().String()+"/hello"))
+	})
+
+	t.Run("should serve on listeners inherited from systemd", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		assert.NoError(t, err)
+		file, err := listener.(*net.TCPListener).File()
+		assert.NoError(t, err)
+		listener.Close()
+		defer file.Close()
+
+		defer func(start int) { listenFdsStart = start }(listenFdsStart)
+		listenFdsStart = int(file.Fd())
+		t.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()))
+		t.Setenv("LISTEN_FDS", "1")
+
+		apiServer := newListenerTestServer(t, "", WithSystemdListeners())
+		addrs := runUntilCleanup(t, apiServer)
+
+		assert.Len(t, addrs, 1)
+		assert.Equal(t, "hello", get(t, http.DefaultClient, "http://"+addrs[0].String()+"/hello"))
+		assert.Empty(t, os.Getenv("LISTEN_FDS"))
+	})
+
+	t.Run("should ignore systemd variables meant for another process", func(t *testing.T) {
+		t.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()+1))
+		t.Setenv("LISTEN_FDS", "1")
+
+		listeners, err := systemdListeners()
+		assert.NoError(t, err)
+		assert.Empty(t, listeners)
+	})
+
+	t.Run("should close opened listeners when another one fails", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		assert.NoError(t, err)
+		defer listener.Close()
+
+		logger, _ := initLog()
+		apiServer := NewAPIServer("127.0.0.1:0", "", logger, WithSignals(), WithListen("tcp", listener.Addr().String()))
+		err = apiServer.Run(context.Background())
+
+		var serverErr *ServerError
+		assert.ErrorAs(t, err, &serverErr)
+		assert.Equal(t, PhaseListen, serverErr.Phase)
+		assert.Empty(t, apiServer.Addrs())
+	})
+}
+
+func newListenerTestServer(t *testing.T, addr string, opts ...Option) *APIServer {
+	logger, _ := initLog()
+	apiServer := NewAPIServer(addr, "", logger, append([]Option{WithSignals()}, opts...)...)
+	apiServer.RegisterRoute("/hello", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello"))
+	}, http.MethodGet)
+	return apiServer
+}
+
+// runUntilCleanup runs apiServer until the test ends and returns its bound
+// addresses once it is listening.
+func runUntilCleanup(t *testing.T, apiServer *APIServer) []net.Addr {
+	ctx, cancel := context.WithCancel(context.Background())
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- apiServer.Run(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		assert.NoError(t, <-errChan)
+	})
+
+	select {
+	case <-apiServer.Ready():
+	case err := <-errChan:
+		t.Fatalf("server failed to start: %v", err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("Test timed out waiting for server to listen")
+	}
+	return apiServer.Addrs()
+}
+
+func get(t *testing.T, client *http.Client, url string) string {
+	resp, err := client.Get(url)
+	if !assert.NoError(t, err) {
+		return ""
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	return string(body)
+}
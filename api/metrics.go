@@ -0,0 +1,85 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/chlovec/rest-pack/metrics"
+)
+
+const (
+	defaultMetricsPath = "/metrics"
+	unmatchedRoute     = "unmatched"
+	otherMethod        = "OTHER"
+)
+
+// standardMethods are the methods recorded as they are. Others are recorded
+// as "OTHER", so that clients cannot create unbounded label values.
+var standardMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodPost:    true,
+	http.MethodPut:     true,
+	http.MethodPatch:   true,
+	http.MethodDelete:  true,
+	http.MethodConnect: true,
+	http.MethodOptions: true,
+	http.MethodTrace:   true,
+}
+
+// WithMetrics records the count, latency and number of in-flight requests
+// for every request the server handles, labelled by route template, method
+// and status, and serves reg on path, "/metrics" if empty, outside the path
+// prefix. Requests that match no route are labelled route="unmatched".
+func WithMetrics(reg *metrics.Registry, path string) Option {
+	return func(s *APIServer) {
+		if path == "" {
+			path = defaultMetricsPath
+		}
+
+		s.instrument(newHTTPMetrics(reg).middleware)
+		s.apiRouter.Handle(path, reg.Handler()).Methods(http.MethodGet, http.MethodHead)
+	}
+}
+
+type httpMetrics struct {
+	requests *metrics.Counter
+	duration *metrics.Histogram
+	inFlight *metrics.Gauge
+}
+
+func newHTTPMetrics(reg *metrics.Registry) *httpMetrics {
+	return &httpMetrics{
+		requests: reg.NewCounter("http_requests_total",
+			"Total number of HTTP requests handled.", "route", "method", "status"),
+		duration: reg.NewHistogram("http_request_duration_seconds",
+			"Time taken to handle HTTP requests.", nil, "route", "method", "status"),
+		inFlight: reg.NewGauge("http_requests_in_flight",
+			"Number of HTTP requests being handled.", "route", "method"),
+	}
+}
+
+func (m *httpMetrics) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := RouteTemplate(r)
+		if route == "" {
+			route = unmatchedRoute
+		}
+		method := r.Method
+		if !standardMethods[method] {
+			method = otherMethod
+		}
+
+		start := time.Now()
+		m.inFlight.Inc(route, method)
+		defer m.inFlight.Dec(route, method)
+
+		rw := newResponseWriter(w)
+		next.ServeHTTP(rw, r)
+
+		status := strconv.Itoa(rw.status)
+		m.requests.Inc(route, method, status)
+		m.duration.Observe(time.Since(start).Seconds(), route, method, status)
+	})
+}
@@ -0,0 +1,74 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/chlovec/rest-pack/metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithMetrics(t *testing.T) {
+	t.Run("should record requests by route template, method and status", func(t *testing.T) {
+		logger, _ := initLog()
+		reg := metrics.NewRegistry()
+		server := NewAPIServer(":8080", "/api", logger, WithMetrics(reg, ""))
+
+		var inFlight string
+		server.RegisterRoute("/products/{id}", func(w http.ResponseWriter, r *http.Request) {
+			rr := httptest.NewRecorder()
+			reg.Handler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+			inFlight = rr.Body.String()
+			w.WriteHeader(http.StatusNoContent)
+		}, http.MethodGet)
+
+		for _, path := range []string{"/api/products/1", "/api/products/2", "/api/missing"} {
+			server.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+		}
+
+		assert.Contains(t, inFlight, `http_requests_in_flight{route="/api/products/{id}",method="GET"} 1`)
+
+		rr := httptest.NewRecorder()
+		server.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+		assert.Equal(t, http.StatusOK, rr.Code)
+
+		body := rr.Body.String()
+		assert.Contains(t, body, `http_requests_total{route="/api/products/{id}",method="GET",status="204"} 2`)
+		assert.Contains(t, body, `http_requests_total{route="unmatched",method="GET",status="404"} 1`)
+		assert.Contains(t, body, `http_request_duration_seconds_count{route="/api/products/{id}",method="GET",status="204"} 2`)
+		assert.Contains(t, body, `http_request_duration_seconds_bucket{route="/api/products/{id}",method="GET",status="204",le="+Inf"} 2`)
+		assert.Contains(t, body, `http_requests_in_flight{route="/api/products/{id}",method="GET"} 0`)
+	})
+
+	t.Run("should record non-standard methods as OTHER", func(t *testing.T) {
+		logger, _ := initLog()
+		reg := metrics.NewRegistry()
+		server := NewAPIServer(":8080", "/api", logger, WithMetrics(reg, ""))
+
+		for _, method := range []string{"PROPFIND", "X-RANDOM-1", http.MethodDelete} {
+			server.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(method, "/api/missing", nil))
+		}
+
+		rr := httptest.NewRecorder()
+		server.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+		body := rr.Body.String()
+		assert.Contains(t, body, `http_requests_total{route="unmatched",method="OTHER",status="404"} 2`)
+		assert.Contains(t, body, `http_requests_total{route="unmatched",method="DELETE",status="404"} 1`)
+		assert.NotContains(t, body, "PROPFIND")
+	})
+
+	t.Run("should serve metrics on a custom path", func(t *testing.T) {
+		logger, _ := initLog()
+		reg := metrics.NewRegistry()
+		reg.NewGauge("up", "").Set(1)
+		server := NewAPIServer(":8080", "/api", logger, WithMetrics(reg, "/internal/metrics"))
+
+		rr := httptest.NewRecorder()
+		server.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/internal/metrics", nil))
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Contains(t, rr.Body.String(), "up 1\n")
+	})
+}
@@ -0,0 +1,150 @@
+package api
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/chlovec/rest-pack/auth"
+	"github.com/chlovec/rest-pack/utils"
+	"github.com/gorilla/mux"
+)
+
+// Middleware wraps an http.Handler with cross-cutting logic. Middleware
+// registered first runs outermost.
+type Middleware func(http.Handler) http.Handler
+
+// Route is a route registered through RegisterRoute. Middleware added with
+// Use runs after the server and subrouter middleware, right around the handler.
+type Route struct {
+	path        string
+	template    string
+	methods     []string
+	handler     http.Handler
+	middlewares []Middleware
+	chain       http.Handler
+	cors        *corsPolicy
+	skipAuth    bool
+	policies    []auth.Policy
+}
+
+func newRoute(path, template string, handler http.Handler, methods []string) *Route {
+	return &Route{
+		path:     path,
+		template: template,
+		methods:  methods,
+		handler:  handler,
+		chain:    handler,
+	}
+}
+
+func (r *Route) Path() string {
+	return r.path
+}
+
+// Template returns the full path template of the route, including the path
+// prefix and group prefixes, e.g. "/api/products/{id}".
+func (r *Route) Template() string {
+	return r.template
+}
+
+func (r *Route) Methods() []string {
+	return r.methods
+}
+
+// Use appends middleware to the route. It should be called before the server
+// starts serving requests.
+func (r *Route) Use(middlewares ...Middleware) *Route {
+	r.middlewares = append(r.middlewares, middlewares...)
+	r.buildChain()
+	return r
+}
+
+// buildChain wraps the handler in the route's middleware. Authorization runs
+// innermost, after any middleware that authenticates the caller.
+func (r *Route) buildChain() {
+	handler := r.handler
+	if len(r.policies) > 0 {
+		handler = r.authorizeHandler(handler)
+	}
+	r.chain = chain(handler, r.middlewares)
+}
+
+// ServeHTTP runs the route's middleware and handler. The route template and
+// method are added to the request context for the logger.
+func (r *Route) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	template := RouteTemplate(req)
+	if template == "" {
+		template = r.path
+	}
+	ctx := utils.WithLogAttrs(req.Context(),
+		slog.String("route", template),
+		slog.String("method", req.Method),
+	)
+	r.chain.ServeHTTP(w, req.WithContext(ctx))
+}
+
+type matchedRouteKey struct{}
+
+// withMatchedRoute stores the route that router matches for r in its context,
+// so server-level middleware, which runs before the router, can find it.
+func withMatchedRoute(r *http.Request, router *mux.Router) *http.Request {
+	var match mux.RouteMatch
+	if !router.Match(r, &match) || match.Route == nil {
+		return r
+	}
+	return r.WithContext(context.WithValue(r.Context(), matchedRouteKey{}, match.Route))
+}
+
+// matchedRoute returns the route registered through RegisterRoute that
+// matches r, or nil.
+func matchedRoute(r *http.Request) *Route {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		route, _ = r.Context().Value(matchedRouteKey{}).(*mux.Route)
+	}
+	if route == nil {
+		return nil
+	}
+
+	handler, _ := route.GetHandler().(*Route)
+	return handler
+}
+
+// RouteTemplate returns the path template of the route matching r, such as
+// "/api/products/{id}", or "" if no registered route matches.
+func RouteTemplate(r *http.Request) string {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		route, _ = r.Context().Value(matchedRouteKey{}).(*mux.Route)
+	}
+	if route == nil {
+		return ""
+	}
+
+	template, err := route.GetPathTemplate()
+	if err != nil {
+		return ""
+	}
+	return template
+}
+
+// chain wraps handler so that middlewares[0] is the outermost layer.
+func chain(handler http.Handler, middlewares []Middleware) http.Handler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		if middlewares[i] != nil {
+			handler = middlewares[i](handler)
+		}
+	}
+	return handler
+}
+
+func toMuxMiddleware(middlewares []Middleware) []mux.MiddlewareFunc {
+	mws := make([]mux.MiddlewareFunc, 0, len(middlewares))
+	for _, mw := range middlewares {
+		if mw != nil {
+			mws = append(mws, mux.MiddlewareFunc(mw))
+		}
+	}
+	return mws
+}
@@ -0,0 +1,117 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiddleware(t *testing.T) {
+	t.Run("should run server, subrouter and route middleware in order", func(t *testing.T) {
+		logger, _ := initLog()
+		server := NewAPIServer(":8080", "/api", logger)
+
+		var calls []string
+		server.Use(recordMiddleware(&calls, "server-1"), recordMiddleware(&calls, "server-2"))
+		server.UseSubrouter(recordMiddleware(&calls, "subrouter"))
+		server.RegisterRoute("/test", func(w http.ResponseWriter, r *http.Request) {
+			calls = append(calls, "handler")
+			w.WriteHeader(http.StatusOK)
+		}, http.MethodGet).Use(recordMiddleware(&calls, "route-1"), recordMiddleware(&calls, "route-2"))
+
+		rr := httptest.NewRecorder()
+		server.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/test", nil))
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		expected := []string{
+			"server-1:before", "server-2:before", "subrouter:before", "route-1:before", "route-2:before",
+			"handler",
+			"route-2:after", "route-1:after", "subrouter:after", "server-2:after", "server-1:after",
+		}
+		assert.Equal(t, expected, calls)
+	})
+
+	t.Run("should run only server middleware for unmatched routes", func(t *testing.T) {
+		logger, _ := initLog()
+		server := NewAPIServer(":8080", "/api", logger)
+
+		var calls []string
+		server.Use(recordMiddleware(&calls, "server"))
+		server.UseSubrouter(recordMiddleware(&calls, "subrouter"))
+		server.RegisterRoute("/test", func(w http.ResponseWriter, r *http.Request) {}, http.MethodGet)
+
+		rr := httptest.NewRecorder()
+		server.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/missing", nil))
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+		assert.Equal(t, []string{"server:before", "server:after"}, calls)
+	})
+
+	t.Run("should apply route middleware only to its route", func(t *testing.T) {
+		logger, _ := initLog()
+		server := NewAPIServer(":8080", "", logger)
+
+		var calls []string
+		server.RegisterRoute("/a", func(w http.ResponseWriter, r *http.Request) {}, http.MethodGet).
+			Use(recordMiddleware(&calls, "a"))
+		server.RegisterRoute("/b", func(w http.ResponseWriter, r *http.Request) {}, http.MethodGet)
+
+		server.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/b", nil))
+		assert.Empty(t, calls)
+
+		server.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/a", nil))
+		assert.Equal(t, []string{"a:before", "a:after"}, calls)
+	})
+
+	t.Run("should let middleware short-circuit the handler", func(t *testing.T) {
+		logger, _ := initLog()
+		server := NewAPIServer(":8080", "", logger)
+
+		handlerCalled := false
+		server.Use(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusUnauthorized)
+			})
+		})
+		server.RegisterRoute("/test", func(w http.ResponseWriter, r *http.Request) {
+			handlerCalled = true
+		}, http.MethodGet)
+
+		testServer := httptest.NewServer(server)
+		defer testServer.Close()
+
+		resp, err := http.Get(testServer.URL + "/test")
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+		assert.False(t, handlerCalled)
+	})
+
+	t.Run("should ignore nil middleware", func(t *testing.T) {
+		logger, _ := initLog()
+		server := NewAPIServer(":8080", "", logger)
+
+		server.Use(nil)
+		server.UseSubrouter(nil)
+		server.RegisterRoute("/test", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusAccepted)
+		}, http.MethodGet).Use(nil)
+
+		rr := httptest.NewRecorder()
+		server.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/test", nil))
+		assert.Equal(t, http.StatusAccepted, rr.Code)
+	})
+}
+
+func recordMiddleware(calls *[]string, name string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			*calls = append(*calls, name+":before")
+			next.ServeHTTP(w, r)
+			*calls = append(*calls, name+":after")
+		})
+	}
+}
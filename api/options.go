@@ -0,0 +1,122 @@
+package api
+
+import (
+	"net/http"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/chlovec/rest-pack/utils"
+)
+
+const (
+	defaultShutdownTimeout   = 5 * time.Second
+	defaultReadTimeout       = 15 * time.Second
+	defaultReadHeaderTimeout = 5 * time.Second
+	defaultWriteTimeout      = 30 * time.Second
+	defaultIdleTimeout       = 120 * time.Second
+	defaultMaxHeaderBytes    = 1 << 20
+	defaultMaxBodyBytes      = 1 << 20
+)
+
+var defaultSignals = []os.Signal{syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT}
+
+// Option configures an APIServer created by NewAPIServer.
+type Option func(*APIServer)
+
+// WithReadTimeout sets the maximum duration for reading an entire request,
+// including the body. It defaults to 15 seconds.
+func WithReadTimeout(timeout time.Duration) Option {
+	return func(s *APIServer) {
+		s.server.ReadTimeout = timeout
+	}
+}
+
+// WithReadHeaderTimeout sets the maximum duration for reading request
+// headers, which protects against slowloris attacks. It defaults to 5 seconds.
+func WithReadHeaderTimeout(timeout time.Duration) Option {
+	return func(s *APIServer) {
+		s.server.ReadHeaderTimeout = timeout
+	}
+}
+
+// WithWriteTimeout sets the maximum duration before timing out writes of the
+// response. It defaults to 30 seconds.
+func WithWriteTimeout(timeout time.Duration) Option {
+	return func(s *APIServer) {
+		s.server.WriteTimeout = timeout
+	}
+}
+
+// WithIdleTimeout sets how long keep-alive connections stay open between
+// requests. It defaults to 120 seconds.
+func WithIdleTimeout(timeout time.Duration) Option {
+	return func(s *APIServer) {
+		s.server.IdleTimeout = timeout
+	}
+}
+
+// WithMaxHeaderBytes sets the maximum size of request headers. It defaults
+// to 1 MiB.
+func WithMaxHeaderBytes(n int) Option {
+	return func(s *APIServer) {
+		s.server.MaxHeaderBytes = n
+	}
+}
+
+// WithMaxBodyBytes caps the size of request bodies for every route. Requests
+// that declare a larger Content-Length are rejected with 413 after the
+// server-level middleware and before routing, and reads past the cap fail.
+// It defaults to 1 MiB; a non-positive value removes the cap.
+func WithMaxBodyBytes(n int64) Option {
+	return func(s *APIServer) {
+		s.maxBodyBytes = n
+	}
+}
+
+// WithShutdownTimeout sets how long Run waits for in-flight requests to
+// finish before closing their connections.
+func WithShutdownTimeout(timeout time.Duration) Option {
+	return func(s *APIServer) {
+		if timeout > 0 {
+			s.shutdownTimeout = timeout
+		}
+	}
+}
+
+// WithSignals replaces the signals that trigger a graceful shutdown. Calling
+// it without arguments disables signal handling, leaving shutdown to the
+// context passed to Run.
+func WithSignals(signals ...os.Signal) Option {
+	return func(s *APIServer) {
+		s.signals = signals
+	}
+}
+
+// WithHealth serves health's liveness and readiness handlers on /healthz and
+// /readyz, outside the path prefix. Readiness starts failing as soon as a
+// graceful shutdown begins.
+func WithHealth(health *Health) Option {
+	return func(s *APIServer) {
+		s.health = health
+		s.apiRouter.HandleFunc("/healthz", health.LivenessHandler).Methods(http.MethodGet, http.MethodHead)
+		s.apiRouter.HandleFunc("/readyz", health.ReadinessHandler).Methods(http.MethodGet, http.MethodHead)
+	}
+}
+
+// WithTLS serves HTTPS using the certificate and key files in config, which
+// are reloaded when they change on disk. Errors loading them are reported by
+// Start and Run.
+func WithTLS(config TLSConfig) Option {
+	return func(s *APIServer) {
+		s.tlsConfig = &config
+	}
+}
+
+// WithErrorFormat sets the format of error responses, which defaults to
+// utils.ErrorFormatLegacy.
+func WithErrorFormat(format utils.ErrorFormat) Option {
+	return func(s *APIServer) {
+		s.errorFormat = format
+	}
+}
@@ -0,0 +1,198 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chlovec/rest-pack/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServerOptions(t *testing.T) {
+	t.Run("should apply secure defaults", func(t *testing.T) {
+		logger, _ := initLog()
+		server := NewAPIServer(":8080", "", logger)
+
+		assert.Equal(t, 15*time.Second, server.server.ReadTimeout)
+		assert.Equal(t, 5*time.Second, server.server.ReadHeaderTimeout)
+		assert.Equal(t, 30*time.Second, server.server.WriteTimeout)
+		assert.Equal(t, 120*time.Second, server.server.IdleTimeout)
+		assert.Equal(t, 1<<20, server.server.MaxHeaderBytes)
+		assert.Equal(t, int64(1<<20), server.maxBodyBytes)
+		assert.Equal(t, 5*time.Second, server.shutdownTimeout)
+	})
+
+	t.Run("should override defaults", func(t *testing.T) {
+		logger, _ := initLog()
+		server := NewAPIServer(":8080", "", logger,
+			WithReadTimeout(time.Second),
+			WithReadHeaderTimeout(2*time.Second),
+			WithWriteTimeout(3*time.Second),
+			WithIdleTimeout(4*time.Second),
+			WithMaxHeaderBytes(4096),
+			WithMaxBodyBytes(512),
+			WithShutdownTimeout(6*time.Second),
+		)
+
+		assert.Equal(t, time.Second, server.server.ReadTimeout)
+		assert.Equal(t, 2*time.Second, server.server.ReadHeaderTimeout)
+		assert.Equal(t, 3*time.Second, server.server.WriteTimeout)
+		assert.Equal(t, 4*time.Second, server.server.IdleTimeout)
+		assert.Equal(t, 4096, server.server.MaxHeaderBytes)
+		assert.Equal(t, int64(512), server.maxBodyBytes)
+		assert.Equal(t, 6*time.Second, server.shutdownTimeout)
+	})
+
+	t.Run("should close connections that send headers too slowly", func(t *testing.T) {
+		logger, _ := initLog()
+		addr := freeAddr(t)
+		apiServer := NewAPIServer(addr, "", logger, WithSignals(), WithReadHeaderTimeout(100*time.Millisecond))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		errChan := make(chan error, 1)
+		go func() {
+			errChan <- apiServer.Run(ctx)
+		}()
+		defer func() {
+			cancel()
+			<-errChan
+		}()
+		waitForServer(t, addr)
+
+		conn, err := net.Dial("tcp", addr)
+		assert.NoError(t, err)
+		defer conn.Close()
+		_, err = conn.Write([]byte("GET / HTTP/1.1\r\nHost: localhost\r\n"))
+		assert.NoError(t, err)
+
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		_, err = io.ReadAll(conn)
+		assert.NoError(t, err, "server should close the connection before the client deadline")
+	})
+}
+
+func TestMaxBodyBytes(t *testing.T) {
+	newServer := func(opts ...Option) (*APIServer, *error) {
+		logger, _ := initLog()
+		server := NewAPIServer(":8080", "", logger, opts...)
+		var readErr error
+		server.RegisterRoute("/upload", func(w http.ResponseWriter, r *http.Request) {
+			_, readErr = io.ReadAll(r.Body)
+			if readErr != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		}, http.MethodPost)
+		return server, &readErr
+	}
+
+	t.Run("should reject declared oversized bodies inside the middleware", func(t *testing.T) {
+		var calls []string
+		server, _ := newServer(WithMaxBodyBytes(8))
+		server.Use(RequestID(), recordMiddleware(&calls, "server"))
+
+		req := httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader("0123456789"))
+		req.Header.Set(utils.RequestIDHeader, "req-1")
+		rr := httptest.NewRecorder()
+		server.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rr.Code)
+		assert.JSONEq(t, `{"error": "Request Entity Too Large", "requestId": "req-1"}`, rr.Body.String())
+		assert.Equal(t, "req-1", rr.Header().Get(utils.RequestIDHeader))
+		assert.Equal(t, []string{"server:before", "server:after"}, calls)
+	})
+
+	t.Run("should fail reads past the cap when the length is unknown", func(t *testing.T) {
+		server, readErr := newServer(WithMaxBodyBytes(8))
+
+		req := httptest.NewRequest(http.MethodPost, "/upload", bufio.NewReader(strings.NewReader("0123456789")))
+		req.ContentLength = -1
+		rr := httptest.NewRecorder()
+		server.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		var maxBytesErr *http.MaxBytesError
+		assert.ErrorAs(t, *readErr, &maxBytesErr)
+	})
+
+	t.Run("should accept bodies within the cap", func(t *testing.T) {
+		server, _ := newServer(WithMaxBodyBytes(16))
+
+		rr := httptest.NewRecorder()
+		server.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader("0123456789")))
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("should allow disabling the cap", func(t *testing.T) {
+		server, _ := newServer(WithMaxBodyBytes(0))
+
+		rr := httptest.NewRecorder()
+		server.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader(strings.Repeat("x", 2<<20))))
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+}
+
+func TestErrorFormat(t *testing.T) {
+	newServer := func(opts ...Option) *APIServer {
+		logger, _ := initLog()
+		server := NewAPIServer(":8080", "/api", logger, opts...)
+		server.Use(RequestID())
+		server.UseSubrouter(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				next.ServeHTTP(newResponseWriter(w), r)
+			})
+		})
+		server.RegisterRoute("/products/{id}", func(w http.ResponseWriter, r *http.Request) {
+			utils.WriteNotFound(w, "", nil)
+		}, http.MethodGet)
+		return server
+	}
+
+	serve := func(server *APIServer) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/products/7?expand=true", nil)
+		req.Header.Set(utils.RequestIDHeader, "req-1")
+		rr := httptest.NewRecorder()
+		server.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("should write legacy errors by default", func(t *testing.T) {
+		rr := serve(newServer())
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+		assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+		assert.JSONEq(t, `{"error":"Not Found","requestId":"req-1"}`, rr.Body.String())
+	})
+
+	t.Run("should write problems through wrapped writers", func(t *testing.T) {
+		rr := serve(newServer(WithErrorFormat(utils.ErrorFormatProblem)))
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+		assert.Equal(t, utils.ProblemContentType, rr.Header().Get("Content-Type"))
+		assert.JSONEq(t, `{
+			"type": "about:blank",
+			"title": "Not Found",
+			"status": 404,
+			"instance": "/api/products/7",
+			"requestId": "req-1"
+		}`, rr.Body.String())
+	})
+
+	t.Run("should write problems for oversized bodies", func(t *testing.T) {
+		server := newServer(WithErrorFormat(utils.ErrorFormatProblem), WithMaxBodyBytes(4))
+
+		rr := httptest.NewRecorder()
+		server.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/api/products/7", strings.NewReader("0123456789")))
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rr.Code)
+		assert.Equal(t, utils.ProblemContentType, rr.Header().Get("Content-Type"))
+	})
+}
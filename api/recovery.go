@@ -0,0 +1,71 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/chlovec/rest-pack/utils"
+)
+
+// PanicHandler is notified when a handler panics, e.g. to report the panic to
+// an error tracker.
+type PanicHandler func(r *http.Request, recovered any, stack []byte)
+
+// Recover returns middleware that recovers from panics in the handlers it
+// wraps. The panic and its stack are logged at error level through logger
+// with utils.WriteLogLevel, each onPanic handler is called, and the client
+// receives the same JSON 500 as utils.WriteInternalServerError if nothing was
+// written yet.
+// http.ErrAbortHandler is re-panicked so net/http can abort the response.
+func Recover(logger *slog.Logger, onPanic ...PanicHandler) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rw := newResponseWriter(w)
+			defer func() {
+				recovered := recover()
+				if recovered == nil {
+					return
+				}
+				if err, ok := recovered.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+					panic(recovered)
+				}
+
+				stack := debug.Stack()
+				utils.WriteLogLevel(r.Context(), logger, slog.LevelError, "panic", map[string]any{
+					"error":  fmt.Sprint(recovered),
+					"method": r.Method,
+					"path":   r.URL.Path,
+					"stack":  string(stack),
+				})
+				for _, handler := range onPanic {
+					notifyPanic(logger, handler, r, recovered, stack)
+				}
+
+				if !rw.wroteHeader {
+					utils.WriteInternalServerError(rw, "", nil)
+				}
+			}()
+
+			next.ServeHTTP(rw, r)
+		})
+	}
+}
+
+// Recover returns the Recover middleware logging through the server's logger.
+func (s *APIServer) Recover(onPanic ...PanicHandler) Middleware {
+	return Recover(s.logger, onPanic...)
+}
+
+// notifyPanic calls handler, making sure a panicking handler cannot take the
+// server down.
+func notifyPanic(logger *slog.Logger, handler PanicHandler, r *http.Request, recovered any, stack []byte) {
+	defer func() {
+		if err := recover(); err != nil {
+			logger.Error("Panic handler failed", "error", err)
+		}
+	}()
+	handler(r, recovered, stack)
+}
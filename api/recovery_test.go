@@ -0,0 +1,101 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecover(t *testing.T) {
+	t.Run("should return a JSON 500 and log the stack", func(t *testing.T) {
+		logger, logBuffer := initLog()
+		server := NewAPIServer(":8080", "", logger)
+		server.Use(server.Recover())
+		server.RegisterRoute("/panic", func(w http.ResponseWriter, r *http.Request) {
+			panic("something went wrong")
+		}, http.MethodGet)
+
+		rr := httptest.NewRecorder()
+		server.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/panic", nil))
+
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+		assert.JSONEq(t, `{"error": "Internal Server Error"}`, rr.Body.String())
+
+		logContent := logBuffer.String()
+		assert.Contains(t, logContent, `"level":"ERROR","msg":"panic"`)
+		assert.Contains(t, logContent, `"error":"something went wrong"`)
+		assert.Contains(t, logContent, `"path":"/panic"`)
+		assert.Contains(t, logContent, "runtime/debug.Stack")
+	})
+
+	t.Run("should call panic handlers", func(t *testing.T) {
+		logger, _ := initLog()
+
+		var reported any
+		var reportedPath string
+		var reportedStack []byte
+		handler := Recover(logger,
+			func(r *http.Request, recovered any, stack []byte) {
+				panic("broken reporter")
+			},
+			func(r *http.Request, recovered any, stack []byte) {
+				reported = recovered
+				reportedPath = r.URL.Path
+				reportedStack = stack
+			},
+		)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			panic(errors.New("boom"))
+		}))
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/products", nil))
+
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+		assert.EqualError(t, reported.(error), "boom")
+		assert.Equal(t, "/products", reportedPath)
+		assert.NotEmpty(t, reportedStack)
+	})
+
+	t.Run("should not overwrite a response that was already started", func(t *testing.T) {
+		logger, _ := initLog()
+		handler := Recover(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusAccepted)
+			w.Write([]byte("partial"))
+			panic("late panic")
+		}))
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		assert.Equal(t, http.StatusAccepted, rr.Code)
+		assert.Equal(t, "partial", rr.Body.String())
+	})
+
+	t.Run("should re-panic http.ErrAbortHandler", func(t *testing.T) {
+		logger, logBuffer := initLog()
+		handler := Recover(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			panic(http.ErrAbortHandler)
+		}))
+
+		assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+		})
+		assert.Empty(t, logBuffer.String())
+	})
+
+	t.Run("should pass through requests that do not panic", func(t *testing.T) {
+		logger, logBuffer := initLog()
+		handler := Recover(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusCreated)
+		}))
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		assert.Equal(t, http.StatusCreated, rr.Code)
+		assert.Empty(t, logBuffer.String())
+	})
+}
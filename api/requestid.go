@@ -0,0 +1,56 @@
+package api
+
+import (
+	"crypto/rand"
+	"fmt"
+	"net/http"
+
+	"github.com/chlovec/rest-pack/utils"
+)
+
+const maxRequestIDLength = 128
+
+// RequestID returns middleware that takes the request ID from the
+// X-Request-ID header, or generates one when it is missing or malformed. The
+// ID is stored in the request context, where utils.RequestIDFromContext can
+// read it, and echoed in the response header so error bodies written by
+// utils.WriteErrorJSON include it.
+func RequestID() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requestID := r.Header.Get(utils.RequestIDHeader)
+			if !validRequestID(requestID) {
+				requestID = NewRequestID()
+			}
+
+			w.Header().Set(utils.RequestIDHeader, requestID)
+			next.ServeHTTP(w, r.WithContext(utils.WithRequestID(r.Context(), requestID)))
+		})
+	}
+}
+
+// NewRequestID returns a random UUID (version 4).
+func NewRequestID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// validRequestID accepts short IDs made of characters that are safe to echo
+// in headers and logs.
+func validRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range requestID {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,102 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/chlovec/rest-pack/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestID(t *testing.T) {
+	uuidPattern := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+	newServer := func() (*APIServer, *string) {
+		logger, _ := initLog()
+		server := NewAPIServer(":8080", "", logger)
+		server.Use(RequestID())
+
+		var seen string
+		server.RegisterRoute("/test", func(w http.ResponseWriter, r *http.Request) {
+			seen = utils.RequestIDFromContext(r.Context())
+			w.WriteHeader(http.StatusOK)
+		}, http.MethodGet)
+		server.RegisterRoute("/missing", func(w http.ResponseWriter, r *http.Request) {
+			utils.WriteNotFound(w, "", nil)
+		}, http.MethodGet)
+		return server, &seen
+	}
+
+	t.Run("should generate a request ID", func(t *testing.T) {
+		server, seen := newServer()
+
+		rr := httptest.NewRecorder()
+		server.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/test", nil))
+
+		requestID := rr.Header().Get("X-Request-ID")
+		assert.Regexp(t, uuidPattern, requestID)
+		assert.Equal(t, requestID, *seen)
+	})
+
+	t.Run("should propagate an incoming request ID", func(t *testing.T) {
+		server, seen := newServer()
+
+		req := httptest.NewRequest(http.MethodGet, "/test", nil)
+		req.Header.Set("X-Request-ID", "abc-123")
+		rr := httptest.NewRecorder()
+		server.ServeHTTP(rr, req)
+
+		assert.Equal(t, "abc-123", rr.Header().Get("X-Request-ID"))
+		assert.Equal(t, "abc-123", *seen)
+	})
+
+	t.Run("should replace malformed incoming request IDs", func(t *testing.T) {
+		for _, requestID := range []string{"bad id", "<script>", strings.Repeat("a", 129)} {
+			server, seen := newServer()
+
+			req := httptest.NewRequest(http.MethodGet, "/test", nil)
+			req.Header.Set("X-Request-ID", requestID)
+			rr := httptest.NewRecorder()
+			server.ServeHTTP(rr, req)
+
+			assert.Regexp(t, uuidPattern, rr.Header().Get("X-Request-ID"))
+			assert.Equal(t, rr.Header().Get("X-Request-ID"), *seen)
+		}
+	})
+
+	t.Run("should include the request ID in error bodies", func(t *testing.T) {
+		server, _ := newServer()
+
+		req := httptest.NewRequest(http.MethodGet, "/missing", nil)
+		req.Header.Set("X-Request-ID", "req-1")
+		rr := httptest.NewRecorder()
+		server.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+		assert.JSONEq(t, `{"error": "Not Found", "requestId": "req-1"}`, rr.Body.String())
+	})
+
+	t.Run("should include the request ID in panic logs", func(t *testing.T) {
+		logger, logBuffer := initLog()
+		server := NewAPIServer(":8080", "", logger)
+		server.Use(RequestID(), server.Recover())
+		server.RegisterRoute("/panic", func(w http.ResponseWriter, r *http.Request) {
+			panic("boom")
+		}, http.MethodGet)
+
+		req := httptest.NewRequest(http.MethodGet, "/panic", nil)
+		req.Header.Set("X-Request-ID", "req-2")
+		rr := httptest.NewRecorder()
+		server.ServeHTTP(rr, req)
+
+		assert.JSONEq(t, `{"error": "Internal Server Error", "requestId": "req-2"}`, rr.Body.String())
+		assert.Contains(t, logBuffer.String(), `"requestId":"req-2"`)
+	})
+
+	t.Run("should generate unique IDs", func(t *testing.T) {
+		assert.NotEqual(t, NewRequestID(), NewRequestID())
+	})
+}
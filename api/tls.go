@@ -0,0 +1,190 @@
+package api
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+const defaultReloadInterval = 10 * time.Second
+
+// DefaultCipherSuites are the TLS 1.2 cipher suites used when TLSConfig does
+// not list any: ECDHE key exchange with AEAD ciphers only. TLS 1.3 suites are
+// not configurable.
+var DefaultCipherSuites = []uint16{
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256,
+}
+
+// TLSConfig configures HTTPS serving. Setting ClientCAFile enables mutual TLS.
+type TLSConfig struct {
+	CertFile string
+	KeyFile  string
+
+	// MinVersion defaults to TLS 1.2.
+	MinVersion uint16
+	// CipherSuites defaults to DefaultCipherSuites.
+	CipherSuites []uint16
+
+	// ClientCAFile is a PEM bundle used to verify client certificates.
+	ClientCAFile string
+	// ClientAuth defaults to tls.RequireAndVerifyClientCert when ClientCAFile
+	// is set.
+	ClientAuth tls.ClientAuthType
+
+	// ReloadInterval is how often the files are checked for changes when a
+	// new connection is accepted. It defaults to 10 seconds; a negative value
+	// disables reloading.
+	ReloadInterval time.Duration
+}
+
+// certReloader serves the certificate and client CA pool loaded from disk and
+// reloads them when the files change. Established connections keep the
+// certificate they negotiated.
+type certReloader struct {
+	config TLSConfig
+	logger *slog.Logger
+
+	mu        sync.RWMutex
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool
+	modTimes  map[string]time.Time
+	checkedAt time.Time
+}
+
+func newCertReloader(config TLSConfig, logger *slog.Logger) (*certReloader, error) {
+	if config.CertFile == "" || config.KeyFile == "" {
+		return nil, errors.New("tls: certificate and key files are required")
+	}
+	if config.ReloadInterval == 0 {
+		config.ReloadInterval = defaultReloadInterval
+	}
+
+	r := &certReloader{config: config, logger: logger}
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *certReloader) files() []string {
+	files := []string{r.config.CertFile, r.config.KeyFile}
+	if r.config.ClientCAFile != "" {
+		files = append(files, r.config.ClientCAFile)
+	}
+	return files
+}
+
+func (r *certReloader) load() error {
+	modTimes := make(map[string]time.Time)
+	for _, file := range r.files() {
+		info, err := os.Stat(file)
+		if err != nil {
+			return fmt.Errorf("tls: %w", err)
+		}
+		modTimes[file] = info.ModTime()
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.config.CertFile, r.config.KeyFile)
+	if err != nil {
+		return fmt.Errorf("tls: %w", err)
+	}
+
+	var clientCAs *x509.CertPool
+	if r.config.ClientCAFile != "" {
+		pem, err := os.ReadFile(r.config.ClientCAFile)
+		if err != nil {
+			return fmt.Errorf("tls: %w", err)
+		}
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("tls: no certificates found in %s", r.config.ClientCAFile)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cert = &cert
+	r.clientCAs = clientCAs
+	r.modTimes = modTimes
+	r.checkedAt = time.Now()
+	return nil
+}
+
+// maybeReload reloads the files if the reload interval has elapsed and any of
+// them changed. On failure the previous certificate stays in use.
+func (r *certReloader) maybeReload() {
+	if r.config.ReloadInterval < 0 {
+		return
+	}
+
+	r.mu.Lock()
+	if time.Since(r.checkedAt) < r.config.ReloadInterval {
+		r.mu.Unlock()
+		return
+	}
+	r.checkedAt = time.Now()
+	changed := false
+	for _, file := range r.files() {
+		info, err := os.Stat(file)
+		if err == nil && !info.ModTime().Equal(r.modTimes[file]) {
+			changed = true
+			break
+		}
+	}
+	r.mu.Unlock()
+
+	if !changed {
+		return
+	}
+	if err := r.load(); err != nil {
+		r.logger.Error("Failed to reload TLS certificates, keeping previous ones", "error", err)
+		return
+	}
+	r.logger.Info("Reloaded TLS certificates", "certFile", r.config.CertFile)
+}
+
+func (r *certReloader) tlsConfig() *tls.Config {
+	base := &tls.Config{
+		MinVersion:   r.config.MinVersion,
+		CipherSuites: r.config.CipherSuites,
+		ClientAuth:   r.config.ClientAuth,
+		NextProtos:   []string{"h2", "http/1.1"},
+	}
+	if base.MinVersion == 0 {
+		base.MinVersion = tls.VersionTLS12
+	}
+	if base.CipherSuites == nil {
+		base.CipherSuites = DefaultCipherSuites
+	}
+	if r.config.ClientCAFile != "" && base.ClientAuth == tls.NoClientCert {
+		base.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	config := base.Clone()
+	config.GetCertificate = func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+		r.mu.RLock()
+		defer r.mu.RUnlock()
+		return r.cert, nil
+	}
+	config.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		r.maybeReload()
+
+		r.mu.RLock()
+		defer r.mu.RUnlock()
+		current := base.Clone()
+		current.Certificates = []tls.Certificate{*r.cert}
+		current.ClientCAs = r.clientCAs
+		return current, nil
+	}
+	return config
+}
@@ -0,0 +1,221 @@
+package api
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTLS(t *testing.T) {
+	t.Run("should serve HTTPS", func(t *testing.T) {
+		dir := t.TempDir()
+		ca := newTestCA(t, "test-ca")
+		certFile, keyFile := ca.issue(t, dir, "server", "server-1", x509.ExtKeyUsageServerAuth)
+
+		addr := startTLSServer(t, TLSConfig{CertFile: certFile, KeyFile: keyFile})
+		client := tlsClient(ca, nil)
+
+		resp, err := client.Get("https://" + addr + "/test")
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "server-1", resp.TLS.PeerCertificates[0].Subject.CommonName)
+		assert.GreaterOrEqual(t, resp.TLS.Version, uint16(tls.VersionTLS12))
+	})
+
+	t.Run("should reject TLS versions below the minimum", func(t *testing.T) {
+		dir := t.TempDir()
+		ca := newTestCA(t, "test-ca")
+		certFile, keyFile := ca.issue(t, dir, "server", "server-1", x509.ExtKeyUsageServerAuth)
+
+		addr := startTLSServer(t, TLSConfig{CertFile: certFile, KeyFile: keyFile, MinVersion: tls.VersionTLS13})
+		client := tlsClient(ca, nil)
+		client.Transport.(*http.Transport).TLSClientConfig.MaxVersion = tls.VersionTLS12
+
+		_, err := client.Get("https://" + addr + "/test")
+		assert.Error(t, err)
+	})
+
+	t.Run("should require client certificates for mutual TLS", func(t *testing.T) {
+		dir := t.TempDir()
+		ca := newTestCA(t, "test-ca")
+		certFile, keyFile := ca.issue(t, dir, "server", "server-1", x509.ExtKeyUsageServerAuth)
+		clientCertFile, clientKeyFile := ca.issue(t, dir, "client", "client-1", x509.ExtKeyUsageClientAuth)
+		caFile := ca.write(t, dir)
+
+		addr := startTLSServer(t, TLSConfig{CertFile: certFile, KeyFile: keyFile, ClientCAFile: caFile})
+
+		_, err := tlsClient(ca, nil).Get("https://" + addr + "/test")
+		assert.Error(t, err)
+
+		untrusted := newTestCA(t, "untrusted-ca")
+		untrustedCertFile, untrustedKeyFile := untrusted.issue(t, dir, "untrusted", "client-2", x509.ExtKeyUsageClientAuth)
+		untrustedCert, err := tls.LoadX509KeyPair(untrustedCertFile, untrustedKeyFile)
+		assert.NoError(t, err)
+		_, err = tlsClient(ca, &untrustedCert).Get("https://" + addr + "/test")
+		assert.Error(t, err)
+
+		clientCert, err := tls.LoadX509KeyPair(clientCertFile, clientKeyFile)
+		assert.NoError(t, err)
+		resp, err := tlsClient(ca, &clientCert).Get("https://" + addr + "/test")
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+
+	t.Run("should reload certificates when the files change", func(t *testing.T) {
+		dir := t.TempDir()
+		ca := newTestCA(t, "test-ca")
+		certFile, keyFile := ca.issue(t, dir, "server", "server-1", x509.ExtKeyUsageServerAuth)
+
+		addr := startTLSServer(t, TLSConfig{CertFile: certFile, KeyFile: keyFile, ReloadInterval: time.Millisecond})
+
+		client := tlsClient(ca, nil)
+		resp, err := client.Get("https://" + addr + "/test")
+		assert.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, "server-1", resp.TLS.PeerCertificates[0].Subject.CommonName)
+
+		ca.issue(t, dir, "server", "server-2", x509.ExtKeyUsageServerAuth)
+		future := time.Now().Add(time.Minute)
+		assert.NoError(t, os.Chtimes(certFile, future, future))
+		assert.NoError(t, os.Chtimes(keyFile, future, future))
+		time.Sleep(5 * time.Millisecond)
+
+		// The established connection keeps working with the old certificate.
+		resp, err = client.Get("https://" + addr + "/test")
+		assert.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, "server-1", resp.TLS.PeerCertificates[0].Subject.CommonName)
+
+		resp, err = tlsClient(ca, nil).Get("https://" + addr + "/test")
+		assert.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, "server-2", resp.TLS.PeerCertificates[0].Subject.CommonName)
+	})
+
+	t.Run("should report missing certificate files", func(t *testing.T) {
+		logger, _ := initLog()
+		apiServer := NewAPIServer(freeAddr(t), "", logger, WithSignals(), WithTLS(TLSConfig{
+			CertFile: filepath.Join(t.TempDir(), "missing.pem"),
+			KeyFile:  filepath.Join(t.TempDir(), "missing-key.pem"),
+		}))
+
+		err := apiServer.Run(context.Background())
+
+		var serverErr *ServerError
+		assert.ErrorAs(t, err, &serverErr)
+		assert.Equal(t, PhaseListen, serverErr.Phase)
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	})
+}
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	der  []byte
+}
+
+func newTestCA(t *testing.T, name string) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	assert.NoError(t, err)
+
+	return &testCA{cert: cert, key: key, der: der}
+}
+
+// issue writes a certificate signed by the CA and its key to dir and returns
+// their paths.
+func (ca *testCA) issue(t *testing.T, dir, name, commonName string, usage x509.ExtKeyUsage) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	assert.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	assert.NoError(t, err)
+
+	certFile := filepath.Join(dir, name+".pem")
+	keyFile := filepath.Join(dir, name+"-key.pem")
+	assert.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	assert.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+	return certFile, keyFile
+}
+
+func (ca *testCA) write(t *testing.T, dir string) string {
+	file := filepath.Join(dir, "ca.pem")
+	assert.NoError(t, os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.der}), 0o600))
+	return file
+}
+
+func tlsClient(ca *testCA, cert *tls.Certificate) *http.Client {
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	config := &tls.Config{RootCAs: pool}
+	if cert != nil {
+		config.Certificates = []tls.Certificate{*cert}
+	}
+	return &http.Client{Transport: &http.Transport{TLSClientConfig: config}}
+}
+
+// startTLSServer runs an APIServer with config until the test ends and
+// returns its address.
+func startTLSServer(t *testing.T, config TLSConfig) string {
+	logger, _ := initLog()
+	addr := freeAddr(t)
+	apiServer := NewAPIServer(addr, "", logger, WithSignals(), WithTLS(config))
+	apiServer.RegisterRoute("/test", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}, http.MethodGet)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- apiServer.Run(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-errChan
+	})
+
+	waitForServer(t, addr)
+	return addr
+}
@@ -0,0 +1,60 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/chlovec/rest-pack/tracing"
+	"github.com/chlovec/rest-pack/utils"
+)
+
+// WithTracing records a server span for every request the server handles,
+// named after the method and route template, e.g. "GET /api/products/{id}".
+// A traceparent header on the request makes the span part of the caller's
+// trace. The trace and span IDs are added to records logged with the request
+// context.
+func WithTracing(tracer *tracing.Tracer) Option {
+	return func(s *APIServer) {
+		s.instrument(traceRequests(tracer))
+	}
+}
+
+func traceRequests(tracer *tracing.Tracer) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route := RouteTemplate(r)
+			name := r.Method
+			if route != "" {
+				name += " " + route
+			}
+
+			ctx := tracing.Extract(r.Context(), r.Header)
+			ctx, span := tracer.Start(ctx, name,
+				tracing.WithSpanKind(tracing.SpanKindServer),
+				tracing.WithAttributes(map[string]any{
+					"http.request.method": r.Method,
+					"url.path":            r.URL.Path,
+				}),
+			)
+			defer span.End()
+			if route != "" {
+				span.SetAttribute("http.route", route)
+			}
+
+			sc := span.SpanContext()
+			ctx = utils.WithLogAttrs(ctx,
+				slog.String("traceId", sc.TraceID.String()),
+				slog.String("spanId", sc.SpanID.String()),
+			)
+
+			rw := newResponseWriter(w)
+			next.ServeHTTP(rw, r.WithContext(ctx))
+
+			span.SetAttribute("http.response.status_code", rw.status)
+			if rw.status >= http.StatusInternalServerError {
+				span.SetStatus(tracing.StatusError, strconv.Itoa(rw.status)+" "+http.StatusText(rw.status))
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/chlovec/rest-pack/tracing"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithTracing(t *testing.T) {
+	t.Run("should record a server span per request", func(t *testing.T) {
+		logger, logBuffer := initLog()
+		exporter := tracing.NewInMemoryExporter()
+		server := NewAPIServer(":8080", "/api", logger, WithTracing(tracing.NewTracer(exporter)))
+
+		var handlerSpan *tracing.Span
+		server.RegisterRoute("/products/{id}", func(w http.ResponseWriter, r *http.Request) {
+			handlerSpan = tracing.SpanFromContext(r.Context())
+			logger.InfoContext(r.Context(), "loading product")
+			w.WriteHeader(http.StatusOK)
+		}, http.MethodGet)
+
+		req := httptest.NewRequest(http.MethodGet, "/api/products/1", nil)
+		req.Header.Set(tracing.TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+		server.ServeHTTP(httptest.NewRecorder(), req)
+
+		spans := exporter.Spans()
+		assert.Len(t, spans, 1)
+		assert.Equal(t, "GET /api/products/{id}", spans[0].Name)
+		assert.Equal(t, tracing.SpanKindServer, spans[0].Kind)
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", spans[0].TraceID)
+		assert.Equal(t, "00f067aa0ba902b7", spans[0].ParentSpanID)
+		assert.Equal(t, map[string]any{
+			"http.request.method":       "GET",
+			"http.route":                "/api/products/{id}",
+			"url.path":                  "/api/products/1",
+			"http.response.status_code": 200,
+		}, spans[0].Attributes)
+		assert.Equal(t, spans[0].SpanID, handlerSpan.SpanContext().SpanID.String())
+		assert.Contains(t, logBuffer.String(), `"traceId":"4bf92f3577b34da6a3ce929d0e0e4736","spanId":"`+spans[0].SpanID+`"`)
+	})
+
+	t.Run("should mark server errors and unmatched requests", func(t *testing.T) {
+		logger, _ := initLog()
+		exporter := tracing.NewInMemoryExporter()
+		server := NewAPIServer(":8080", "/api", logger, WithTracing(tracing.NewTracer(exporter)))
+		server.RegisterRoute("/fail", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+		}, http.MethodPost)
+
+		server.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/api/fail", nil))
+		server.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/missing", nil))
+
+		spans := exporter.Spans()
+		assert.Len(t, spans, 2)
+		assert.Equal(t, tracing.Status{Code: tracing.StatusError, Message: "502 Bad Gateway"}, spans[0].Status)
+		assert.Equal(t, "GET", spans[1].Name)
+		assert.Empty(t, spans[1].ParentSpanID)
+		assert.Equal(t, 404, spans[1].Attributes["http.response.status_code"])
+		assert.NotContains(t, spans[1].Attributes, "http.route")
+	})
+}
@@ -0,0 +1,146 @@
+package apikey
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/chlovec/rest-pack/api"
+	"github.com/chlovec/rest-pack/utils"
+	"github.com/gorilla/mux"
+)
+
+// CreateKeyPayload is the body of a request to create a key. Scopes may not
+// contain spaces.
+type CreateKeyPayload struct {
+	Name   string   `json:"name" validate:"required,max=255"`
+	Scopes []string `json:"scopes" validate:"dive,required,excludesall=0x20"`
+}
+
+// CreatedKey is a key together with the key to hand to the client.
+type CreatedKey struct {
+	*Key
+	Secret string `json:"key"`
+}
+
+// Handler serves the admin API for managing keys. It must be protected, for
+// example with an admin-only key or token.
+type Handler struct {
+	logger *slog.Logger
+	store  *Store
+}
+
+func NewHandler(logger *slog.Logger, store *Store) *Handler {
+	return &Handler{
+		logger: logger,
+		store:  store,
+	}
+}
+
+// RegisterRoutes registers the admin API under path, such as "/api-keys":
+//
+//	POST   path              create a key
+//	GET    path              list keys
+//	DELETE path/{id}         revoke a key
+//	POST   path/{id}/rotate  replace a key with a new one
+//
+// The registered routes are returned so that middleware can be added to
+// them.
+func (h *Handler) RegisterRoutes(server api.APIServerInterface, path string) []*api.Route {
+	routes := make([]*api.Route, 0, 4)
+	for _, route := range []*api.Route{
+		server.RegisterRoute(path, h.CreateKey, http.MethodPost),
+		server.RegisterRoute(path, h.ListKeys, http.MethodGet),
+		server.RegisterRoute(path+"/{id}", h.RevokeKey, http.MethodDelete),
+		server.RegisterRoute(path+"/{id}/rotate", h.RotateKey, http.MethodPost),
+	} {
+		if route != nil {
+			routes = append(routes, route)
+		}
+	}
+	return routes
+}
+
+func (h *Handler) CreateKey(w http.ResponseWriter, r *http.Request) {
+	var payload CreateKeyPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteBadRequest(w, "", nil)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		details := utils.GetLocalizedValidationError(err, utils.TranslatorOf(w))
+		utils.WriteBadRequest(w, "Validation Error", details)
+		return
+	}
+
+	key, secret, err := h.store.Create(r.Context(), payload.Name, payload.Scopes)
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "Failed to create API key", "error", err)
+		utils.WriteInternalServerError(w, "", nil)
+		return
+	}
+
+	h.logger.InfoContext(r.Context(), "API key created", "id", key.ID, "prefix", key.Prefix)
+	utils.WriteJSON(w, http.StatusCreated, CreatedKey{Key: key, Secret: secret})
+}
+
+func (h *Handler) ListKeys(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.store.List(r.Context())
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "Failed to list API keys", "error", err)
+		utils.WriteInternalServerError(w, "", nil)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, keys)
+}
+
+func (h *Handler) RevokeKey(w http.ResponseWriter, r *http.Request) {
+	id, err := keyID(r)
+	if err != nil {
+		utils.WriteBadRequest(w, "", nil)
+		return
+	}
+
+	err = h.store.Revoke(r.Context(), id)
+	if errors.Is(err, ErrNotFound) {
+		utils.WriteNotFound(w, "", nil)
+		return
+	}
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "Failed to revoke API key", "error", err, "id", id)
+		utils.WriteInternalServerError(w, "", nil)
+		return
+	}
+
+	h.logger.InfoContext(r.Context(), "API key revoked", "id", id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) RotateKey(w http.ResponseWriter, r *http.Request) {
+	id, err := keyID(r)
+	if err != nil {
+		utils.WriteBadRequest(w, "", nil)
+		return
+	}
+
+	key, secret, err := h.store.Rotate(r.Context(), id)
+	if errors.Is(err, ErrNotFound) {
+		utils.WriteNotFound(w, "", nil)
+		return
+	}
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "Failed to rotate API key", "error", err, "id", id)
+		utils.WriteInternalServerError(w, "", nil)
+		return
+	}
+
+	h.logger.InfoContext(r.Context(), "API key rotated", "id", id, "newId", key.ID, "prefix", key.Prefix)
+	utils.WriteJSON(w, http.StatusCreated, CreatedKey{Key: key, Secret: secret})
+}
+
+func keyID(r *http.Request) (int64, error) {
+	return strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+}
@@ -0,0 +1,140 @@
+package apikey
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/chlovec/rest-pack/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestServer(t *testing.T) (*api.APIServer, sqlmock.Sqlmock) {
+	t.Helper()
+
+	store, mock := newTestStore(t)
+	logger := slog.New(slog.NewJSONHandler(&bytes.Buffer{}, nil))
+	server := api.NewAPIServer(":8080", "/api", logger)
+	routes := NewHandler(logger, store).RegisterRoutes(server, "/api-keys")
+	require.Len(t, routes, 4)
+	return server, mock
+}
+
+func serveAdmin(server *api.APIServer, method, target, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, bytes.NewBufferString(body))
+	rr := httptest.NewRecorder()
+	server.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestRegisterRoutes(t *testing.T) {
+	store, _ := newTestStore(t)
+	logger := slog.New(slog.NewJSONHandler(&bytes.Buffer{}, nil))
+	server := api.NewAPIServer(":8080", "/api", logger)
+
+	routes := NewHandler(logger, store).RegisterRoutes(server, "")
+
+	require.Len(t, routes, 2)
+	assert.Equal(t, "/api/{id}", routes[0].Template())
+	assert.Equal(t, "/api/{id}/rotate", routes[1].Template())
+}
+
+func TestCreateKey(t *testing.T) {
+	t.Run("should create a key", func(t *testing.T) {
+		server, mock := newTestServer(t)
+		mock.ExpectExec(regexp.QuoteMeta(insertKey)).
+			WithArgs("billing", sqlmock.AnyArg(), sqlmock.AnyArg(), "products:read", testNow).
+			WillReturnResult(sqlmock.NewResult(3, 1))
+
+		rr := serveAdmin(server, http.MethodPost, "/api/api-keys", `{"name":"billing","scopes":["products:read"]}`)
+		assert.Equal(t, http.StatusCreated, rr.Code)
+
+		var created map[string]any
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &created))
+		assert.Equal(t, float64(3), created["id"])
+		assert.Equal(t, "billing", created["name"])
+		assert.Equal(t, []any{"products:read"}, created["scopes"])
+		assert.Regexp(t, `^`+created["prefix"].(string)+`\.`, created["key"])
+	})
+
+	t.Run("should validate the payload", func(t *testing.T) {
+		server, _ := newTestServer(t)
+
+		rr := serveAdmin(server, http.MethodPost, "/api/api-keys", `{"scopes":["products read"]}`)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Contains(t, rr.Body.String(), "Validation Error")
+
+		rr = serveAdmin(server, http.MethodPost, "/api/api-keys", `{`)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("should fail on database errors", func(t *testing.T) {
+		server, mock := newTestServer(t)
+		mock.ExpectExec(regexp.QuoteMeta(insertKey)).WillReturnError(errDatabase)
+
+		rr := serveAdmin(server, http.MethodPost, "/api/api-keys", `{"name":"billing"}`)
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+	})
+}
+
+func TestListKeys(t *testing.T) {
+	server, mock := newTestServer(t)
+	mock.ExpectQuery(regexp.QuoteMeta(selectKeys)).WillReturnRows(sqlmock.NewRows(keyColumns).
+		AddRow(1, "billing", testPrefix, "hash", "products:read", testNow, nil, nil))
+
+	rr := serveAdmin(server, http.MethodGet, "/api/api-keys", "")
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `[{"id":1,"name":"billing","prefix":"0123456789abcdef","scopes":["products:read"],"createdAt":"2024-03-05T13:55:00Z"}]`, rr.Body.String())
+	assert.NotContains(t, rr.Body.String(), "hash")
+
+	mock.ExpectQuery(regexp.QuoteMeta(selectKeys)).WillReturnError(errDatabase)
+	assert.Equal(t, http.StatusInternalServerError, serveAdmin(server, http.MethodGet, "/api/api-keys", "").Code)
+}
+
+func TestRevokeKey(t *testing.T) {
+	server, mock := newTestServer(t)
+
+	mock.ExpectExec(regexp.QuoteMeta(revokeKey)).WithArgs(testNow, 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	assert.Equal(t, http.StatusNoContent, serveAdmin(server, http.MethodDelete, "/api/api-keys/1", "").Code)
+
+	mock.ExpectExec(regexp.QuoteMeta(revokeKey)).WithArgs(testNow, 2).WillReturnResult(sqlmock.NewResult(0, 0))
+	assert.Equal(t, http.StatusNotFound, serveAdmin(server, http.MethodDelete, "/api/api-keys/2", "").Code)
+
+	mock.ExpectExec(regexp.QuoteMeta(revokeKey)).WithArgs(testNow, 3).WillReturnError(errDatabase)
+	assert.Equal(t, http.StatusInternalServerError, serveAdmin(server, http.MethodDelete, "/api/api-keys/3", "").Code)
+
+	assert.Equal(t, http.StatusBadRequest, serveAdmin(server, http.MethodDelete, "/api/api-keys/abc", "").Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRotateKey(t *testing.T) {
+	server, mock := newTestServer(t)
+	selectActive := "SELECT name, scopes FROM api_keys WHERE id = ? AND revoked_at IS NULL FOR UPDATE"
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(selectActive)).WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"name", "scopes"}).AddRow("billing", ""))
+	mock.ExpectExec(regexp.QuoteMeta(insertKey)).WillReturnResult(sqlmock.NewResult(9, 1))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE api_keys SET revoked_at = ? WHERE id = ?")).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	rr := serveAdmin(server, http.MethodPost, "/api/api-keys/1/rotate", "")
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"id":9`)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(selectActive)).WithArgs(2).WillReturnError(sql.ErrNoRows)
+	mock.ExpectRollback()
+	assert.Equal(t, http.StatusNotFound, serveAdmin(server, http.MethodPost, "/api/api-keys/2/rotate", "").Code)
+
+	assert.Equal(t, http.StatusBadRequest, serveAdmin(server, http.MethodPost, "/api/api-keys/x/rotate", "").Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,165 @@
+package apikey
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chlovec/rest-pack/auth"
+	"github.com/chlovec/rest-pack/utils"
+)
+
+const (
+	DefaultHeader = "X-API-Key"
+
+	// lastUsedResolution limits how often the last use of a key is written.
+	lastUsedResolution = time.Minute
+	// markUsedTimeout bounds recording the last use of a key, which outlives
+	// the request.
+	markUsedTimeout = 5 * time.Second
+)
+
+var ErrMissingKey = errors.New("missing API key")
+
+type keyKey struct{}
+
+// KeyFromContext returns the key the request was authenticated with.
+func KeyFromContext(ctx context.Context) (*Key, bool) {
+	key, ok := ctx.Value(keyKey{}).(*Key)
+	return key, ok
+}
+
+type Option func(*middleware)
+
+// WithHeader sets the header carrying the key. It defaults to X-API-Key.
+func WithHeader(header string) Option {
+	return func(m *middleware) {
+		m.header = header
+	}
+}
+
+// WithQueryParam also accepts the key in the query parameter, for clients
+// that cannot set headers. Keys in URLs end up in access logs, so this is
+// off by default.
+func WithQueryParam(param string) Option {
+	return func(m *middleware) {
+		m.queryParam = param
+	}
+}
+
+// WithScopes rejects keys lacking any of scopes with 403 Forbidden.
+func WithScopes(scopes ...string) Option {
+	return func(m *middleware) {
+		m.scopes = scopes
+	}
+}
+
+// WithLogger logs store errors, which are answered with 500.
+func WithLogger(logger *slog.Logger) Option {
+	return func(m *middleware) {
+		m.logger = logger
+	}
+}
+
+type middleware struct {
+	store      *Store
+	header     string
+	queryParam string
+	scopes     []string
+	logger     *slog.Logger
+	// marking holds the IDs of keys whose use is being recorded.
+	marking sync.Map
+}
+
+// Middleware returns middleware that requires an active API key and stores
+// it in the request context, both as a *Key and as auth.Claims whose subject
+// is "apikey:<id>" and whose scope claim lists the key's scopes. Requests
+// without a valid key are rejected with 401 Unauthorized.
+func Middleware(store *Store, opts ...Option) func(http.Handler) http.Handler {
+	m := &middleware{store: store, header: DefaultHeader, logger: slog.Default()}
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			plain := m.keyFrom(r)
+			if plain == "" {
+				utils.WriteErrorJSON(w, http.StatusUnauthorized, ErrMissingKey, nil)
+				return
+			}
+
+			ctx := r.Context()
+			key, err := m.store.Verify(ctx, plain)
+			if errors.Is(err, ErrInvalidKey) {
+				utils.WriteErrorJSON(w, http.StatusUnauthorized, err, nil)
+				return
+			}
+			if err != nil {
+				m.logger.ErrorContext(ctx, "API key verification failed", "error", err)
+				utils.WriteInternalServerError(w, "", nil)
+				return
+			}
+
+			claims := keyClaims(key)
+			if err := auth.RequireScopes(m.scopes...).Authorize(r, claims); err != nil {
+				utils.WriteErrorJSON(w, http.StatusForbidden, err, nil)
+				return
+			}
+
+			if key.LastUsedAt == nil || m.store.now().Sub(*key.LastUsedAt) >= lastUsedResolution {
+				m.markUsed(ctx, key)
+			}
+
+			ctx = context.WithValue(ctx, keyKey{}, key)
+			ctx = auth.ContextWithClaims(ctx, claims)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+func (m *middleware) keyFrom(r *http.Request) string {
+	if key := r.Header.Get(m.header); key != "" {
+		return key
+	}
+	if m.queryParam != "" {
+		return r.URL.Query().Get(m.queryParam)
+	}
+	return ""
+}
+
+func keyClaims(key *Key) *auth.Claims {
+	subject := "apikey:" + strconv.FormatInt(key.ID, 10)
+	return &auth.Claims{
+		Subject:  subject,
+		IssuedAt: key.CreatedAt,
+		Raw: map[string]any{
+			"sub":   subject,
+			"name":  key.Name,
+			"scope": strings.Join(key.Scopes, " "),
+		},
+	}
+}
+
+// markUsed records the use of key in the background, so that requests do not
+// wait for the write. Uses of a key are not recorded again while a write for
+// it is in flight.
+func (m *middleware) markUsed(ctx context.Context, key *Key) {
+	if _, busy := m.marking.LoadOrStore(key.ID, struct{}{}); busy {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), markUsedTimeout)
+	go func() {
+		defer m.marking.Delete(key.ID)
+		defer cancel()
+		if err := m.store.MarkUsed(ctx, key.ID); err != nil {
+			m.logger.WarnContext(ctx, "Failed to record API key use", "error", err, "prefix", key.Prefix)
+		}
+	}()
+}
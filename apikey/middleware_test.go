@@ -0,0 +1,172 @@
+package apikey
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/chlovec/rest-pack/auth"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiddleware(t *testing.T) {
+	var gotKey *Key
+	var gotClaims *auth.Claims
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotKey, _ = KeyFromContext(r.Context())
+		gotClaims, _ = auth.ClaimsFromContext(r.Context())
+		w.WriteHeader(http.StatusOK)
+	})
+
+	serve := func(handler http.Handler, target, header string) *httptest.ResponseRecorder {
+		gotKey, gotClaims = nil, nil
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		if header != "" {
+			req.Header.Set(DefaultHeader, header)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	expectKey := func(mock sqlmock.Sqlmock, scopes string, lastUsedAt any) {
+		mock.ExpectQuery(regexp.QuoteMeta(selectKey)).WithArgs(testPrefix).WillReturnRows(sqlmock.NewRows(keyColumns).
+			AddRow(4, "billing", testPrefix, hashKey(testKey), scopes, testNow, lastUsedAt, nil))
+	}
+
+	t.Run("should authenticate keys and record their use", func(t *testing.T) {
+		store, mock := newTestStore(t)
+		expectKey(mock, "products:read products:write", nil)
+		mock.ExpectExec(regexp.QuoteMeta(markKeyUsed)).WithArgs(testNow, 4).WillReturnResult(sqlmock.NewResult(0, 1))
+
+		rr := serve(Middleware(store)(next), "/products", testKey)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, int64(4), gotKey.ID)
+		assert.Equal(t, "apikey:4", gotClaims.Subject)
+		assert.Equal(t, "products:read products:write", gotClaims.Raw["scope"])
+		assert.Eventually(t, func() bool { return mock.ExpectationsWereMet() == nil }, time.Second, time.Millisecond)
+	})
+
+	t.Run("should not record recent use again", func(t *testing.T) {
+		store, mock := newTestStore(t)
+		expectKey(mock, "", testNow.Add(-30*time.Second))
+
+		rr := serve(Middleware(store)(next), "/products", testKey)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("should reject missing and invalid keys", func(t *testing.T) {
+		store, mock := newTestStore(t)
+		handler := Middleware(store)(next)
+
+		rr := serve(handler, "/products", "")
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+		assert.JSONEq(t, `{"error":"missing API key"}`, rr.Body.String())
+
+		rr = serve(handler, "/products?api_key="+testKey, "")
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+
+		rr = serve(handler, "/products", "invalid")
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+		assert.JSONEq(t, `{"error":"invalid API key"}`, rr.Body.String())
+		assert.Nil(t, gotKey)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("should accept keys in a custom header or query parameter", func(t *testing.T) {
+		store, mock := newTestStore(t)
+		handler := Middleware(store, WithHeader("Api-Token"), WithQueryParam("api_key"))(next)
+		expectKey(mock, "", testNow)
+		expectKey(mock, "", testNow)
+
+		assert.Equal(t, http.StatusOK, serve(handler, "/products?api_key="+testKey, "").Code)
+
+		req := httptest.NewRequest(http.MethodGet, "/products", nil)
+		req.Header.Set("Api-Token", testKey)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("should require scopes", func(t *testing.T) {
+		store, mock := newTestStore(t)
+		expectKey(mock, "products:read", testNow)
+
+		rr := serve(Middleware(store, WithScopes("products:read", "products:write"))(next), "/products", testKey)
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+		assert.JSONEq(t, `{"error":"missing scope products:write"}`, rr.Body.String())
+	})
+
+	t.Run("should fail on database errors", func(t *testing.T) {
+		var buf bytes.Buffer
+		store, mock := newTestStore(t)
+		mock.ExpectQuery(regexp.QuoteMeta(selectKey)).WillReturnError(errDatabase)
+
+		rr := serve(Middleware(store, WithLogger(slog.New(slog.NewJSONHandler(&buf, nil))))(next), "/products", testKey)
+
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+		assert.Contains(t, buf.String(), `"msg":"API key verification failed","error":"db error"`)
+	})
+
+	t.Run("should allow requests when recording use fails", func(t *testing.T) {
+		buf := &lockedBuffer{}
+		store, mock := newTestStore(t)
+		expectKey(mock, "", nil)
+		mock.ExpectExec(regexp.QuoteMeta(markKeyUsed)).WillReturnError(errDatabase)
+
+		rr := serve(Middleware(store, WithLogger(slog.New(slog.NewJSONHandler(buf, nil))))(next), "/products", testKey)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Eventually(t, func() bool {
+			return strings.Contains(buf.String(), `"msg":"Failed to record API key use"`)
+		}, time.Second, time.Millisecond)
+	})
+
+	t.Run("should record use once while a write is in flight", func(t *testing.T) {
+		buf := &lockedBuffer{}
+		store, mock := newTestStore(t)
+		handler := Middleware(store, WithLogger(slog.New(slog.NewJSONHandler(buf, nil))))(next)
+		mock.MatchExpectationsInOrder(false)
+		expectKey(mock, "", nil)
+		expectKey(mock, "", nil)
+		mock.ExpectExec(regexp.QuoteMeta(markKeyUsed)).WithArgs(testNow, 4).
+			WillDelayFor(50 * time.Millisecond).WillReturnResult(sqlmock.NewResult(0, 1))
+
+		assert.Equal(t, http.StatusOK, serve(handler, "/products", testKey).Code)
+		assert.Equal(t, http.StatusOK, serve(handler, "/products", testKey).Code)
+		assert.Eventually(t, func() bool { return mock.ExpectationsWereMet() == nil }, time.Second, time.Millisecond)
+		// A second write would fail as unexpected before the first returns.
+		assert.Empty(t, buf.String())
+	})
+}
+
+// lockedBuffer is a bytes.Buffer that can be written from the goroutine
+// recording key use while the test reads it.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
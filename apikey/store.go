@@ -0,0 +1,259 @@
+// Package apikey authenticates machine clients with API keys. Keys are stored
+// in SQL as SHA-256 hashes, so a leaked table does not leak usable keys.
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+)
+
+// Schema creates the api_keys table in MySQL.
+const Schema = `CREATE TABLE IF NOT EXISTS api_keys (
+	id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+	name VARCHAR(255) NOT NULL,
+	prefix CHAR(16) NOT NULL UNIQUE,
+	hash CHAR(64) NOT NULL,
+	scopes VARCHAR(1024) NOT NULL DEFAULT '',
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	last_used_at TIMESTAMP NULL,
+	revoked_at TIMESTAMP NULL
+)`
+
+const (
+	prefixBytes = 8
+	secretBytes = 32
+)
+
+var (
+	ErrNotFound   = errors.New("API key not found")
+	ErrInvalidKey = errors.New("invalid API key")
+)
+
+// Key describes an API key. The key itself is only returned when it is
+// created or rotated.
+type Key struct {
+	ID int64 `json:"id"`
+	// Name describes who or what uses the key.
+	Name string `json:"name"`
+	// Prefix is the public part of the key, which identifies it in logs.
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+	RevokedAt  *time.Time `json:"revokedAt,omitempty"`
+}
+
+// HasScope reports whether the key was granted scope.
+func (k *Key) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+type Store struct {
+	db  *sql.DB
+	now func() time.Time
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db, now: time.Now}
+}
+
+// Create stores a new key and returns it along with the key to hand to the
+// client, which cannot be recovered later.
+func (s *Store) Create(ctx context.Context, name string, scopes []string) (*Key, string, error) {
+	return s.create(ctx, s.db, name, scopes)
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+func (s *Store) create(ctx context.Context, db execer, name string, scopes []string) (*Key, string, error) {
+	prefix, secret, err := generateKey()
+	if err != nil {
+		return nil, "", err
+	}
+	plain := prefix + "." + secret
+
+	key := &Key{
+		Name:      name,
+		Prefix:    prefix,
+		Scopes:    scopes,
+		CreatedAt: s.now().UTC().Truncate(time.Second),
+	}
+	if key.Scopes == nil {
+		key.Scopes = []string{}
+	}
+
+	query := "INSERT INTO api_keys(name, prefix, hash, scopes, created_at) VALUES(?, ?, ?, ?, ?)"
+	res, err := db.ExecContext(ctx, query, name, prefix, hashKey(plain), strings.Join(scopes, " "), key.CreatedAt)
+	if err != nil {
+		return nil, "", err
+	}
+	if key.ID, err = res.LastInsertId(); err != nil {
+		return nil, "", err
+	}
+	return key, plain, nil
+}
+
+// List returns every key, including revoked ones, ordered by ID.
+func (s *Store) List(ctx context.Context) ([]*Key, error) {
+	query := "SELECT id, name, prefix, hash, scopes, created_at, last_used_at, revoked_at FROM api_keys ORDER BY id ASC"
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []*Key{}
+	for rows.Next() {
+		key, _, err := scanKeyRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// Revoke disables a key. It returns ErrNotFound if there is no active key
+// with the ID.
+func (s *Store) Revoke(ctx context.Context, id int64) error {
+	query := "UPDATE api_keys SET revoked_at = ? WHERE id = ? AND revoked_at IS NULL"
+	res, err := s.db.ExecContext(ctx, query, s.now().UTC(), id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Rotate replaces an active key with a new key that has the same name and
+// scopes, and revokes the old one.
+func (s *Store) Rotate(ctx context.Context, id int64) (*Key, string, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	defer tx.Rollback()
+
+	var name, scopes string
+	query := "SELECT name, scopes FROM api_keys WHERE id = ? AND revoked_at IS NULL FOR UPDATE"
+	if err := tx.QueryRowContext(ctx, query, id).Scan(&name, &scopes); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, "", ErrNotFound
+		}
+		return nil, "", err
+	}
+
+	key, plain, err := s.create(ctx, tx, name, splitScopes(scopes))
+	if err != nil {
+		return nil, "", err
+	}
+	query = "UPDATE api_keys SET revoked_at = ? WHERE id = ?"
+	if _, err := tx.ExecContext(ctx, query, s.now().UTC(), id); err != nil {
+		return nil, "", err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, "", err
+	}
+	return key, plain, nil
+}
+
+// Verify returns the active key matching plain, or ErrInvalidKey.
+func (s *Store) Verify(ctx context.Context, plain string) (*Key, error) {
+	prefix, _, ok := strings.Cut(plain, ".")
+	if !ok || len(prefix) != 2*prefixBytes {
+		return nil, ErrInvalidKey
+	}
+
+	query := "SELECT id, name, prefix, hash, scopes, created_at, last_used_at, revoked_at FROM api_keys WHERE prefix = ? AND revoked_at IS NULL LIMIT 1"
+	key, hash, err := scanKeyRow(s.db.QueryRowContext(ctx, query, prefix))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrInvalidKey
+		}
+		return nil, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashKey(plain)), []byte(hash)) != 1 {
+		return nil, ErrInvalidKey
+	}
+	return key, nil
+}
+
+// MarkUsed records that a key was just used.
+func (s *Store) MarkUsed(ctx context.Context, id int64) error {
+	query := "UPDATE api_keys SET last_used_at = ? WHERE id = ?"
+	_, err := s.db.ExecContext(ctx, query, s.now().UTC(), id)
+	return err
+}
+
+func generateKey() (prefix, secret string, err error) {
+	buf := make([]byte, prefixBytes+secretBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	return hex.EncodeToString(buf[:prefixBytes]), base64.RawURLEncoding.EncodeToString(buf[prefixBytes:]), nil
+}
+
+// hashKey hashes a key with SHA-256. Keys are random, so they need no salt
+// or slow hash.
+func hashKey(plain string) string {
+	sum := sha256.Sum256([]byte(plain))
+	return hex.EncodeToString(sum[:])
+}
+
+func splitScopes(scopes string) []string {
+	fields := strings.Fields(scopes)
+	if fields == nil {
+		return []string{}
+	}
+	return fields
+}
+
+func scanKeyRow(scanner interface{ Scan(dest ...any) error }) (*Key, string, error) {
+	var key Key
+	var hash, scopes string
+	var lastUsedAt, revokedAt sql.NullTime
+	err := scanner.Scan(
+		&key.ID,
+		&key.Name,
+		&key.Prefix,
+		&hash,
+		&scopes,
+		&key.CreatedAt,
+		&lastUsedAt,
+		&revokedAt,
+	)
+	if err != nil {
+		return nil, "", err
+	}
+
+	key.Scopes = splitScopes(scopes)
+	if lastUsedAt.Valid {
+		key.LastUsedAt = &lastUsedAt.Time
+	}
+	if revokedAt.Valid {
+		key.RevokedAt = &revokedAt.Time
+	}
+	return &key, hash, nil
+}
@@ -0,0 +1,192 @@
+package apikey
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	selectKeys  = "SELECT id, name, prefix, hash, scopes, created_at, last_used_at, revoked_at FROM api_keys ORDER BY id ASC"
+	selectKey   = "SELECT id, name, prefix, hash, scopes, created_at, last_used_at, revoked_at FROM api_keys WHERE prefix = ? AND revoked_at IS NULL LIMIT 1"
+	insertKey   = "INSERT INTO api_keys(name, prefix, hash, scopes, created_at) VALUES(?, ?, ?, ?, ?)"
+	revokeKey   = "UPDATE api_keys SET revoked_at = ? WHERE id = ? AND revoked_at IS NULL"
+	markKeyUsed = "UPDATE api_keys SET last_used_at = ? WHERE id = ?"
+)
+
+var (
+	testNow     = time.Date(2024, time.March, 5, 13, 55, 0, 0, time.UTC)
+	testPrefix  = "0123456789abcdef"
+	testKey     = testPrefix + ".c2VjcmV0LXNlY3JldC1zZWNyZXQtc2VjcmV0LXNlY3JldA"
+	keyColumns  = []string{"id", "name", "prefix", "hash", "scopes", "created_at", "last_used_at", "revoked_at"}
+	errDatabase = errors.New("db error")
+)
+
+func newTestStore(t *testing.T) (*Store, sqlmock.Sqlmock) {
+	t.Helper()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	store := NewStore(db)
+	store.now = func() time.Time { return testNow }
+	return store, mock
+}
+
+func TestCreate(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("should store the hash of a new key", func(t *testing.T) {
+		store, mock := newTestStore(t)
+		mock.ExpectExec(regexp.QuoteMeta(insertKey)).
+			WithArgs("billing", sqlmock.AnyArg(), sqlmock.AnyArg(), "products:read products:write", testNow).
+			WillReturnResult(sqlmock.NewResult(7, 1))
+
+		key, plain, err := store.Create(ctx, "billing", []string{"products:read", "products:write"})
+		require.NoError(t, err)
+
+		assert.Equal(t, int64(7), key.ID)
+		assert.Equal(t, "billing", key.Name)
+		assert.Equal(t, []string{"products:read", "products:write"}, key.Scopes)
+		assert.Equal(t, testNow, key.CreatedAt)
+		assert.Len(t, key.Prefix, 16)
+		assert.Regexp(t, `^[0-9a-f]{16}\.[A-Za-z0-9_-]{43}$`, plain)
+		assert.Equal(t, key.Prefix, plain[:16])
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("should return database errors", func(t *testing.T) {
+		store, mock := newTestStore(t)
+		mock.ExpectExec(regexp.QuoteMeta(insertKey)).WillReturnError(errDatabase)
+
+		_, _, err := store.Create(ctx, "billing", nil)
+		assert.ErrorIs(t, err, errDatabase)
+	})
+}
+
+func TestList(t *testing.T) {
+	store, mock := newTestStore(t)
+	usedAt := testNow.Add(-time.Hour)
+	mock.ExpectQuery(regexp.QuoteMeta(selectKeys)).WillReturnRows(sqlmock.NewRows(keyColumns).
+		AddRow(1, "billing", testPrefix, "hash", "products:read", testNow, usedAt, nil).
+		AddRow(2, "legacy", "fedcba9876543210", "hash", "", testNow, nil, testNow))
+
+	keys, err := store.List(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, []*Key{
+		{ID: 1, Name: "billing", Prefix: testPrefix, Scopes: []string{"products:read"}, CreatedAt: testNow, LastUsedAt: &usedAt},
+		{ID: 2, Name: "legacy", Prefix: "fedcba9876543210", Scopes: []string{}, CreatedAt: testNow, RevokedAt: &testNow},
+	}, keys)
+}
+
+func TestRevoke(t *testing.T) {
+	ctx := context.Background()
+	store, mock := newTestStore(t)
+
+	mock.ExpectExec(regexp.QuoteMeta(revokeKey)).WithArgs(testNow, 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	assert.NoError(t, store.Revoke(ctx, 1))
+
+	mock.ExpectExec(regexp.QuoteMeta(revokeKey)).WithArgs(testNow, 2).WillReturnResult(sqlmock.NewResult(0, 0))
+	assert.ErrorIs(t, store.Revoke(ctx, 2), ErrNotFound)
+
+	mock.ExpectExec(regexp.QuoteMeta(revokeKey)).WithArgs(testNow, 3).WillReturnError(errDatabase)
+	assert.ErrorIs(t, store.Revoke(ctx, 3), errDatabase)
+}
+
+func TestRotate(t *testing.T) {
+	ctx := context.Background()
+	selectActive := "SELECT name, scopes FROM api_keys WHERE id = ? AND revoked_at IS NULL FOR UPDATE"
+
+	t.Run("should replace the key in a transaction", func(t *testing.T) {
+		store, mock := newTestStore(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(selectActive)).WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"name", "scopes"}).AddRow("billing", "products:read"))
+		mock.ExpectExec(regexp.QuoteMeta(insertKey)).
+			WithArgs("billing", sqlmock.AnyArg(), sqlmock.AnyArg(), "products:read", testNow).
+			WillReturnResult(sqlmock.NewResult(8, 1))
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE api_keys SET revoked_at = ? WHERE id = ?")).
+			WithArgs(testNow, 1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		key, plain, err := store.Rotate(ctx, 1)
+		require.NoError(t, err)
+		assert.Equal(t, int64(8), key.ID)
+		assert.Equal(t, []string{"products:read"}, key.Scopes)
+		assert.NotEmpty(t, plain)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("should roll back when the key is not active", func(t *testing.T) {
+		store, mock := newTestStore(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(selectActive)).WithArgs(2).WillReturnError(sql.ErrNoRows)
+		mock.ExpectRollback()
+
+		_, _, err := store.Rotate(ctx, 2)
+		assert.ErrorIs(t, err, ErrNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("should roll back when the insert fails", func(t *testing.T) {
+		store, mock := newTestStore(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(selectActive)).WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"name", "scopes"}).AddRow("billing", ""))
+		mock.ExpectExec(regexp.QuoteMeta(insertKey)).WillReturnError(errDatabase)
+		mock.ExpectRollback()
+
+		_, _, err := store.Rotate(ctx, 1)
+		assert.ErrorIs(t, err, errDatabase)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestVerify(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("should return the matching key", func(t *testing.T) {
+		store, mock := newTestStore(t)
+		mock.ExpectQuery(regexp.QuoteMeta(selectKey)).WithArgs(testPrefix).WillReturnRows(sqlmock.NewRows(keyColumns).
+			AddRow(1, "billing", testPrefix, hashKey(testKey), "products:read", testNow, nil, nil))
+
+		key, err := store.Verify(ctx, testKey)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), key.ID)
+	})
+
+	t.Run("should reject wrong keys", func(t *testing.T) {
+		store, mock := newTestStore(t)
+		mock.ExpectQuery(regexp.QuoteMeta(selectKey)).WithArgs(testPrefix).WillReturnRows(sqlmock.NewRows(keyColumns).
+			AddRow(1, "billing", testPrefix, hashKey(testKey), "", testNow, nil, nil))
+		mock.ExpectQuery(regexp.QuoteMeta(selectKey)).WithArgs("fedcba9876543210").WillReturnError(sql.ErrNoRows)
+
+		_, err := store.Verify(ctx, testPrefix+".wrong")
+		assert.ErrorIs(t, err, ErrInvalidKey)
+		_, err = store.Verify(ctx, "fedcba9876543210.secret")
+		assert.ErrorIs(t, err, ErrInvalidKey)
+		_, err = store.Verify(ctx, "short.secret")
+		assert.ErrorIs(t, err, ErrInvalidKey)
+		_, err = store.Verify(ctx, "no-separator")
+		assert.ErrorIs(t, err, ErrInvalidKey)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("should return database errors", func(t *testing.T) {
+		store, mock := newTestStore(t)
+		mock.ExpectQuery(regexp.QuoteMeta(selectKey)).WillReturnError(errDatabase)
+
+		_, err := store.Verify(ctx, testKey)
+		assert.ErrorIs(t, err, errDatabase)
+	})
+}
@@ -0,0 +1,311 @@
+// Package auth authenticates requests with JSON Web Tokens sent as bearer
+// tokens and makes their claims available on the request context.
+package auth
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	HS256 = "HS256"
+	RS256 = "RS256"
+	ES256 = "ES256"
+)
+
+var (
+	ErrMissingToken     = errors.New("missing bearer token")
+	ErrMalformedToken   = errors.New("malformed token")
+	ErrUnsupportedAlg   = errors.New("unsupported signing algorithm")
+	ErrUnknownKey       = errors.New("unknown signing key")
+	ErrInvalidSignature = errors.New("invalid token signature")
+	ErrTokenExpired     = errors.New("token has expired")
+	ErrTokenNotYetValid = errors.New("token is not valid yet")
+	ErrInvalidIssuer    = errors.New("invalid token issuer")
+	ErrInvalidAudience  = errors.New("invalid token audience")
+)
+
+// Claims are the claims of a validated token. The registered claims are
+// parsed into fields; Raw holds every claim, including custom ones.
+type Claims struct {
+	Subject   string
+	Issuer    string
+	Audience  []string
+	ExpiresAt time.Time
+	NotBefore time.Time
+	IssuedAt  time.Time
+	ID        string
+	Raw       map[string]any
+}
+
+type Option func(*Validator)
+
+// WithAlgorithms restricts the accepted signing algorithms. By default
+// HS256, RS256 and ES256 are accepted.
+func WithAlgorithms(algorithms ...string) Option {
+	return func(v *Validator) {
+		v.algorithms = algorithms
+	}
+}
+
+// WithIssuer requires the iss claim to be one of issuers.
+func WithIssuer(issuers ...string) Option {
+	return func(v *Validator) {
+		v.issuers = issuers
+	}
+}
+
+// WithAudience requires the aud claim to contain one of audiences.
+func WithAudience(audiences ...string) Option {
+	return func(v *Validator) {
+		v.audiences = audiences
+	}
+}
+
+// WithLeeway tolerates clock skew when checking exp and nbf.
+func WithLeeway(leeway time.Duration) Option {
+	return func(v *Validator) {
+		v.leeway = leeway
+	}
+}
+
+// Validator verifies the signature and claims of tokens.
+type Validator struct {
+	keys       KeySet
+	algorithms []string
+	issuers    []string
+	audiences  []string
+	leeway     time.Duration
+	now        func() time.Time
+}
+
+func NewValidator(keys KeySet, opts ...Option) *Validator {
+	if static, ok := keys.(StaticKeys); ok {
+		for _, key := range static {
+			if secret, ok := key.Key.([]byte); ok && len(secret) < MinHMACKeySize {
+				panic(fmt.Sprintf("auth: key %q: %v", key.ID, ErrWeakKey))
+			}
+		}
+	}
+	v := &Validator{
+		keys:       keys,
+		algorithms: []string{HS256, RS256, ES256},
+		now:        time.Now,
+	}
+	for _, opt := range opts {
+		opt(v)
+	}
+	return v
+}
+
+type header struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// Validate verifies token and returns its claims. Tokens without exp or nbf
+// are not checked for them.
+func (v *Validator) Validate(token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrMalformedToken
+	}
+
+	var h header
+	if err := decodeSegment(parts[0], &h); err != nil {
+		return nil, err
+	}
+	if !slices.Contains(v.algorithms, h.Alg) {
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedAlg, h.Alg)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrMalformedToken
+	}
+	if err := v.verify(h, []byte(parts[0]+"."+parts[1]), signature); err != nil {
+		return nil, err
+	}
+
+	var raw map[string]any
+	if err := decodeSegment(parts[1], &raw); err != nil {
+		return nil, err
+	}
+	claims, err := parseClaims(raw)
+	if err != nil {
+		return nil, err
+	}
+	return claims, v.validateClaims(claims)
+}
+
+// verify checks the signature against every key that may have signed it.
+func (v *Validator) verify(h header, signed, signature []byte) error {
+	found := false
+	for _, key := range v.keys.Keys(h.Kid) {
+		if key.Algorithm != "" && key.Algorithm != h.Alg {
+			continue
+		}
+		ok, usable := verifySignature(h.Alg, key.Key, signed, signature)
+		if ok {
+			return nil
+		}
+		found = found || usable
+	}
+	if !found {
+		return ErrUnknownKey
+	}
+	return ErrInvalidSignature
+}
+
+// verifySignature reports whether signature is valid and whether key can be
+// used with alg at all, which keeps a public key or a short secret from being
+// used as an HMAC secret.
+func verifySignature(alg string, key any, signed, signature []byte) (ok, usable bool) {
+	digest := sha256.Sum256(signed)
+
+	switch alg {
+	case HS256:
+		secret, usable := key.([]byte)
+		if !usable || len(secret) < MinHMACKeySize {
+			return false, false
+		}
+		mac := hmac.New(sha256.New, secret)
+		mac.Write(signed)
+		return hmac.Equal(signature, mac.Sum(nil)), true
+	case RS256:
+		pub, usable := key.(*rsa.PublicKey)
+		if !usable {
+			return false, false
+		}
+		return rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], signature) == nil, true
+	case ES256:
+		pub, usable := key.(*ecdsa.PublicKey)
+		if !usable || pub.Curve != elliptic.P256() {
+			return false, false
+		}
+		if len(signature) != 64 {
+			return false, true
+		}
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		return ecdsa.Verify(pub, digest[:], r, s), true
+	}
+	return false, false
+}
+
+func (v *Validator) validateClaims(c *Claims) error {
+	now := v.now()
+	if !c.ExpiresAt.IsZero() && !now.Before(c.ExpiresAt.Add(v.leeway)) {
+		return ErrTokenExpired
+	}
+	if !c.NotBefore.IsZero() && now.Add(v.leeway).Before(c.NotBefore) {
+		return ErrTokenNotYetValid
+	}
+	if len(v.issuers) > 0 && !slices.Contains(v.issuers, c.Issuer) {
+		return ErrInvalidIssuer
+	}
+	if len(v.audiences) > 0 && !slices.ContainsFunc(c.Audience, func(aud string) bool {
+		return slices.Contains(v.audiences, aud)
+	}) {
+		return ErrInvalidAudience
+	}
+	return nil
+}
+
+func decodeSegment(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return ErrMalformedToken
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(v); err != nil {
+		return ErrMalformedToken
+	}
+	return nil
+}
+
+func parseClaims(raw map[string]any) (*Claims, error) {
+	c := &Claims{Raw: raw}
+
+	var err error
+	if c.Subject, err = stringClaim(raw, "sub"); err != nil {
+		return nil, err
+	}
+	if c.Issuer, err = stringClaim(raw, "iss"); err != nil {
+		return nil, err
+	}
+	if c.ID, err = stringClaim(raw, "jti"); err != nil {
+		return nil, err
+	}
+	if c.ExpiresAt, err = timeClaim(raw, "exp"); err != nil {
+		return nil, err
+	}
+	if c.NotBefore, err = timeClaim(raw, "nbf"); err != nil {
+		return nil, err
+	}
+	if c.IssuedAt, err = timeClaim(raw, "iat"); err != nil {
+		return nil, err
+	}
+
+	switch aud := raw["aud"].(type) {
+	case nil:
+	case string:
+		c.Audience = []string{aud}
+	case []any:
+		for _, item := range aud {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("%w: invalid aud claim", ErrMalformedToken)
+			}
+			c.Audience = append(c.Audience, s)
+		}
+	default:
+		return nil, fmt.Errorf("%w: invalid aud claim", ErrMalformedToken)
+	}
+	return c, nil
+}
+
+func stringClaim(raw map[string]any, name string) (string, error) {
+	value, ok := raw[name]
+	if !ok {
+		return "", nil
+	}
+	s, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("%w: invalid %s claim", ErrMalformedToken, name)
+	}
+	return s, nil
+}
+
+// timeClaim parses a NumericDate, the number of seconds since the epoch.
+func timeClaim(raw map[string]any, name string) (time.Time, error) {
+	value, ok := raw[name]
+	if !ok {
+		return time.Time{}, nil
+	}
+	n, ok := value.(json.Number)
+	if !ok {
+		return time.Time{}, fmt.Errorf("%w: invalid %s claim", ErrMalformedToken, name)
+	}
+	seconds, err := n.Float64()
+	if err != nil || math.IsInf(seconds, 0) {
+		return time.Time{}, fmt.Errorf("%w: invalid %s claim", ErrMalformedToken, name)
+	}
+	whole, frac := math.Modf(seconds)
+	return time.Unix(int64(whole), int64(frac*float64(time.Second))), nil
+}
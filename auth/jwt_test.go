@@ -0,0 +1,215 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	testSecret = []byte("0123456789abcdef0123456789abcdef")
+	testRSAKey = mustRSAKey()
+	testECKey  = mustECKey()
+	testNow    = time.Date(2024, time.March, 5, 13, 55, 0, 0, time.UTC)
+)
+
+func mustRSAKey() *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+	return key
+}
+
+func mustECKey() *ecdsa.PrivateKey {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+	return key
+}
+
+// sign returns a token for claims signed with key, which is a []byte
+// secret, an *rsa.PrivateKey or an *ecdsa.PrivateKey.
+func sign(t *testing.T, alg, kid string, key any, claims map[string]any) string {
+	t.Helper()
+
+	header := map[string]any{"alg": alg, "typ": "JWT"}
+	if kid != "" {
+		header["kid"] = kid
+	}
+	h, err := json.Marshal(header)
+	require.NoError(t, err)
+	c, err := json.Marshal(claims)
+	require.NoError(t, err)
+	signed := base64.RawURLEncoding.EncodeToString(h) + "." + base64.RawURLEncoding.EncodeToString(c)
+
+	digest := sha256.Sum256([]byte(signed))
+	var signature []byte
+	switch key := key.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(signed))
+		signature = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		signature, err = rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+		require.NoError(t, err)
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+		require.NoError(t, err)
+		signature = make([]byte, 64)
+		r.FillBytes(signature[:32])
+		s.FillBytes(signature[32:])
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func testKeys() StaticKeys {
+	return StaticKeys{
+		{ID: "hmac", Algorithm: HS256, Key: testSecret},
+		{ID: "rsa", Key: &testRSAKey.PublicKey},
+		{ID: "ec", Key: &testECKey.PublicKey},
+	}
+}
+
+type keySetFunc func(kid string) []Key
+
+func (f keySetFunc) Keys(kid string) []Key {
+	return f(kid)
+}
+
+func newTestValidator(opts ...Option) *Validator {
+	v := NewValidator(testKeys(), opts...)
+	v.now = func() time.Time { return testNow }
+	return v
+}
+
+func TestValidate(t *testing.T) {
+	claims := map[string]any{
+		"sub":   "user-1",
+		"iss":   "https://issuer.example.com",
+		"aud":   "products",
+		"exp":   testNow.Add(time.Hour).Unix(),
+		"nbf":   testNow.Add(-time.Minute).Unix(),
+		"iat":   1709646900.5,
+		"jti":   "token-1",
+		"roles": []string{"admin"},
+	}
+
+	t.Run("should accept every algorithm", func(t *testing.T) {
+		v := newTestValidator()
+		for _, tc := range []struct {
+			alg, kid string
+			key      any
+		}{
+			{HS256, "hmac", testSecret},
+			{RS256, "rsa", testRSAKey},
+			{ES256, "ec", testECKey},
+			{RS256, "", testRSAKey},
+		} {
+			got, err := v.Validate(sign(t, tc.alg, tc.kid, tc.key, claims))
+			require.NoError(t, err, tc.alg)
+			assert.Equal(t, "user-1", got.Subject)
+			assert.Equal(t, "https://issuer.example.com", got.Issuer)
+			assert.Equal(t, []string{"products"}, got.Audience)
+			assert.Equal(t, testNow.Add(time.Hour), got.ExpiresAt.UTC())
+			assert.Equal(t, time.Unix(1709646900, int64(time.Second/2)), got.IssuedAt)
+			assert.Equal(t, "token-1", got.ID)
+			assert.Equal(t, []any{"admin"}, got.Raw["roles"])
+		}
+	})
+
+	t.Run("should check registered claims", func(t *testing.T) {
+		token := func(overrides map[string]any) string {
+			c := map[string]any{}
+			for k, v := range claims {
+				c[k] = v
+			}
+			for k, v := range overrides {
+				c[k] = v
+			}
+			return sign(t, HS256, "hmac", testSecret, c)
+		}
+		v := newTestValidator(
+			WithIssuer("https://issuer.example.com"),
+			WithAudience("products", "orders"),
+			WithLeeway(30*time.Second),
+		)
+
+		for _, tc := range []struct {
+			name      string
+			overrides map[string]any
+			err       error
+		}{
+			{"valid", nil, nil},
+			{"audience list", map[string]any{"aud": []string{"billing", "orders"}}, nil},
+			{"expired", map[string]any{"exp": testNow.Add(-time.Minute).Unix()}, ErrTokenExpired},
+			{"expired within leeway", map[string]any{"exp": testNow.Add(-10 * time.Second).Unix()}, nil},
+			{"not yet valid", map[string]any{"nbf": testNow.Add(time.Minute).Unix()}, ErrTokenNotYetValid},
+			{"wrong issuer", map[string]any{"iss": "https://evil.example.com"}, ErrInvalidIssuer},
+			{"wrong audience", map[string]any{"aud": "billing"}, ErrInvalidAudience},
+			{"invalid exp", map[string]any{"exp": "tomorrow"}, ErrMalformedToken},
+			{"invalid aud", map[string]any{"aud": 42}, ErrMalformedToken},
+		} {
+			_, err := v.Validate(token(tc.overrides))
+			if tc.err == nil {
+				assert.NoError(t, err, tc.name)
+			} else {
+				assert.ErrorIs(t, err, tc.err, tc.name)
+			}
+		}
+	})
+
+	t.Run("should reject bad signatures and keys", func(t *testing.T) {
+		v := newTestValidator()
+		otherRSA := mustRSAKey()
+
+		token := sign(t, HS256, "hmac", testSecret, claims)
+		tampered := token[:len(token)-4] + "AAAA"
+
+		for _, tc := range []struct {
+			name  string
+			token string
+			err   error
+		}{
+			{"tampered", tampered, ErrInvalidSignature},
+			{"wrong key", sign(t, RS256, "rsa", otherRSA, claims), ErrInvalidSignature},
+			{"unknown kid", sign(t, RS256, "other", testRSAKey, claims), ErrUnknownKey},
+			{"algorithm not allowed for key", sign(t, HS256, "rsa", testSecret, claims), ErrUnknownKey},
+			{"none", sign(t, "none", "", nil, claims), ErrUnsupportedAlg},
+			{"malformed", "not-a-token", ErrMalformedToken},
+			{"bad encoding", "a.b.c", ErrMalformedToken},
+		} {
+			_, err := v.Validate(tc.token)
+			assert.ErrorIs(t, err, tc.err, tc.name)
+		}
+	})
+
+	t.Run("should reject tokens signed with an empty secret", func(t *testing.T) {
+		assert.PanicsWithValue(t, `auth: key "": HMAC key shorter than 32 bytes`, func() {
+			NewValidator(StaticKeys{{Key: []byte("")}})
+		})
+
+		v := NewValidator(keySetFunc(func(kid string) []Key { return []Key{{Key: []byte("")}} }))
+		_, err := v.Validate(sign(t, HS256, "", []byte(""), claims))
+		assert.ErrorIs(t, err, ErrUnknownKey)
+	})
+
+	t.Run("should reject algorithms that are not allowed", func(t *testing.T) {
+		v := newTestValidator(WithAlgorithms(RS256))
+
+		_, err := v.Validate(sign(t, HS256, "hmac", testSecret, claims))
+		assert.ErrorIs(t, err, ErrUnsupportedAlg)
+	})
+}
@@ -0,0 +1,255 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	defaultReloadInterval = 10 * time.Second
+
+	// MinHMACKeySize is the minimum size of HS256 secrets, the size of the
+	// SHA-256 output.
+	MinHMACKeySize = 32
+)
+
+// ErrWeakKey is returned for HS256 secrets shorter than MinHMACKeySize.
+var ErrWeakKey = fmt.Errorf("HMAC key shorter than %d bytes", MinHMACKeySize)
+
+// Key is a key that verifies token signatures.
+type Key struct {
+	// ID is matched against the kid header of tokens. Tokens without a kid
+	// are checked against every key, and keys without an ID against every
+	// token.
+	ID string
+	// Algorithm restricts the key to one algorithm. If empty, the key is
+	// used with every algorithm that fits its type.
+	Algorithm string
+	// Key is a []byte secret of at least MinHMACKeySize bytes for HS256, an
+	// *rsa.PublicKey for RS256 or an *ecdsa.PublicKey on the P-256 curve for
+	// ES256.
+	Key any
+}
+
+// KeySet provides the keys tokens are verified with.
+type KeySet interface {
+	// Keys returns the keys that may have signed a token with the key ID
+	// kid.
+	Keys(kid string) []Key
+}
+
+// StaticKeys is a fixed KeySet. NewValidator panics if it holds an HS256
+// secret shorter than MinHMACKeySize.
+type StaticKeys []Key
+
+func (k StaticKeys) Keys(kid string) []Key {
+	return filterKeys(k, kid)
+}
+
+func filterKeys(keys []Key, kid string) []Key {
+	if kid == "" {
+		return keys
+	}
+
+	var matched []Key
+	for _, key := range keys {
+		if key.ID == kid || key.ID == "" {
+			matched = append(matched, key)
+		}
+	}
+	return matched
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	K   string `json:"k"`
+}
+
+// ParseJWKS parses a JSON Web Key Set. RSA, P-256 EC and symmetric keys are
+// supported; other keys and keys not meant for signatures are skipped.
+func ParseJWKS(data []byte) ([]Key, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("jwks: %w", err)
+	}
+
+	var keys []Key
+	for i, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+
+		var key any
+		var err error
+		switch k.Kty {
+		case "RSA":
+			key, err = parseRSAKey(k)
+		case "EC":
+			if k.Crv != "P-256" {
+				continue
+			}
+			key, err = parseECKey(k)
+		case "oct":
+			key, err = parseOctKey(k)
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("jwks: key %d: %w", i, err)
+		}
+		keys = append(keys, Key{ID: k.Kid, Algorithm: k.Alg, Key: key})
+	}
+	return keys, nil
+}
+
+func parseOctKey(k jwk) ([]byte, error) {
+	secret, err := base64.RawURLEncoding.DecodeString(k.K)
+	if err != nil {
+		return nil, err
+	}
+	if len(secret) < MinHMACKeySize {
+		return nil, ErrWeakKey
+	}
+	return secret, nil
+}
+
+func parseRSAKey(k jwk) (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return nil, err
+	}
+	e, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil {
+		return nil, err
+	}
+	exponent := new(big.Int).SetBytes(e)
+	if len(n) == 0 || !exponent.IsInt64() || exponent.Int64() > 1<<31-1 {
+		return nil, errors.New("invalid RSA key")
+	}
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+}
+
+func parseECKey(k jwk) (*ecdsa.PublicKey, error) {
+	x, err := base64.RawURLEncoding.DecodeString(k.X)
+	if err != nil {
+		return nil, err
+	}
+	y, err := base64.RawURLEncoding.DecodeString(k.Y)
+	if err != nil {
+		return nil, err
+	}
+	key := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+	if !key.Curve.IsOnCurve(key.X, key.Y) {
+		return nil, errors.New("invalid EC key")
+	}
+	return key, nil
+}
+
+// JWKSFile is a KeySet loaded from a local JWKS file. The file is checked for
+// changes at most once per reload interval when keys are looked up.
+type JWKSFile struct {
+	path           string
+	reloadInterval time.Duration
+	logger         *slog.Logger
+
+	mu        sync.RWMutex
+	keys      []Key
+	modTime   time.Time
+	checkedAt time.Time
+}
+
+// NewJWKSFile loads the keys in path. A zero reloadInterval defaults to 10
+// seconds and a negative one disables reloading. Reload failures are logged
+// to logger, or slog.Default() if nil, and the previous keys stay in use.
+func NewJWKSFile(path string, reloadInterval time.Duration, logger *slog.Logger) (*JWKSFile, error) {
+	if reloadInterval == 0 {
+		reloadInterval = defaultReloadInterval
+	}
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	f := &JWKSFile{path: path, reloadInterval: reloadInterval, logger: logger}
+	if err := f.Reload(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Reload loads the file now, for example when the process receives SIGHUP.
+func (f *JWKSFile) Reload() error {
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return fmt.Errorf("jwks: %w", err)
+	}
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return fmt.Errorf("jwks: %w", err)
+	}
+	keys, err := ParseJWKS(data)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.keys = keys
+	f.modTime = info.ModTime()
+	f.checkedAt = time.Now()
+	return nil
+}
+
+func (f *JWKSFile) Keys(kid string) []Key {
+	f.maybeReload()
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return filterKeys(f.keys, kid)
+}
+
+// maybeReload reloads the file if the reload interval has elapsed and it
+// changed.
+func (f *JWKSFile) maybeReload() {
+	if f.reloadInterval < 0 {
+		return
+	}
+
+	f.mu.Lock()
+	if time.Since(f.checkedAt) < f.reloadInterval {
+		f.mu.Unlock()
+		return
+	}
+	f.checkedAt = time.Now()
+	info, err := os.Stat(f.path)
+	changed := err == nil && !info.ModTime().Equal(f.modTime)
+	f.mu.Unlock()
+
+	if !changed {
+		return
+	}
+	if err := f.Reload(); err != nil {
+		f.logger.Error("Failed to reload JWKS, keeping previous keys", "error", err)
+		return
+	}
+	f.logger.Info("Reloaded JWKS", "path", f.path)
+}
@@ -0,0 +1,104 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"log/slog"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func b64(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func testJWKS(t *testing.T) []byte {
+	t.Helper()
+
+	data, err := json.Marshal(map[string]any{"keys": []map[string]any{
+		{"kty": "RSA", "kid": "rsa", "alg": "RS256", "use": "sig", "n": b64(testRSAKey.N.Bytes()), "e": b64(big.NewInt(int64(testRSAKey.E)).Bytes())},
+		{"kty": "EC", "kid": "ec", "crv": "P-256", "x": b64(testECKey.X.Bytes()), "y": b64(testECKey.Y.Bytes())},
+		{"kty": "oct", "kid": "hmac", "k": b64(testSecret)},
+		{"kty": "RSA", "kid": "enc", "use": "enc", "n": b64(testRSAKey.N.Bytes()), "e": "AQAB"},
+		{"kty": "EC", "kid": "p384", "crv": "P-384", "x": "AA", "y": "AA"},
+		{"kty": "OKP", "kid": "ed25519", "crv": "Ed25519", "x": "AA"},
+	}})
+	require.NoError(t, err)
+	return data
+}
+
+func TestParseJWKS(t *testing.T) {
+	t.Run("should parse supported signing keys", func(t *testing.T) {
+		keys, err := ParseJWKS(testJWKS(t))
+		require.NoError(t, err)
+
+		require.Len(t, keys, 3)
+		assert.Equal(t, Key{ID: "rsa", Algorithm: RS256, Key: &testRSAKey.PublicKey}, keys[0])
+		assert.True(t, testECKey.PublicKey.Equal(keys[1].Key))
+		assert.Equal(t, testSecret, keys[2].Key)
+	})
+
+	t.Run("should reject invalid keys", func(t *testing.T) {
+		_, err := ParseJWKS([]byte(`{"keys":[{"kty":"EC","crv":"P-256","x":"AQ","y":"AQ"}]}`))
+		assert.EqualError(t, err, "jwks: key 0: invalid EC key")
+
+		_, err = ParseJWKS([]byte(`{"keys":[{"kty":"RSA","n":"","e":"AQAB"}]}`))
+		assert.EqualError(t, err, "jwks: key 0: invalid RSA key")
+
+		_, err = ParseJWKS([]byte(`{"keys":[{"kty":"oct","k":"c2VjcmV0"}]}`))
+		assert.ErrorIs(t, err, ErrWeakKey)
+
+		_, err = ParseJWKS([]byte(`not json`))
+		assert.Error(t, err)
+	})
+}
+
+func TestJWKSFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(path, testJWKS(t), 0o600))
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+	keys, err := NewJWKSFile(path, time.Nanosecond, logger)
+	require.NoError(t, err)
+
+	v := NewValidator(keys)
+	_, err = v.Validate(sign(t, ES256, "ec", testECKey, map[string]any{"sub": "user-1"}))
+	assert.NoError(t, err)
+
+	t.Run("should keep the keys when the file is broken", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(path, []byte("{"), 0o600))
+		require.NoError(t, os.Chtimes(path, time.Now(), time.Now().Add(time.Minute)))
+
+		assert.Len(t, keys.Keys(""), 3)
+		assert.Contains(t, buf.String(), "Failed to reload JWKS, keeping previous keys")
+	})
+
+	t.Run("should reload changed files", func(t *testing.T) {
+		other := mustECKey()
+		data, err := json.Marshal(map[string]any{"keys": []map[string]any{
+			{"kty": "EC", "kid": "ec2", "crv": "P-256", "x": b64(other.X.Bytes()), "y": b64(other.Y.Bytes())},
+		}})
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(path, data, 0o600))
+		require.NoError(t, os.Chtimes(path, time.Now(), time.Now().Add(2*time.Minute)))
+
+		_, err = v.Validate(sign(t, ES256, "ec2", other, map[string]any{"sub": "user-1"}))
+		assert.NoError(t, err)
+		_, err = v.Validate(sign(t, ES256, "ec", testECKey, map[string]any{"sub": "user-1"}))
+		assert.ErrorIs(t, err, ErrUnknownKey)
+		assert.Contains(t, buf.String(), "Reloaded JWKS")
+	})
+
+	t.Run("should fail for a missing file", func(t *testing.T) {
+		_, err := NewJWKSFile(filepath.Join(t.TempDir(), "missing.json"), 0, nil)
+		assert.Error(t, err)
+	})
+}
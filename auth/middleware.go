@@ -0,0 +1,111 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/chlovec/rest-pack/utils"
+)
+
+type claimsKey struct{}
+
+// ContextWithClaims returns a copy of ctx carrying claims.
+func ContextWithClaims(ctx context.Context, claims *Claims) context.Context {
+	return context.WithValue(ctx, claimsKey{}, claims)
+}
+
+// ClaimsFromContext returns the claims of the token the request was
+// authenticated with.
+func ClaimsFromContext(ctx context.Context) (*Claims, bool) {
+	claims, ok := ctx.Value(claimsKey{}).(*Claims)
+	return claims, ok
+}
+
+type MiddlewareOption func(*middleware)
+
+// WithSkip lets requests for which skip returns true through without a token.
+func WithSkip(skip func(r *http.Request) bool) MiddlewareOption {
+	return func(m *middleware) {
+		m.skip = skip
+	}
+}
+
+// WithAuthorize checks the claims of authenticated requests. Requests the
+// policy denies are rejected with 403 Forbidden.
+func WithAuthorize(policy Policy) MiddlewareOption {
+	return func(m *middleware) {
+		m.policy = policy
+	}
+}
+
+type middleware struct {
+	validator *Validator
+	skip      func(r *http.Request) bool
+	policy    Policy
+}
+
+// Middleware returns middleware that requires a valid bearer token in the
+// Authorization header and stores its claims in the request context.
+// Requests without a valid token are rejected with 401 Unauthorized in the
+// utils.WriteErrorJSON format.
+func Middleware(validator *Validator, opts ...MiddlewareOption) func(http.Handler) http.Handler {
+	m := &middleware{validator: validator}
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if m.skip != nil && m.skip(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			token, err := bearerToken(r)
+			if err != nil {
+				WriteUnauthorized(w, err)
+				return
+			}
+			claims, err := m.validator.Validate(token)
+			if err != nil {
+				WriteUnauthorized(w, err)
+				return
+			}
+
+			if m.policy != nil {
+				if err := m.policy.Authorize(r, claims); err != nil {
+					WriteForbidden(w, err)
+					return
+				}
+			}
+			next.ServeHTTP(w, r.WithContext(ContextWithClaims(r.Context(), claims)))
+		})
+	}
+}
+
+func bearerToken(r *http.Request) (string, error) {
+	scheme, token, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+	token = strings.TrimSpace(token)
+	if !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", ErrMissingToken
+	}
+	return token, nil
+}
+
+// WriteUnauthorized writes a 401 response with a WWW-Authenticate challenge.
+func WriteUnauthorized(w http.ResponseWriter, err error) {
+	challenge := "Bearer"
+	if !errors.Is(err, ErrMissingToken) && !errors.Is(err, ErrUnauthenticated) {
+		challenge += ` error="invalid_token", error_description="` + strings.ReplaceAll(err.Error(), `"`, `'`) + `"`
+	}
+	w.Header().Set("WWW-Authenticate", challenge)
+	utils.WriteErrorJSON(w, http.StatusUnauthorized, err, nil)
+}
+
+// WriteForbidden writes a 403 response for a caller lacking permission.
+func WriteForbidden(w http.ResponseWriter, err error) {
+	w.Header().Set("WWW-Authenticate", `Bearer error="insufficient_scope"`)
+	utils.WriteErrorJSON(w, http.StatusForbidden, err, nil)
+}
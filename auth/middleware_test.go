@@ -0,0 +1,82 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiddleware(t *testing.T) {
+	var got *Claims
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ = ClaimsFromContext(r.Context())
+		w.WriteHeader(http.StatusOK)
+	})
+	valid := sign(t, HS256, "hmac", testSecret, map[string]any{"sub": "user-1", "exp": testNow.Add(time.Hour).Unix()})
+	expired := sign(t, HS256, "hmac", testSecret, map[string]any{"sub": "user-1", "exp": testNow.Add(-time.Hour).Unix()})
+
+	serve := func(handler http.Handler, path, authorization string) *httptest.ResponseRecorder {
+		got = nil
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("should put claims on the context", func(t *testing.T) {
+		rr := serve(Middleware(newTestValidator())(next), "/", "bearer "+valid)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		if assert.NotNil(t, got) {
+			assert.Equal(t, "user-1", got.Subject)
+		}
+	})
+
+	t.Run("should reject missing and invalid tokens", func(t *testing.T) {
+		handler := Middleware(newTestValidator())(next)
+
+		rr := serve(handler, "/", "")
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+		assert.Equal(t, "Bearer", rr.Header().Get("WWW-Authenticate"))
+		assert.JSONEq(t, `{"error":"missing bearer token"}`, rr.Body.String())
+
+		rr = serve(handler, "/", "Basic dXNlcjpwYXNz")
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+
+		rr = serve(handler, "/", "Bearer "+expired)
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+		assert.Equal(t, `Bearer error="invalid_token", error_description="token has expired"`, rr.Header().Get("WWW-Authenticate"))
+		assert.JSONEq(t, `{"error":"token has expired"}`, rr.Body.String())
+		assert.Nil(t, got)
+	})
+
+	t.Run("should skip requests", func(t *testing.T) {
+		handler := Middleware(newTestValidator(), WithSkip(func(r *http.Request) bool {
+			return r.URL.Path == "/health"
+		}))(next)
+
+		assert.Equal(t, http.StatusOK, serve(handler, "/health", "").Code)
+		assert.Equal(t, http.StatusUnauthorized, serve(handler, "/products", "").Code)
+	})
+
+	t.Run("should reject unauthorized callers with 403", func(t *testing.T) {
+		handler := Middleware(newTestValidator(), WithAuthorize(PolicyFunc(func(r *http.Request, claims *Claims) error {
+			if claims.Subject != "admin" {
+				return errors.New("admin only")
+			}
+			return nil
+		})))(next)
+
+		rr := serve(handler, "/", "Bearer "+valid)
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+		assert.Equal(t, `Bearer error="insufficient_scope"`, rr.Header().Get("WWW-Authenticate"))
+		assert.JSONEq(t, `{"error":"admin only"}`, rr.Body.String())
+	})
+}
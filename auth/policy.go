@@ -0,0 +1,116 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// ErrUnauthenticated is returned when a request that must be authorized
+// carries no claims.
+var ErrUnauthenticated = errors.New("authentication required")
+
+// Policy decides whether the caller with claims may make request r. A
+// non-nil error explains why not and is answered with 403 Forbidden.
+type Policy interface {
+	Authorize(r *http.Request, claims *Claims) error
+}
+
+// PolicyFunc adapts a function to a Policy.
+type PolicyFunc func(r *http.Request, claims *Claims) error
+
+func (f PolicyFunc) Authorize(r *http.Request, claims *Claims) error {
+	return f(r, claims)
+}
+
+// RequireRoles allows callers that have at least one of roles.
+func RequireRoles(roles ...string) Policy {
+	return PolicyFunc(func(r *http.Request, claims *Claims) error {
+		for _, role := range claims.Roles() {
+			if slices.Contains(roles, role) {
+				return nil
+			}
+		}
+		if len(roles) == 1 {
+			return errors.New("requires role " + roles[0])
+		}
+		return errors.New("requires one of the roles " + strings.Join(roles, ", "))
+	})
+}
+
+// RequireScopes allows callers that have all of scopes.
+func RequireScopes(scopes ...string) Policy {
+	return PolicyFunc(func(r *http.Request, claims *Claims) error {
+		granted := claims.Scopes()
+		for _, scope := range scopes {
+			if !slices.Contains(granted, scope) {
+				return errors.New("missing scope " + scope)
+			}
+		}
+		return nil
+	})
+}
+
+// AllOf allows callers allowed by every policy.
+func AllOf(policies ...Policy) Policy {
+	return PolicyFunc(func(r *http.Request, claims *Claims) error {
+		for _, policy := range policies {
+			if err := policy.Authorize(r, claims); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// AnyOf allows callers allowed by at least one policy. If none allows the
+// caller, the error of the first policy is returned.
+func AnyOf(policies ...Policy) Policy {
+	return PolicyFunc(func(r *http.Request, claims *Claims) error {
+		var first error
+		for _, policy := range policies {
+			err := policy.Authorize(r, claims)
+			if err == nil {
+				return nil
+			}
+			if first == nil {
+				first = err
+			}
+		}
+		return first
+	})
+}
+
+// Roles returns the roles claim, which may be a list or a space-separated
+// string.
+func (c *Claims) Roles() []string {
+	return stringList(c.Raw["roles"])
+}
+
+// Scopes returns the scope claim, a space-separated string, or else the scp
+// claim, which may also be a list.
+func (c *Claims) Scopes() []string {
+	if scope, ok := c.Raw["scope"]; ok {
+		return stringList(scope)
+	}
+	return stringList(c.Raw["scp"])
+}
+
+func stringList(value any) []string {
+	switch value := value.(type) {
+	case string:
+		return strings.Fields(value)
+	case []string:
+		return value
+	case []any:
+		list := make([]string, 0, len(value))
+		for _, item := range value {
+			if s, ok := item.(string); ok {
+				list = append(list, s)
+			}
+		}
+		return list
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPolicies(t *testing.T) {
+	req := httptest.NewRequest(http.MethodDelete, "/products/1", nil)
+	admin := &Claims{Raw: map[string]any{"roles": []any{"admin", "editor"}, "scope": "products:read products:write"}}
+	viewer := &Claims{Raw: map[string]any{"roles": "viewer", "scp": []any{"products:read"}}}
+
+	t.Run("should require one of the roles", func(t *testing.T) {
+		assert.NoError(t, RequireRoles("admin").Authorize(req, admin))
+		assert.NoError(t, RequireRoles("viewer", "admin").Authorize(req, viewer))
+		assert.EqualError(t, RequireRoles("admin").Authorize(req, viewer), "requires role admin")
+		assert.EqualError(t, RequireRoles("admin", "editor").Authorize(req, viewer), "requires one of the roles admin, editor")
+		assert.Error(t, RequireRoles("admin").Authorize(req, &Claims{}))
+	})
+
+	t.Run("should require all scopes", func(t *testing.T) {
+		assert.NoError(t, RequireScopes("products:read", "products:write").Authorize(req, admin))
+		assert.NoError(t, RequireScopes("products:read").Authorize(req, viewer))
+		assert.EqualError(t, RequireScopes("products:read", "products:write").Authorize(req, viewer), "missing scope products:write")
+		assert.NoError(t, RequireScopes().Authorize(req, &Claims{}))
+	})
+
+	t.Run("should combine policies", func(t *testing.T) {
+		owner := PolicyFunc(func(r *http.Request, claims *Claims) error {
+			if claims.Subject != "user-1" {
+				return ErrUnauthenticated
+			}
+			return nil
+		})
+
+		assert.NoError(t, AllOf(RequireRoles("admin"), RequireScopes("products:write")).Authorize(req, admin))
+		assert.EqualError(t, AllOf(RequireRoles("viewer"), RequireScopes("products:write")).Authorize(req, viewer), "missing scope products:write")
+
+		assert.NoError(t, AnyOf(RequireRoles("admin"), owner).Authorize(req, &Claims{Subject: "user-1"}))
+		assert.EqualError(t, AnyOf(RequireRoles("admin"), owner).Authorize(req, viewer), "requires role admin")
+	})
+}
+
+func TestClaimLists(t *testing.T) {
+	assert.Equal(t, []string{"a", "b"}, (&Claims{Raw: map[string]any{"roles": []string{"a", "b"}}}).Roles())
+	assert.Equal(t, []string{"a"}, (&Claims{Raw: map[string]any{"roles": []any{"a", 1}}}).Roles())
+	assert.Nil(t, (&Claims{Raw: map[string]any{"roles": 1}}).Roles())
+	assert.Equal(t, []string{"read", "write"}, (&Claims{Raw: map[string]any{"scope": "read  write", "scp": "other"}}).Scopes())
+	assert.Nil(t, (&Claims{}).Scopes())
+}
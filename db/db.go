@@ -6,7 +6,11 @@ import (
 	"time"
 )
 
-func InitDB(sqlOpen func(driverName, dataSourceName string) (*sql.DB, error),driverName string, dataSourceName string, timeout time.Duration) (*sql.DB, error) {
+// Option configures a database opened by InitDB before it is pinged, e.g. to
+// set pool limits or record its pool stats.
+type Option func(*sql.DB)
+
+func InitDB(sqlOpen func(driverName, dataSourceName string) (*sql.DB, error),driverName string, dataSourceName string, timeout time.Duration, opts ...Option) (*sql.DB, error) {
 	if timeout == 0 {
 		timeout = 2*time.Second
 	}
@@ -15,6 +19,9 @@ func InitDB(sqlOpen func(driverName, dataSourceName string) (*sql.DB, error),dri
 	if err != nil {
 		return nil, err
 	}
+	for _, opt := range opts {
+		opt(db)
+	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
@@ -1,15 +1,22 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"log"
+	"log/slog"
 	"net/http"
+	"os"
 	"time"
 
 	"github.com/chlovec/rest-pack/api"
 	"github.com/chlovec/rest-pack/db"
 	"github.com/chlovec/rest-pack/examples/config"
 	"github.com/chlovec/rest-pack/examples/services/product"
+	"github.com/chlovec/rest-pack/metrics"
+	"github.com/chlovec/rest-pack/ratelimit"
+	"github.com/chlovec/rest-pack/tracing"
+	"github.com/chlovec/rest-pack/utils"
 	_ "github.com/go-sql-driver/mysql"
 )
 
@@ -18,27 +25,35 @@ func main() {
 	config.InitConfig()
 
 	// start server
-	logger := log.Default()
-	apiServer := api.NewAPIServer(config.Envs.ServerAddress, config.Envs.PathPrefix, logger)
-	err := run(apiServer, sql.Open, config.GetDataSourceName(), logger, 0)
+	logger := utils.NewLogger(os.Stdout, utils.LogFormatJSON, slog.LevelInfo)
+	tracer := tracing.NewTracer(tracing.NewStdoutExporter())
+	apiServer := api.NewAPIServer(config.Envs.ServerAddress, config.Envs.PathPrefix, logger,
+		api.WithMetrics(metrics.NewRegistry(), ""),
+		api.WithTracing(tracer),
+	)
+	err := run(apiServer, tracer.WrapSQLOpen(sql.Open), config.GetDataSourceName(), logger, 0)
 	if err != nil {
 		log.Fatalf("error starting server: %v", err)
 	}
 }
 
-func run(apiServer api.APIServerInterface, sqlOpen func(driverName, dataSourceName string) (*sql.DB, error), dsn string, logger *log.Logger, timeout time.Duration) error {
+func run(apiServer api.APIServerInterface, sqlOpen func(driverName, dataSourceName string) (*sql.DB, error), dsn string, logger *slog.Logger, timeout time.Duration) error {
 	// Create db
 	mysqlDB, err := db.InitDB(sqlOpen, "mysql", dsn, timeout)
 	if err != nil {
 		return err
 	}
-	logger.Println("Initialized DB!")
+	logger.Info("Initialized DB!")
+	apiServer.OnShutdown("database", timeout, func(ctx context.Context) error {
+		return mysqlDB.Close()
+	})
 
 	// Create server and register routes
 	store := product.NewStore(mysqlDB)
 	handler := product.NewHandler(logger, store)
 	apiServer.RegisterRoute("/products", handler.ListProducts, http.MethodGet)
-	apiServer.RegisterRoute("/products", handler.CreateProduct, http.MethodPost)
+	limitCreate := ratelimit.Middleware(ratelimit.NewTokenBucket(ratelimit.NewMemoryStore(), 10, time.Minute, 0), ratelimit.WithLogger(logger))
+	apiServer.RegisterRoute("/products", handler.CreateProduct, http.MethodPost).Use(limitCreate)
 
 	// Start server
 	err = apiServer.Start(timeout)
@@ -2,6 +2,7 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"database/sql"
 	"errors"
 	"log"
@@ -10,6 +11,7 @@ import (
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/chlovec/rest-pack/api"
+	"github.com/chlovec/rest-pack/utils"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 )
@@ -23,8 +25,12 @@ func TestRun_Success(t *testing.T) {
 	mockAPIServer := api.NewMockAPIServerInterface(ctrl)
 
 	// Define the behavior for the mock
+	var closeDB api.Hook
+	mockAPIServer.EXPECT().OnShutdown("database", 5*time.Second, gomock.Any()).Do(func(name string, timeout time.Duration, fn api.Hook) {
+		closeDB = fn
+	}).Times(1)
 	mockAPIServer.EXPECT().RegisterRoute("/products", gomock.Any(), "GET").Times(1)
-	mockAPIServer.EXPECT().RegisterRoute("/products", gomock.Any(), "POST").Times(1)
+	mockAPIServer.EXPECT().RegisterRoute("/products", gomock.Any(), "POST").Return(new(api.Route)).Times(1)
 	mockAPIServer.EXPECT().Start(gomock.Any()).Return(nil).Times(1)
 
 	// Create a mock database connection
@@ -42,7 +48,7 @@ func TestRun_Success(t *testing.T) {
 
 	// Create a bytes buffer to capture logs
 	var logBuffer bytes.Buffer
-	mockLogger := log.New(&logBuffer, "", log.LstdFlags) // Redirect log output to the buffer
+	mockLogger := utils.FromStdLogger(log.New(&logBuffer, "", log.LstdFlags)) // Redirect log output to the buffer
 
 	// Call the function under test
 	err = run(mockAPIServer, mockSQLOpen, "mock-dsn", mockLogger, 5*time.Second)
@@ -51,6 +57,11 @@ func TestRun_Success(t *testing.T) {
 	// Verify that the expected log message was written
 	logContents := logBuffer.String()
 	assert.Contains(t, logContents, "Initialized DB!")
+
+	// Verify that the shutdown hook closes the database
+	mock.ExpectClose()
+	assert.NoError(t, closeDB(context.Background()))
+	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
 func TestRun_InitDBError(t *testing.T) {
@@ -75,7 +86,7 @@ func TestRun_InitDBError(t *testing.T) {
 
 	// Create a bytes buffer to capture logs
 	var logBuffer bytes.Buffer
-	mockLogger := log.New(&logBuffer, "", log.LstdFlags) // Redirect log output to the buffer
+	mockLogger := utils.FromStdLogger(log.New(&logBuffer, "", log.LstdFlags)) // Redirect log output to the buffer
 
 	// Call the function under test
 	err = run(mockAPIServer, mockSQLOpen, "mock-dsn", mockLogger, 5*time.Second)
@@ -91,8 +102,9 @@ func TestRun_ServerStartError(t *testing.T) {
 	mockAPIServer := api.NewMockAPIServerInterface(ctrl)
 
 	// Define the behavior for the mock
+	mockAPIServer.EXPECT().OnShutdown("database", 5*time.Second, gomock.Any()).Times(1)
 	mockAPIServer.EXPECT().RegisterRoute("/products", gomock.Any(), "GET").Times(1)
-	mockAPIServer.EXPECT().RegisterRoute("/products", gomock.Any(), "POST").Times(1)
+	mockAPIServer.EXPECT().RegisterRoute("/products", gomock.Any(), "POST").Return(new(api.Route)).Times(1)
 	mockAPIServer.EXPECT().Start(gomock.Any()).Return(errors.New("failed to start server")).Times(1)
 
 	// Create a mock database connection (sqlmock)
@@ -110,7 +122,7 @@ func TestRun_ServerStartError(t *testing.T) {
 
 	// Create a bytes buffer to capture logs
 	var logBuffer bytes.Buffer
-	mockLogger := log.New(&logBuffer, "", log.LstdFlags) // Redirect log output to the buffer
+	mockLogger := utils.FromStdLogger(log.New(&logBuffer, "", log.LstdFlags)) // Redirect log output to the buffer
 
 	// Call the function under test
 	err = run(mockAPIServer, mockSQLOpen, "mock-dsn", mockLogger, 5*time.Second)
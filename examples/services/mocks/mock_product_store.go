@@ -10,6 +10,7 @@
 package mocks
 
 import (
+	context "context"
 	reflect "reflect"
 
 	types "github.com/chlovec/rest-pack/examples/types"
@@ -41,74 +42,74 @@ func (m *MockProductStore) EXPECT() *MockProductStoreMockRecorder {
 }
 
 // CreateProduct mocks base method.
-func (m *MockProductStore) CreateProduct(product types.CreateProductPayload) (int64, error) {
+func (m *MockProductStore) CreateProduct(ctx context.Context, product types.CreateProductPayload) (int64, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateProduct", product)
+	ret := m.ctrl.Call(m, "CreateProduct", ctx, product)
 	ret0, _ := ret[0].(int64)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // CreateProduct indicates an expected call of CreateProduct.
-func (mr *MockProductStoreMockRecorder) CreateProduct(product any) *gomock.Call {
+func (mr *MockProductStoreMockRecorder) CreateProduct(ctx, product any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateProduct", reflect.TypeOf((*MockProductStore)(nil).CreateProduct), product)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateProduct", reflect.TypeOf((*MockProductStore)(nil).CreateProduct), ctx, product)
 }
 
 // DeleteProduct mocks base method.
-func (m *MockProductStore) DeleteProduct(id int) error {
+func (m *MockProductStore) DeleteProduct(ctx context.Context, id int) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteProduct", id)
+	ret := m.ctrl.Call(m, "DeleteProduct", ctx, id)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // DeleteProduct indicates an expected call of DeleteProduct.
-func (mr *MockProductStoreMockRecorder) DeleteProduct(id any) *gomock.Call {
+func (mr *MockProductStoreMockRecorder) DeleteProduct(ctx, id any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteProduct", reflect.TypeOf((*MockProductStore)(nil).DeleteProduct), id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteProduct", reflect.TypeOf((*MockProductStore)(nil).DeleteProduct), ctx, id)
 }
 
 // GetProduct mocks base method.
-func (m *MockProductStore) GetProduct(id int) (*types.Product, error) {
+func (m *MockProductStore) GetProduct(ctx context.Context, id int) (*types.Product, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetProduct", id)
+	ret := m.ctrl.Call(m, "GetProduct", ctx, id)
 	ret0, _ := ret[0].(*types.Product)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetProduct indicates an expected call of GetProduct.
-func (mr *MockProductStoreMockRecorder) GetProduct(id any) *gomock.Call {
+func (mr *MockProductStoreMockRecorder) GetProduct(ctx, id any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetProduct", reflect.TypeOf((*MockProductStore)(nil).GetProduct), id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetProduct", reflect.TypeOf((*MockProductStore)(nil).GetProduct), ctx, id)
 }
 
 // ListProducts mocks base method.
-func (m *MockProductStore) ListProducts(limit, offset int) ([]*types.Product, error) {
+func (m *MockProductStore) ListProducts(ctx context.Context, limit, offset int) ([]*types.Product, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ListProducts", limit, offset)
+	ret := m.ctrl.Call(m, "ListProducts", ctx, limit, offset)
 	ret0, _ := ret[0].([]*types.Product)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // ListProducts indicates an expected call of ListProducts.
-func (mr *MockProductStoreMockRecorder) ListProducts(limit, offset any) *gomock.Call {
+func (mr *MockProductStoreMockRecorder) ListProducts(ctx, limit, offset any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListProducts", reflect.TypeOf((*MockProductStore)(nil).ListProducts), limit, offset)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListProducts", reflect.TypeOf((*MockProductStore)(nil).ListProducts), ctx, limit, offset)
 }
 
 // UpdateProduct mocks base method.
-func (m *MockProductStore) UpdateProduct(product types.UpdateProductPayload) error {
+func (m *MockProductStore) UpdateProduct(ctx context.Context, product types.UpdateProductPayload) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpdateProduct", product)
+	ret := m.ctrl.Call(m, "UpdateProduct", ctx, product)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // UpdateProduct indicates an expected call of UpdateProduct.
-func (mr *MockProductStoreMockRecorder) UpdateProduct(product any) *gomock.Call {
+func (mr *MockProductStoreMockRecorder) UpdateProduct(ctx, product any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateProduct", reflect.TypeOf((*MockProductStore)(nil).UpdateProduct), product)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateProduct", reflect.TypeOf((*MockProductStore)(nil).UpdateProduct), ctx, product)
 }
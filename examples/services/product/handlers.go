@@ -2,18 +2,17 @@ package product
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"strconv"
 
 	"github.com/chlovec/rest-pack/examples/config"
 	"github.com/chlovec/rest-pack/examples/types"
 	"github.com/chlovec/rest-pack/utils"
-	"github.com/gorilla/mux"
 )
 
 type Handler struct {
-	logger *log.Logger
+	logger *slog.Logger
 	store types.ProductStore
 }
 
@@ -24,13 +23,19 @@ type Product struct {
 	Qty   int     `json:"qty"`
 }
 
-func NewHandler(logger *log.Logger, store types.ProductStore) *Handler {
+func NewHandler(logger *slog.Logger, store types.ProductStore) *Handler {
 	return &Handler{
 		logger: logger,
 		store: store,
 	}
 }
 
+// productIDParams requires the id path variable. It is a pointer so that
+// product id 0 is still looked up.
+type productIDParams struct {
+	ID *int `path:"id" validate:"required"`
+}
+
 func (h *Handler) ListProducts(w http.ResponseWriter, r *http.Request) {
 	// Default values
 	const defaultPageSize = 1000
@@ -53,7 +58,7 @@ func (h *Handler) ListProducts(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Fetch products
-	products, err := h.store.ListProducts(pageSize, pageNum * pageSize)
+	products, err := h.store.ListProducts(r.Context(), pageSize, pageNum * pageSize)
 	if err != nil {
 		utils.WriteInternalServerError(w, "", nil)
 		return
@@ -64,14 +69,13 @@ func (h *Handler) ListProducts(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) GetProduct(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	productId, err := strconv.Atoi(vars["id"])
+	productId, err := GetProductId(r)
 	if err != nil {
 		utils.WriteBadRequest(w, "", nil)
 		return
 	}
 
-	product, err := h.store.GetProduct(productId)
+	product, err := h.store.GetProduct(r.Context(), productId)
 	if err != nil {
 		utils.WriteInternalServerError(w, "", nil)
 		return
@@ -94,13 +98,13 @@ func (h *Handler) CreateProduct(w http.ResponseWriter, r *http.Request) {
 
 	// Validate payload
 	if err := utils.Validate.Struct(product); err != nil {
-		details := utils.GetValidationError(err)
+		details := utils.GetLocalizedValidationError(err, utils.TranslatorOf(w))
 		utils.WriteBadRequest(w, "Validation Error", details)
 		return
 	}
 
 	// Create product
-	productID, err := h.store.CreateProduct(product);
+	productID, err := h.store.CreateProduct(r.Context(), product);
 	if err != nil {
 		utils.WriteInternalServerError(w, "", nil)
 		return
@@ -136,13 +140,13 @@ func (h *Handler) UpdateProduct(w http.ResponseWriter, r *http.Request) {
 
 	// Validate payload
 	if err := utils.Validate.Struct(product); err != nil {
-		details := utils.GetValidationError(err)
+		details := utils.GetLocalizedValidationError(err, utils.TranslatorOf(w))
 		utils.WriteBadRequest(w, "Validation Error", details)
 		return
 	}
 
 	// Check that product exists
-	existingProduct, err := h.store.GetProduct(productID)
+	existingProduct, err := h.store.GetProduct(r.Context(), productID)
 	if err != nil {
 		utils.WriteInternalServerError(w, "", nil)
 		return
@@ -152,7 +156,7 @@ func (h *Handler) UpdateProduct(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Update product
-	err = h.store.UpdateProduct(product);
+	err = h.store.UpdateProduct(r.Context(), product);
 	if err != nil {
 		utils.WriteInternalServerError(w, "", nil)
 		return
@@ -174,7 +178,7 @@ func (h *Handler) DeleteProduct(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Check that product exists
-	product, err := h.store.GetProduct(productID)
+	product, err := h.store.GetProduct(r.Context(), productID)
 	if err != nil {
 		utils.WriteInternalServerError(w, "", nil)
 		return
@@ -184,7 +188,7 @@ func (h *Handler) DeleteProduct(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Create product
-	err = h.store.DeleteProduct(productID);
+	err = h.store.DeleteProduct(r.Context(), productID);
 	if err != nil {
 		utils.WriteInternalServerError(w, "", nil)
 		return
@@ -198,6 +202,9 @@ func (h *Handler) DeleteProduct(w http.ResponseWriter, r *http.Request) {
 }
 
 func GetProductId(r *http.Request) (int, error) {
-	vars := mux.Vars(r)
-	return strconv.Atoi(vars["id"])
+	var params productIDParams
+	if err := utils.Bind(r, &params); err != nil {
+		return 0, err
+	}
+	return *params.ID, nil
 }
\ No newline at end of file
@@ -4,7 +4,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"errors"
-	"log"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -23,11 +23,11 @@ func TestListProductsHandler(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockStore := mocks.NewMockProductStore(ctrl)
-	handler := NewHandler(log.Default(), mockStore)
+	handler := NewHandler(slog.Default(), mockStore)
 
 	t.Run("should list products", func(t *testing.T) {
 		expectedProducts := []*types.Product{&prodA, &prodB}
-		mockStore.EXPECT().ListProducts(1000, 0).Return(expectedProducts, nil)
+		mockStore.EXPECT().ListProducts(gomock.Any(), 1000, 0).Return(expectedProducts, nil)
 
 		req, err := http.NewRequest(http.MethodGet, "/products", nil)
 		assert.NoError(t, err)
@@ -47,7 +47,7 @@ func TestListProductsHandler(t *testing.T) {
 
 	t.Run("should return empty list", func(t *testing.T) {
 		expectedProducts := []*types.Product{}
-		mockStore.EXPECT().ListProducts(1000, 0).Return(expectedProducts, nil)
+		mockStore.EXPECT().ListProducts(gomock.Any(), 1000, 0).Return(expectedProducts, nil)
 
 		req, err := http.NewRequest(http.MethodGet, "/products", nil)
 		assert.NoError(t, err)
@@ -67,7 +67,7 @@ func TestListProductsHandler(t *testing.T) {
 
 	t.Run("should handle page size and number", func(t *testing.T) {
 		expectedProducts := []*types.Product{&prodB}
-		mockStore.EXPECT().ListProducts(100, 800).Return(expectedProducts, nil)
+		mockStore.EXPECT().ListProducts(gomock.Any(), 100, 800).Return(expectedProducts, nil)
 
 		req, err := http.NewRequest(http.MethodGet, "/products?pagesize=100&pagenumber=9", nil)
 		assert.NoError(t, err)
@@ -85,8 +85,23 @@ func TestListProductsHandler(t *testing.T) {
 		assert.Equal(t, expectedProducts, actualProducts)
 	})
 
+	t.Run("should fall back to the defaults for invalid page size and number", func(t *testing.T) {
+		expectedProducts := []*types.Product{&prodA}
+		mockStore.EXPECT().ListProducts(gomock.Any(), 1000, 0).Return(expectedProducts, nil)
+
+		req, err := http.NewRequest(http.MethodGet, "/products?pagesize=abc&pagenumber=-2", nil)
+		assert.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		router := mux.NewRouter()
+		router.HandleFunc("/products", handler.ListProducts).Methods(http.MethodGet)
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+
 	t.Run("should return internal server error", func(t *testing.T) {
-		mockStore.EXPECT().ListProducts(100, 800).Return(nil, errors.New(DbError))
+		mockStore.EXPECT().ListProducts(gomock.Any(), 100, 800).Return(nil, errors.New(DbError))
 
 		req, err := http.NewRequest(http.MethodGet, "/products?pagesize=100&pagenumber=9", nil)
 		assert.NoError(t, err)
@@ -109,10 +124,10 @@ func TestGetProductHandler(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockStore := mocks.NewMockProductStore(ctrl)
-	handler := NewHandler(log.Default(), mockStore)
+	handler := NewHandler(slog.Default(), mockStore)
 
 	t.Run("should return product", func(t *testing.T) {
-		mockStore.EXPECT().GetProduct(1).Return(&prodA, nil)
+		mockStore.EXPECT().GetProduct(gomock.Any(), 1).Return(&prodA, nil)
 
 		req, err := http.NewRequest(http.MethodGet, "/products/1", nil)
 		assert.NoError(t, err)
@@ -131,7 +146,7 @@ func TestGetProductHandler(t *testing.T) {
 	})
 
 	t.Run("should return not found", func(t *testing.T) {
-		mockStore.EXPECT().GetProduct(1).Return(nil, nil)
+		mockStore.EXPECT().GetProduct(gomock.Any(), 1).Return(nil, nil)
 
 		req, err := http.NewRequest(http.MethodGet, "/products/1", nil)
 		assert.NoError(t, err)
@@ -148,8 +163,22 @@ func TestGetProductHandler(t *testing.T) {
 		assert.JSONEq(t, expectedResponse, rr.Body.String())
 	})
 
+	t.Run("should look up product id 0", func(t *testing.T) {
+		mockStore.EXPECT().GetProduct(gomock.Any(), 0).Return(nil, nil)
+
+		req, err := http.NewRequest(http.MethodGet, "/products/0", nil)
+		assert.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		router := mux.NewRouter()
+		router.HandleFunc("/products/{id}", handler.GetProduct).Methods(http.MethodGet)
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+
 	t.Run("should internal server error", func(t *testing.T) {
-		mockStore.EXPECT().GetProduct(1).Return(nil, errors.New(DbError))
+		mockStore.EXPECT().GetProduct(gomock.Any(), 1).Return(nil, errors.New(DbError))
 
 		req, err := http.NewRequest(http.MethodGet, "/products/1", nil)
 		assert.NoError(t, err)
@@ -181,6 +210,18 @@ func TestGetProductHandler(t *testing.T) {
 		}`
 		assert.JSONEq(t, expectedResponse, rr.Body.String())
 	})
+
+	t.Run("should return bad request without an id", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/products", nil)
+		assert.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		router := mux.NewRouter()
+		router.HandleFunc("/products", handler.GetProduct).Methods(http.MethodGet)
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
 }
 
 func TestCreateProductHandler(t *testing.T) {
@@ -193,7 +234,7 @@ func TestCreateProductHandler(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockStore := mocks.NewMockProductStore(ctrl)
-	handler := NewHandler(log.Default(), mockStore)
+	handler := NewHandler(slog.Default(), mockStore)
 
 	t.Run("should create new product", func(t *testing.T) {
 		product := types.CreateProductPayload{
@@ -204,7 +245,7 @@ func TestCreateProductHandler(t *testing.T) {
 			Quantity:    20,
 		}
 
-		mockStore.EXPECT().CreateProduct(product).Return(int64(1), nil)
+		mockStore.EXPECT().CreateProduct(gomock.Any(), product).Return(int64(1), nil)
 
 		// Create http request
 		body, _ := json.Marshal(product)
@@ -236,7 +277,7 @@ func TestCreateProductHandler(t *testing.T) {
 			Quantity:    20,
 		}
 
-		mockStore.EXPECT().CreateProduct(product).Return(int64(0), errors.New("DB error"))
+		mockStore.EXPECT().CreateProduct(gomock.Any(), product).Return(int64(0), errors.New("DB error"))
 
 		// Create http request
 		body, _ := json.Marshal(product)
@@ -290,11 +331,11 @@ func TestCreateProductHandler(t *testing.T) {
 
 		assert.Equal(t, http.StatusBadRequest, rr.Code)
 		expectedResponse := `{
-			"details": {
-				"Name": "'Name' is required",
-				"Price": "'Price' is required", 
-				"Quantity": "'Quantity' is required"
-			}, 
+			"details": [
+				{"code": "required", "field": "name", "message": "'name' is required"},
+				{"code": "required", "field": "price", "message": "'price' is required"},
+				{"code": "required", "field": "quantity", "message": "'quantity' is required"}
+			], 
 			"error":"Validation Error"
 		}`
 		assert.JSONEq(t, expectedResponse, rr.Body.String())
@@ -315,15 +356,15 @@ func TestUpdateProductHandler(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockStore := mocks.NewMockProductStore(ctrl)
-	handler := NewHandler(log.Default(), mockStore)
+	handler := NewHandler(slog.Default(), mockStore)
 
 	rr := httptest.NewRecorder()
 	router := mux.NewRouter()
 	router.HandleFunc("/products/{id}", handler.UpdateProduct).Methods(http.MethodPut)
 
 	t.Run("should update product if it exists", func(t *testing.T) {
-		mockStore.EXPECT().GetProduct(1).Return(&prodA, nil)
-		mockStore.EXPECT().UpdateProduct(product).Return(nil)
+		mockStore.EXPECT().GetProduct(gomock.Any(), 1).Return(&prodA, nil)
+		mockStore.EXPECT().UpdateProduct(gomock.Any(), product).Return(nil)
 
 		// Create http request
 		body, _ := json.Marshal(product)
@@ -354,7 +395,7 @@ func TestUpdateProductHandler(t *testing.T) {
 	})
 
 	t.Run("should return internal server error if get product returns error", func(t *testing.T) {
-		mockStore.EXPECT().GetProduct(1).Return(nil, errors.New(DbError))
+		mockStore.EXPECT().GetProduct(gomock.Any(), 1).Return(nil, errors.New(DbError))
 
 		body, _ := json.Marshal(product)
 		req, err := http.NewRequest(http.MethodPut, "/products/1", bytes.NewReader(body))
@@ -371,8 +412,8 @@ func TestUpdateProductHandler(t *testing.T) {
 	})
 
 	t.Run("should return internal server error if update product returns error", func(t *testing.T) {
-		mockStore.EXPECT().GetProduct(1).Return(&prodA, nil)
-		mockStore.EXPECT().UpdateProduct(product).Return(errors.New(DbError))
+		mockStore.EXPECT().GetProduct(gomock.Any(), 1).Return(&prodA, nil)
+		mockStore.EXPECT().UpdateProduct(gomock.Any(), product).Return(errors.New(DbError))
 
 		body, _ := json.Marshal(product)
 		req, err := http.NewRequest(http.MethodPut, "/products/1", bytes.NewReader(body))
@@ -389,7 +430,7 @@ func TestUpdateProductHandler(t *testing.T) {
 	})
 
 	t.Run("should return bad request if product does not exist", func(t *testing.T) {
-		mockStore.EXPECT().GetProduct(1).Return(nil, nil)
+		mockStore.EXPECT().GetProduct(gomock.Any(), 1).Return(nil, nil)
 
 		body, _ := json.Marshal(product)
 		req, err := http.NewRequest(http.MethodPut, "/products/1", bytes.NewReader(body))
@@ -430,11 +471,11 @@ func TestUpdateProductHandler(t *testing.T) {
 
 		assert.Equal(t, http.StatusBadRequest, rr.Code)
 		expectedResponse := `{
-			"details": {
-				"Name": "'Name' is required",
-				"Price": "'Price' is required", 
-				"Quantity": "'Quantity' is required"
-			}, 
+			"details": [
+				{"code": "required", "field": "name", "message": "'name' is required"},
+				{"code": "required", "field": "price", "message": "'price' is required"},
+				{"code": "required", "field": "quantity", "message": "'quantity' is required"}
+			], 
 			"error":"Validation Error"
 		}`
 		assert.JSONEq(t, expectedResponse, rr.Body.String())
@@ -447,13 +488,13 @@ func TestDeleteProductHandler(t *testing.T) {
 
 	mockStore := mocks.NewMockProductStore(ctrl)
 
-	handler := NewHandler(log.Default(), mockStore)
+	handler := NewHandler(slog.Default(), mockStore)
 	router := mux.NewRouter()
 	router.HandleFunc("/products/{id}", handler.DeleteProduct).Methods(http.MethodDelete)
 
 	t.Run("should delete product", func(t *testing.T) {
-		mockStore.EXPECT().GetProduct(1).Return(&prodA, nil)
-		mockStore.EXPECT().DeleteProduct(1).Return(nil)
+		mockStore.EXPECT().GetProduct(gomock.Any(), 1).Return(&prodA, nil)
+		mockStore.EXPECT().DeleteProduct(gomock.Any(), 1).Return(nil)
 
 		req, err := http.NewRequest(http.MethodDelete, "/products/1", nil)
 		assert.NoError(t, err)
@@ -469,8 +510,8 @@ func TestDeleteProductHandler(t *testing.T) {
 	})
 
 	t.Run("should return internal server error if store fails to delete product", func(t *testing.T) {
-		mockStore.EXPECT().GetProduct(1).Return(&prodA, nil)
-		mockStore.EXPECT().DeleteProduct(1).Return(errors.New(DbError))
+		mockStore.EXPECT().GetProduct(gomock.Any(), 1).Return(&prodA, nil)
+		mockStore.EXPECT().DeleteProduct(gomock.Any(), 1).Return(errors.New(DbError))
 
 		req, err := http.NewRequest(http.MethodDelete, "/products/1", nil)
 		assert.NoError(t, err)
@@ -500,7 +541,7 @@ func TestDeleteProductHandler(t *testing.T) {
 	})
 
 	t.Run("should return internal server error if get product returns error", func(t *testing.T) {
-		mockStore.EXPECT().GetProduct(1).Return(nil, errors.New(DbError))
+		mockStore.EXPECT().GetProduct(gomock.Any(), 1).Return(nil, errors.New(DbError))
 
 		req, err := http.NewRequest(http.MethodDelete, "/products/1", nil)
 		assert.NoError(t, err)
@@ -516,7 +557,7 @@ func TestDeleteProductHandler(t *testing.T) {
 	})
 
 	t.Run("should return bad request if product does not exist", func(t *testing.T) {
-		mockStore.EXPECT().GetProduct(1).Return(nil, nil)
+		mockStore.EXPECT().GetProduct(gomock.Any(), 1).Return(nil, nil)
 		req, err := http.NewRequest(http.MethodDelete, "/products/1", nil)
 		assert.NoError(t, err)
 
@@ -1,6 +1,7 @@
 package product
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 
@@ -15,9 +16,9 @@ func NewStore(db *sql.DB) *Store {
 	return &Store{db: db}
 }
 
-func (s *Store) GetProduct(id int) (*types.Product, error) {
+func (s *Store) GetProduct(ctx context.Context, id int) (*types.Product, error) {
 	query := "SELECT * FROM products WHERE id = ? LIMIT 1"
-	row := s.db.QueryRow(query, id)
+	row := s.db.QueryRowContext(ctx, query, id)
 	product, err := scanProductRow(row)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -29,13 +30,13 @@ func (s *Store) GetProduct(id int) (*types.Product, error) {
 	return product, nil
 }
 
-func (s *Store) ListProducts(limit int, offset int) ([]*types.Product, error) {
+func (s *Store) ListProducts(ctx context.Context, limit int, offset int) ([]*types.Product, error) {
 	if limit <= 0 || limit > 1000 {
 		limit = 1000
 	}
 
 	query := "SELECT * FROM products ORDER BY id ASC LIMIT ? OFFSET ?"
-	rows, err := s.db.Query(query, limit, offset)
+	rows, err := s.db.QueryContext(ctx, query, limit, offset)
 	if err != nil {
 		return nil, err
 	}
@@ -54,9 +55,9 @@ func (s *Store) ListProducts(limit int, offset int) ([]*types.Product, error) {
 	return products, nil
 }
 
-func (s *Store) CreateProduct(product types.CreateProductPayload) (int64, error) {
+func (s *Store) CreateProduct(ctx context.Context, product types.CreateProductPayload) (int64, error) {
 	query := "INSERT INTO products(name, description, ImageUrl, price, quantity) VALUES(?, ?, ?, ?, ?)"
-	res, err := s.db.Exec(query, product.Name, product.Description, product.ImageUrl, product.Price, product.Quantity)
+	res, err := s.db.ExecContext(ctx, query, product.Name, product.Description, product.ImageUrl, product.Price, product.Quantity)
 	if err != nil {
 		return 0, err
 	}
@@ -64,9 +65,9 @@ func (s *Store) CreateProduct(product types.CreateProductPayload) (int64, error)
 	return res.LastInsertId()
 }
 
-func (s *Store) UpdateProduct(product types.UpdateProductPayload) error {
+func (s *Store) UpdateProduct(ctx context.Context, product types.UpdateProductPayload) error {
 	query := "UPDATE products SET name = ?, description = ?, imageUrl = ?, price = ?, quantity = ? WHERE id = ?"
-	_, err := s.db.Exec(query, product.Name, product.Description, product.ImageUrl, product.Price, product.Quantity, product.ID)
+	_, err := s.db.ExecContext(ctx, query, product.Name, product.Description, product.ImageUrl, product.Price, product.Quantity, product.ID)
 	if err != nil {
 		return err
 	}
@@ -74,9 +75,9 @@ func (s *Store) UpdateProduct(product types.UpdateProductPayload) error {
 	return nil
 }
 
-func (s *Store) DeleteProduct(id int) error {
+func (s *Store) DeleteProduct(ctx context.Context, id int) error {
 	query := "DELETE FROM products WHERE id = ?"
-	_, err := s.db.Exec(query, id)
+	_, err := s.db.ExecContext(ctx, query, id)
 	if err != nil {
 		return err
 	}
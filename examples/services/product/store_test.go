@@ -1,14 +1,22 @@
 package product
 
 import (
+	"context"
+	"database/sql"
 	"errors"
+	"io"
 	"log"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
 	"regexp"
 	"testing"
 	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/chlovec/rest-pack/api"
 	"github.com/chlovec/rest-pack/examples/types"
+	"github.com/chlovec/rest-pack/tracing"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -50,7 +58,7 @@ func TestListProduct(t *testing.T) {
 			WithArgs(1000, 0).
 			WillReturnRows(rows)
 
-		actualProducts, err := store.ListProducts(1000, 0)
+		actualProducts, err := store.ListProducts(context.Background(), 1000, 0)
 
 		assert.NoError(t, err)
 		assert.NotNil(t, actualProducts)
@@ -64,7 +72,7 @@ func TestListProduct(t *testing.T) {
 			WithArgs(50, 203).
 			WillReturnRows(rows)
 
-		actualProducts, err := store.ListProducts(50, 203)
+		actualProducts, err := store.ListProducts(context.Background(), 50, 203)
 
 		assert.NoError(t, err)
 		assert.NotNil(t, actualProducts)
@@ -78,7 +86,7 @@ func TestListProduct(t *testing.T) {
 			WithArgs(1000, 0).
 			WillReturnRows(rows)
 
-		products, err := store.ListProducts(0, 0)
+		products, err := store.ListProducts(context.Background(), 0, 0)
 
 		assert.NoError(t, err)
 		log.Printf("products \n%v", products)
@@ -90,7 +98,7 @@ func TestListProduct(t *testing.T) {
 			WithArgs(1000, 0).
 			WillReturnError(errors.New(DbError))
 
-		product, err := store.ListProducts(0, 0)
+		product, err := store.ListProducts(context.Background(), 0, 0)
 
 		assert.Error(t, err)
 		assert.Equal(t, DbError, err.Error())
@@ -105,7 +113,7 @@ func TestListProduct(t *testing.T) {
 			WithArgs(1000, 0).
 			WillReturnRows(rows)
 
-		products, err := store.ListProducts(0, 0)
+		products, err := store.ListProducts(context.Background(), 0, 0)
 		expectedError := "sql: Scan error on column index 6, name \"created_at\": unsupported Scan, storing driver.Value type string into type *time.Time"
 		assert.Error(t, err)
 		assert.Equal(t, expectedError, err.Error())
@@ -128,7 +136,7 @@ func TestGetProduct(t *testing.T) {
 			WithArgs(1).
 			WillReturnRows(rows)
 
-		product, err := store.GetProduct(1)
+		product, err := store.GetProduct(context.Background(), 1)
 
 		assert.NoError(t, err)
 		assert.NotNil(t, product)
@@ -142,7 +150,7 @@ func TestGetProduct(t *testing.T) {
 			WithArgs(1).
 			WillReturnRows(rows)
 
-		product, err := store.GetProduct(1)
+		product, err := store.GetProduct(context.Background(), 1)
 
 		assert.NoError(t, err)
 		assert.Nil(t, product)
@@ -153,7 +161,7 @@ func TestGetProduct(t *testing.T) {
 			WithArgs(1).
 			WillReturnError(errors.New(DbError))
 
-		product, err := store.GetProduct(1)
+		product, err := store.GetProduct(context.Background(), 1)
 
 		assert.Error(t, err)
 		assert.Equal(t, DbError, err.Error())
@@ -180,7 +188,7 @@ func TestCreateProduct(t *testing.T) {
 		mock.ExpectExec("INSERT INTO products").
 			WithArgs(product.Name, product.Description, product.ImageUrl, product.Price, product.Quantity).WillReturnResult(sqlmock.NewResult(One, One))
 
-		res, err := store.CreateProduct(product)
+		res, err := store.CreateProduct(context.Background(), product)
 
 		// Assert
 		assert.NoError(t, err)
@@ -193,7 +201,7 @@ func TestCreateProduct(t *testing.T) {
 		mock.ExpectExec("INSERT INTO products").
 			WithArgs(product.Name, product.Description, product.ImageUrl, product.Price, product.Quantity).WillReturnError(errors.New(DbError))
 
-		res, err := store.CreateProduct(product)
+		res, err := store.CreateProduct(context.Background(), product)
 
 		// Assert
 		assert.Error(t, err)
@@ -224,7 +232,7 @@ func TestUpdateProduct(t *testing.T) {
 			WithArgs(product.Name, product.Description, product.ImageUrl, product.Price, product.Quantity, product.ID).
 			WillReturnResult(sqlmock.NewResult(One, One))
 
-		err := store.UpdateProduct(product)
+		err := store.UpdateProduct(context.Background(), product)
 
 		// Assert
 		assert.NoError(t, err)
@@ -237,7 +245,7 @@ func TestUpdateProduct(t *testing.T) {
 			WithArgs(product.Name, product.Description, product.ImageUrl, product.Price, product.Quantity, product.ID).
 			WillReturnError(errors.New(DbError))
 
-		err := store.UpdateProduct(product)
+		err := store.UpdateProduct(context.Background(), product)
 
 		// Assert
 		assert.Error(t, err)
@@ -258,7 +266,7 @@ func TestDeleteProduct(t *testing.T) {
 			WithArgs(One).
 			WillReturnResult(sqlmock.NewResult(One, One))
 
-		err := store.DeleteProduct(1)
+		err := store.DeleteProduct(context.Background(), 1)
 
 		// Assert
 		assert.NoError(t, err)
@@ -271,7 +279,7 @@ func TestDeleteProduct(t *testing.T) {
 			WithArgs(One).
 			WillReturnError(errors.New(DbError))
 
-		err := store.DeleteProduct(1)
+		err := store.DeleteProduct(context.Background(), 1)
 
 		// Assert
 		assert.Error(t, err)
@@ -279,3 +287,36 @@ func TestDeleteProduct(t *testing.T) {
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 }
+
+func TestStoreTracing(t *testing.T) {
+	dsn := "product_" + t.Name()
+	mockDB, mock, err := sqlmock.NewWithDSN(dsn)
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	exporter := tracing.NewInMemoryExporter()
+	tracer := tracing.NewTracer(exporter)
+	db, err := tracer.WrapSQLOpen(sql.Open)("sqlmock", dsn)
+	assert.NoError(t, err)
+	defer db.Close()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	server := api.NewAPIServer(":8080", "/api", logger, api.WithTracing(tracer))
+	server.RegisterRoute("/products/{id}", NewHandler(logger, NewStore(db)).GetProduct, http.MethodGet)
+
+	rows := sqlmock.NewRows([]string{"id", "name", "description", "imageUrl", "price", "quantity", "createdAt"}).
+		AddRow(prodA.ID, prodA.Name, prodA.Description, prodA.ImageUrl, prodA.Price, prodA.Quantity, prodA.CreatedAt)
+	mock.ExpectQuery("SELECT \\* FROM products WHERE id = \\? LIMIT 1").WithArgs(1).WillReturnRows(rows)
+
+	rr := httptest.NewRecorder()
+	server.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/products/1", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	spans := exporter.Spans()
+	assert.Len(t, spans, 2)
+	assert.Equal(t, "SELECT", spans[0].Name)
+	assert.Equal(t, "GET /api/products/{id}", spans[1].Name)
+	assert.Equal(t, spans[1].TraceID, spans[0].TraceID)
+	assert.Equal(t, spans[1].SpanID, spans[0].ParentSpanID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
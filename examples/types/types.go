@@ -1,6 +1,9 @@
 package types
 
-import "time"
+import (
+	"context"
+	"time"
+)
 
 type Product struct {
 	ID          int       `json:"id"`
@@ -14,11 +17,11 @@ type Product struct {
 
 // Stores
 type ProductStore interface {
-	CreateProduct(product CreateProductPayload) (int64, error)
-	UpdateProduct(product UpdateProductPayload) error
-	DeleteProduct(id int) error
-	GetProduct(id int) (*Product, error)
-	ListProducts(limit int, offset int) ([]*Product, error)
+	CreateProduct(ctx context.Context, product CreateProductPayload) (int64, error)
+	UpdateProduct(ctx context.Context, product UpdateProductPayload) error
+	DeleteProduct(ctx context.Context, id int) error
+	GetProduct(ctx context.Context, id int) (*Product, error)
+	ListProducts(ctx context.Context, limit int, offset int) ([]*Product, error)
 }
 
 // Payloads
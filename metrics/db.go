@@ -0,0 +1,103 @@
+package metrics
+
+import (
+	"bufio"
+	"database/sql"
+	"sort"
+	"sync"
+
+	"github.com/chlovec/rest-pack/db"
+)
+
+type dbStat struct {
+	name  string
+	help  string
+	typ   metricType
+	value func(sql.DBStats) float64
+}
+
+var dbStats = []dbStat{
+	{"db_max_open_connections", "Maximum number of open connections to the database.", gaugeType,
+		func(s sql.DBStats) float64 { return float64(s.MaxOpenConnections) }},
+	{"db_open_connections", "Number of established connections, both in use and idle.", gaugeType,
+		func(s sql.DBStats) float64 { return float64(s.OpenConnections) }},
+	{"db_in_use_connections", "Number of connections currently in use.", gaugeType,
+		func(s sql.DBStats) float64 { return float64(s.InUse) }},
+	{"db_idle_connections", "Number of idle connections.", gaugeType,
+		func(s sql.DBStats) float64 { return float64(s.Idle) }},
+	{"db_wait_count_total", "Total number of connections waited for.", counterType,
+		func(s sql.DBStats) float64 { return float64(s.WaitCount) }},
+	{"db_wait_duration_seconds_total", "Total time blocked waiting for a new connection.", counterType,
+		func(s sql.DBStats) float64 { return s.WaitDuration.Seconds() }},
+	{"db_max_idle_closed_total", "Total number of connections closed due to SetMaxIdleConns.", counterType,
+		func(s sql.DBStats) float64 { return float64(s.MaxIdleClosed) }},
+	{"db_max_idle_time_closed_total", "Total number of connections closed due to SetConnMaxIdleTime.", counterType,
+		func(s sql.DBStats) float64 { return float64(s.MaxIdleTimeClosed) }},
+	{"db_max_lifetime_closed_total", "Total number of connections closed due to SetConnMaxLifetime.", counterType,
+		func(s sql.DBStats) float64 { return float64(s.MaxLifetimeClosed) }},
+}
+
+// dbStatsCollector reads the connection pool stats of its databases each time
+// the registry is scraped.
+type dbStatsCollector struct {
+	mu  sync.Mutex
+	dbs map[string]*sql.DB
+}
+
+// RegisterDB records the connection pool stats of sqlDB, labelled with
+// db=name.
+func (r *Registry) RegisterDB(name string, sqlDB *sql.DB) {
+	c := r.dbCollector()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.dbs[name] = sqlDB
+}
+
+// dbCollector returns the collector of RegisterDB, registering it on
+// first use.
+func (r *Registry) dbCollector() *dbStatsCollector {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.db == nil {
+		r.db = &dbStatsCollector{dbs: make(map[string]*sql.DB)}
+		r.registerLocked(r.db)
+	}
+	return r.db
+}
+
+// DBStats returns a db.InitDB option that records the pool stats of the
+// opened database with RegisterDB.
+func (r *Registry) DBStats(name string) db.Option {
+	return func(sqlDB *sql.DB) {
+		r.RegisterDB(name, sqlDB)
+	}
+}
+
+func (c *dbStatsCollector) names() []string {
+	names := make([]string, len(dbStats))
+	for i, stat := range dbStats {
+		names[i] = stat.name
+	}
+	return names
+}
+
+func (c *dbStatsCollector) write(w *bufio.Writer) {
+	c.mu.Lock()
+	names := make([]string, 0, len(c.dbs))
+	stats := make(map[string]sql.DBStats, len(c.dbs))
+	for name, sqlDB := range c.dbs {
+		names = append(names, name)
+		stats[name] = sqlDB.Stats()
+	}
+	c.mu.Unlock()
+	sort.Strings(names)
+
+	for _, stat := range dbStats {
+		f := newFamily(stat.name, stat.help, stat.typ, []string{"db"}, nil)
+		for _, name := range names {
+			value := stat.value(stats[name])
+			f.update([]string{name}, func(s *series) { s.value = value })
+		}
+		f.write(w)
+	}
+}
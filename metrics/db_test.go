@@ -0,0 +1,50 @@
+package metrics
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/chlovec/rest-pack/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDBStats(t *testing.T) {
+	t.Run("should record pool stats of databases opened with InitDB", func(t *testing.T) {
+		mockDB, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+		assert.NoError(t, err)
+		defer mockDB.Close()
+		mock.ExpectPing()
+
+		reg := NewRegistry()
+		sqlOpen := func(driverName, dataSourceName string) (*sql.DB, error) {
+			return mockDB, nil
+		}
+		_, err = db.InitDB(sqlOpen, "mysql", "dsn", 0, reg.DBStats("main"), func(sqlDB *sql.DB) {
+			sqlDB.SetMaxOpenConns(10)
+		})
+		assert.NoError(t, err)
+
+		out := scrape(t, reg)
+		assert.Contains(t, out, "# TYPE db_max_open_connections gauge\ndb_max_open_connections{db=\"main\"} 10\n")
+		assert.Contains(t, out, "db_open_connections{db=\"main\"} 1\n")
+		assert.Contains(t, out, "db_idle_connections{db=\"main\"} 1\n")
+		assert.Contains(t, out, "# TYPE db_wait_count_total counter\ndb_wait_count_total{db=\"main\"} 0\n")
+	})
+
+	t.Run("should label each database", func(t *testing.T) {
+		primary, _, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer primary.Close()
+		replica, _, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer replica.Close()
+
+		reg := NewRegistry()
+		reg.RegisterDB("replica", replica)
+		reg.RegisterDB("primary", primary)
+
+		assert.Contains(t, scrape(t, reg),
+			"# TYPE db_in_use_connections gauge\ndb_in_use_connections{db=\"primary\"} 0\ndb_in_use_connections{db=\"replica\"} 0\n")
+	})
+}
@@ -0,0 +1,354 @@
+// Package metrics collects counters, gauges and histograms and serves them in
+// the Prometheus text exposition format.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const contentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DefBuckets are the default histogram buckets, in seconds, suited to
+// request latencies.
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+type metricType string
+
+const (
+	counterType   metricType = "counter"
+	gaugeType     metricType = "gauge"
+	histogramType metricType = "histogram"
+)
+
+// collector writes one or more metric families when the registry is scraped.
+type collector interface {
+	names() []string
+	write(w *bufio.Writer)
+}
+
+// Registry holds the metrics served by its Handler. It is safe for concurrent
+// use.
+type Registry struct {
+	mu         sync.Mutex
+	collectors []collector
+	names      map[string]struct{}
+	db         *dbStatsCollector
+}
+
+func NewRegistry() *Registry {
+	return &Registry{names: make(map[string]struct{})}
+}
+
+// register adds c to the registry. It panics if one of its metric names is
+// invalid or already registered, as that is a programming error.
+func (r *Registry) register(c collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.registerLocked(c)
+}
+
+func (r *Registry) registerLocked(c collector) {
+	for _, name := range c.names() {
+		if !validName(name) {
+			panic(fmt.Sprintf("metrics: invalid metric name %q", name))
+		}
+		if _, ok := r.names[name]; ok {
+			panic(fmt.Sprintf("metrics: metric %q is already registered", name))
+		}
+	}
+	for _, name := range c.names() {
+		r.names[name] = struct{}{}
+	}
+	r.collectors = append(r.collectors, c)
+}
+
+// NewCounter registers a counter, a value that only goes up, partitioned by
+// the given label names.
+func (r *Registry) NewCounter(name, help string, labels ...string) *Counter {
+	f := newFamily(name, help, counterType, labels, nil)
+	r.register(f)
+	return &Counter{f}
+}
+
+// NewGauge registers a gauge, a value that can go up and down, partitioned by
+// the given label names.
+func (r *Registry) NewGauge(name, help string, labels ...string) *Gauge {
+	f := newFamily(name, help, gaugeType, labels, nil)
+	r.register(f)
+	return &Gauge{f}
+}
+
+// NewHistogram registers a histogram counting observations in the given
+// buckets, partitioned by the given label names. Nil buckets use DefBuckets.
+func (r *Registry) NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	if buckets == nil {
+		buckets = DefBuckets
+	}
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+
+	f := newFamily(name, help, histogramType, labels, buckets)
+	r.register(f)
+	return &Histogram{f}
+}
+
+// WriteTo writes all metrics to w in the Prometheus text exposition format.
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.Lock()
+	collectors := append([]collector(nil), r.collectors...)
+	r.mu.Unlock()
+
+	cw := &countingWriter{w: w}
+	bw := bufio.NewWriter(cw)
+	for _, c := range collectors {
+		c.write(bw)
+	}
+	err := bw.Flush()
+	return cw.n, err
+}
+
+// Handler returns a handler serving the metrics to Prometheus scrapes.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		r.WriteTo(w)
+	})
+}
+
+type Counter struct {
+	f *family
+}
+
+// Inc adds 1 to the counter with the given label values.
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add adds v to the counter with the given label values. It panics if v is
+// negative.
+func (c *Counter) Add(v float64, labelValues ...string) {
+	if v < 0 {
+		panic("metrics: counter cannot decrease")
+	}
+	c.f.update(labelValues, func(s *series) { s.value += v })
+}
+
+type Gauge struct {
+	f *family
+}
+
+func (g *Gauge) Set(v float64, labelValues ...string) {
+	g.f.update(labelValues, func(s *series) { s.value = v })
+}
+
+func (g *Gauge) Add(v float64, labelValues ...string) {
+	g.f.update(labelValues, func(s *series) { s.value += v })
+}
+
+func (g *Gauge) Inc(labelValues ...string) {
+	g.Add(1, labelValues...)
+}
+
+func (g *Gauge) Dec(labelValues ...string) {
+	g.Add(-1, labelValues...)
+}
+
+type Histogram struct {
+	f *family
+}
+
+// Observe records v in the histogram with the given label values.
+func (h *Histogram) Observe(v float64, labelValues ...string) {
+	h.f.update(labelValues, func(s *series) {
+		for i, upper := range h.f.buckets {
+			if v <= upper {
+				s.buckets[i]++
+			}
+		}
+		s.sum += v
+		s.count++
+	})
+}
+
+type series struct {
+	labelValues []string
+	value       float64
+	buckets     []uint64
+	sum         float64
+	count       uint64
+}
+
+// family is a metric and all of its labelled series.
+type family struct {
+	name    string
+	help    string
+	typ     metricType
+	labels  []string
+	buckets []float64
+
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+func newFamily(name, help string, typ metricType, labels []string, buckets []float64) *family {
+	for _, label := range labels {
+		if !validLabelName(label) || (typ == histogramType && label == "le") {
+			panic(fmt.Sprintf("metrics: invalid label name %q for %q", label, name))
+		}
+	}
+	return &family{
+		name:    name,
+		help:    help,
+		typ:     typ,
+		labels:  labels,
+		buckets: buckets,
+		series:  make(map[string]*series),
+	}
+}
+
+func (f *family) names() []string {
+	return []string{f.name}
+}
+
+func (f *family) update(labelValues []string, fn func(*series)) {
+	if len(labelValues) != len(f.labels) {
+		panic(fmt.Sprintf("metrics: %q expects %d label values, got %d", f.name, len(f.labels), len(labelValues)))
+	}
+
+	key := strings.Join(labelValues, "\xff")
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s, ok := f.series[key]
+	if !ok {
+		s = &series{labelValues: append([]string(nil), labelValues...)}
+		if f.typ == histogramType {
+			s.buckets = make([]uint64, len(f.buckets))
+		}
+		f.series[key] = s
+	}
+	fn(s)
+}
+
+func (f *family) write(w *bufio.Writer) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	keys := make([]string, 0, len(f.series))
+	for key := range f.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	fmt.Fprintf(w, "# HELP %s %s\n", f.name, escapeHelp(f.help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", f.name, f.typ)
+	for _, key := range keys {
+		s := f.series[key]
+		if f.typ != histogramType {
+			writeSample(w, f.name, f.labels, s.labelValues, "", "", s.value)
+			continue
+		}
+
+		for i, upper := range f.buckets {
+			writeSample(w, f.name+"_bucket", f.labels, s.labelValues, "le", formatFloat(upper), float64(s.buckets[i]))
+		}
+		writeSample(w, f.name+"_bucket", f.labels, s.labelValues, "le", "+Inf", float64(s.count))
+		writeSample(w, f.name+"_sum", f.labels, s.labelValues, "", "", s.sum)
+		writeSample(w, f.name+"_count", f.labels, s.labelValues, "", "", float64(s.count))
+	}
+}
+
+// writeSample writes one sample line. extraName and extraValue add a label
+// after the series labels, such as the "le" label of histogram buckets.
+func writeSample(w *bufio.Writer, name string, labels, values []string, extraName, extraValue string, v float64) {
+	w.WriteString(name)
+	if len(labels) > 0 || extraName != "" {
+		w.WriteByte('{')
+		for i, label := range labels {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			writeLabel(w, label, values[i])
+		}
+		if extraName != "" {
+			if len(labels) > 0 {
+				w.WriteByte(',')
+			}
+			writeLabel(w, extraName, extraValue)
+		}
+		w.WriteByte('}')
+	}
+	w.WriteByte(' ')
+	w.WriteString(formatFloat(v))
+	w.WriteByte('\n')
+}
+
+func writeLabel(w *bufio.Writer, name, value string) {
+	w.WriteString(name)
+	w.WriteString(`="`)
+	w.WriteString(labelValueEscaper.Replace(value))
+	w.WriteByte('"')
+}
+
+var (
+	labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	helpEscaper       = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+func escapeHelp(help string) string {
+	return helpEscaper.Replace(help)
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+}
+
+// validName reports whether name matches [a-zA-Z_:][a-zA-Z0-9_:]*.
+func validName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, c := range name {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c == '_', c == ':':
+		case c >= '0' && c <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// validLabelName reports whether name is a metric name without colons that
+// is not reserved for internal use.
+func validLabelName(name string) bool {
+	return validName(name) && !strings.Contains(name, ":") && !strings.HasPrefix(name, "__")
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.n += int64(n)
+	return n, err
+}
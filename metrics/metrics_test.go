@@ -0,0 +1,124 @@
+package metrics
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func scrape(t *testing.T, reg *Registry) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	n, err := reg.WriteTo(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(buf.Len()), n)
+	return buf.String()
+}
+
+func TestCounter(t *testing.T) {
+	reg := NewRegistry()
+	counter := reg.NewCounter("jobs_total", "Jobs processed.", "queue", "result")
+
+	counter.Inc("emails", "ok")
+	counter.Add(2, "emails", "ok")
+	counter.Inc("sms", "failed")
+
+	assert.Equal(t, `# HELP jobs_total Jobs processed.
+# TYPE jobs_total counter
+jobs_total{queue="emails",result="ok"} 3
+jobs_total{queue="sms",result="failed"} 1
+`, scrape(t, reg))
+	assert.Panics(t, func() { counter.Add(-1, "emails", "ok") })
+	assert.Panics(t, func() { counter.Inc("emails") })
+}
+
+func TestGauge(t *testing.T) {
+	reg := NewRegistry()
+	gauge := reg.NewGauge("temperature_celsius", "Current temperature.")
+
+	gauge.Set(20.5)
+	gauge.Inc()
+	gauge.Dec()
+	gauge.Add(-0.25)
+
+	assert.Equal(t, `# HELP temperature_celsius Current temperature.
+# TYPE temperature_celsius gauge
+temperature_celsius 20.25
+`, scrape(t, reg))
+}
+
+func TestHistogram(t *testing.T) {
+	reg := NewRegistry()
+	histogram := reg.NewHistogram("latency_seconds", "Latency.", []float64{1, 0.1}, "op")
+
+	histogram.Observe(0.05, "read")
+	histogram.Observe(0.5, "read")
+	histogram.Observe(3, "read")
+
+	assert.Equal(t, `# HELP latency_seconds Latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{op="read",le="0.1"} 1
+latency_seconds_bucket{op="read",le="1"} 2
+latency_seconds_bucket{op="read",le="+Inf"} 3
+latency_seconds_sum{op="read"} 3.55
+latency_seconds_count{op="read"} 3
+`, scrape(t, reg))
+}
+
+func TestRegistry(t *testing.T) {
+	t.Run("should escape help and label values", func(t *testing.T) {
+		reg := NewRegistry()
+		reg.NewCounter("escaped_total", "Line one\nback\\slash", "path").Inc("/a\"b\\c\nd")
+
+		assert.Equal(t, `# HELP escaped_total Line one\nback\\slash
+# TYPE escaped_total counter
+escaped_total{path="/a\"b\\c\nd"} 1
+`, scrape(t, reg))
+	})
+
+	t.Run("should reject invalid and duplicate metrics", func(t *testing.T) {
+		reg := NewRegistry()
+		reg.NewCounter("requests_total", "")
+
+		assert.Panics(t, func() { reg.NewGauge("requests_total", "") })
+		assert.Panics(t, func() { reg.NewGauge("1st_metric", "") })
+		assert.Panics(t, func() { reg.NewGauge("with-dash", "") })
+		assert.Panics(t, func() { reg.NewGauge("valid", "", "__reserved") })
+		assert.Panics(t, func() { reg.NewHistogram("valid", "", nil, "le") })
+	})
+
+	t.Run("should be safe for concurrent use", func(t *testing.T) {
+		reg := NewRegistry()
+		counter := reg.NewCounter("hits_total", "", "page")
+
+		var wg sync.WaitGroup
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				counter.Inc("home")
+				scrape(t, reg)
+			}()
+		}
+		wg.Wait()
+
+		assert.Contains(t, scrape(t, reg), `hits_total{page="home"} 50`)
+	})
+
+	t.Run("should serve the text exposition format", func(t *testing.T) {
+		reg := NewRegistry()
+		reg.NewGauge("up", "Whether the service is up.").Set(1)
+
+		rr := httptest.NewRecorder()
+		reg.Handler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", rr.Header().Get("Content-Type"))
+		assert.Contains(t, rr.Body.String(), "up 1\n")
+	})
+}
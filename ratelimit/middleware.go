@@ -0,0 +1,126 @@
+package ratelimit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/chlovec/rest-pack/utils"
+)
+
+// KeyFunc returns the key a request is limited by.
+type KeyFunc func(r *http.Request) string
+
+// KeyByIP limits each client IP address, taken from the request's remote
+// address. Behind a proxy, rewrite RemoteAddr first or use KeyByHeader.
+func KeyByIP() KeyFunc {
+	return func(r *http.Request) string {
+		return "ip:" + clientIP(r)
+	}
+}
+
+// KeyByHeader limits each value of the header, such as "X-Real-IP", falling
+// back to the client IP when the header is missing.
+func KeyByHeader(header string) KeyFunc {
+	return func(r *http.Request) string {
+		if value := r.Header.Get(header); value != "" {
+			return "header:" + header + ":" + value
+		}
+		return "ip:" + clientIP(r)
+	}
+}
+
+// KeyByAPIKey limits each API key sent in the header, falling back to the
+// client IP for requests without a key. Keys are hashed with SHA-256, so that
+// they are not stored in plain text.
+func KeyByAPIKey(header string) KeyFunc {
+	return func(r *http.Request) string {
+		if key := r.Header.Get(header); key != "" {
+			sum := sha256.Sum256([]byte(key))
+			return "apikey:" + hex.EncodeToString(sum[:])
+		}
+		return "ip:" + clientIP(r)
+	}
+}
+
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+type Option func(*middleware)
+
+// WithKeyFunc sets how requests are grouped. It defaults to KeyByIP.
+func WithKeyFunc(keyFunc KeyFunc) Option {
+	return func(m *middleware) {
+		m.keyFunc = keyFunc
+	}
+}
+
+// WithLogger logs store errors. Requests are allowed when the store fails.
+func WithLogger(logger *slog.Logger) Option {
+	return func(m *middleware) {
+		m.logger = logger
+	}
+}
+
+type middleware struct {
+	limiter Limiter
+	keyFunc KeyFunc
+	logger  *slog.Logger
+}
+
+// Middleware returns middleware that rejects requests over the limit with a
+// 429 in the utils.WriteErrorJSON format and a Retry-After header. Every
+// response carries the RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset
+// and RateLimit-Policy headers. Requests are allowed if the store fails.
+func Middleware(limiter Limiter, opts ...Option) func(http.Handler) http.Handler {
+	m := &middleware{limiter: limiter, keyFunc: KeyByIP()}
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			result, err := m.limiter.Allow(r.Context(), m.keyFunc(r))
+			if err != nil {
+				if m.logger != nil {
+					m.logger.ErrorContext(r.Context(), "Rate limit check failed", "error", err)
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			h := w.Header()
+			h.Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+			h.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			h.Set("RateLimit-Reset", ceilSeconds(result.Reset))
+			h.Set("RateLimit-Policy", strconv.Itoa(result.Limit)+";w="+ceilSeconds(result.Window))
+
+			if !result.Allowed {
+				retryAfter := max(1, int(math.Ceil(result.RetryAfter.Seconds())))
+				h.Set("Retry-After", strconv.Itoa(retryAfter))
+				utils.WriteErrorJSON(w, http.StatusTooManyRequests, errors.New(http.StatusText(http.StatusTooManyRequests)), map[string]any{
+					"retryAfter": retryAfter,
+				})
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ceilSeconds formats d in whole seconds, rounding up so that clients do not
+// retry too early.
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,111 @@
+package ratelimit
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func okHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+func TestMiddleware(t *testing.T) {
+	t.Run("should set headers and reject requests over the limit", func(t *testing.T) {
+		clock := newFakeClock()
+		limiter := NewTokenBucket(NewMemoryStore(), 2, time.Minute, 0)
+		limiter.now = clock.Now
+		handler := Middleware(limiter)(okHandler())
+
+		serve := func() *httptest.ResponseRecorder {
+			req := httptest.NewRequest(http.MethodGet, "/products", nil)
+			req.RemoteAddr = "192.0.2.1:1234"
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+			return rr
+		}
+
+		rr := serve()
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "2", rr.Header().Get("RateLimit-Limit"))
+		assert.Equal(t, "1", rr.Header().Get("RateLimit-Remaining"))
+		assert.Equal(t, "30", rr.Header().Get("RateLimit-Reset"))
+		assert.Equal(t, "2;w=60", rr.Header().Get("RateLimit-Policy"))
+		assert.Empty(t, rr.Header().Get("Retry-After"))
+
+		assert.Equal(t, http.StatusOK, serve().Code)
+
+		rr = serve()
+		assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+		assert.Equal(t, "0", rr.Header().Get("RateLimit-Remaining"))
+		assert.Equal(t, "30", rr.Header().Get("Retry-After"))
+		assert.JSONEq(t, `{"error":"Too Many Requests","details":{"retryAfter":30}}`, rr.Body.String())
+	})
+
+	t.Run("should round Retry-After up to one second", func(t *testing.T) {
+		clock := newFakeClock()
+		limiter := NewTokenBucket(NewMemoryStore(), 1000, time.Second, 1)
+		limiter.now = clock.Now
+		handler := Middleware(limiter)(okHandler())
+
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+		assert.Equal(t, "1", rr.Header().Get("Retry-After"))
+	})
+
+	t.Run("should allow requests and log when the store fails", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := slog.New(slog.NewJSONHandler(&buf, nil))
+		handler := Middleware(NewTokenBucket(failingStore{}, 1, time.Second, 0), WithLogger(logger))(okHandler())
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Empty(t, rr.Header().Get("RateLimit-Limit"))
+		assert.Contains(t, buf.String(), `"msg":"Rate limit check failed","error":"store unavailable"`)
+	})
+
+	t.Run("should limit each key separately", func(t *testing.T) {
+		handler := Middleware(NewSlidingWindow(NewMemoryStore(), 1, time.Hour), WithKeyFunc(KeyByAPIKey("X-API-Key")))(okHandler())
+
+		serve := func(key string) int {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("X-API-Key", key)
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+			return rr.Code
+		}
+
+		assert.Equal(t, http.StatusOK, serve("a"))
+		assert.Equal(t, http.StatusOK, serve("b"))
+		assert.Equal(t, http.StatusTooManyRequests, serve("a"))
+	})
+}
+
+func TestKeyFuncs(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "192.0.2.1:1234"
+
+	assert.Equal(t, "ip:192.0.2.1", KeyByIP()(req))
+	assert.Equal(t, "ip:192.0.2.1", KeyByHeader("X-Real-IP")(req))
+	assert.Equal(t, "ip:192.0.2.1", KeyByAPIKey("X-API-Key")(req))
+
+	req.Header.Set("X-Real-IP", "198.51.100.7")
+	req.Header.Set("X-API-Key", "secret")
+	assert.Equal(t, "header:X-Real-IP:198.51.100.7", KeyByHeader("X-Real-IP")(req))
+	assert.Equal(t, "apikey:2bb80d537b1da3e38bd30361aa855686bde0eacd7162fef6a25fe97bf527a25b", KeyByAPIKey("X-API-Key")(req))
+
+	req.RemoteAddr = "unix"
+	assert.Equal(t, "ip:unix", KeyByIP()(req))
+}
@@ -0,0 +1,220 @@
+// Package ratelimit limits how often clients may call an API, using a token
+// bucket or a sliding window whose state is kept in a Store shared by all
+// instances of a service.
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var errInvalidState = errors.New("ratelimit: invalid state")
+
+// Result is the outcome of a rate limit check.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is the time until the limit is fully replenished.
+	Reset time.Duration
+	// RetryAfter is the time until the next request is allowed. It is zero
+	// when the request is allowed.
+	RetryAfter time.Duration
+	// Window is the period over which Limit requests are allowed.
+	Window time.Duration
+}
+
+// Limiter decides whether a request identified by key is allowed.
+type Limiter interface {
+	Allow(ctx context.Context, key string) (Result, error)
+}
+
+// TokenBucket allows bursts of up to burst requests and refills at a rate of
+// limit requests per period.
+type TokenBucket struct {
+	store  Store
+	limit  int
+	period time.Duration
+	burst  int
+	now    func() time.Time
+}
+
+// NewTokenBucket returns a token bucket limiter. A non-positive burst
+// defaults to limit. It panics if limit or period is not positive.
+func NewTokenBucket(store Store, limit int, period time.Duration, burst int) *TokenBucket {
+	checkLimit(limit, period)
+	if burst <= 0 {
+		burst = limit
+	}
+	return &TokenBucket{store: store, limit: limit, period: period, burst: burst, now: time.Now}
+}
+
+func (b *TokenBucket) Allow(ctx context.Context, key string) (Result, error) {
+	now := b.now()
+	rate := float64(b.limit) / b.period.Seconds()
+	result := Result{Limit: b.burst, Window: b.period}
+
+	err := b.store.Update(ctx, "tb:"+key, b.ttl(), func(state []byte) ([]byte, error) {
+		// The store may call fn again after a conflict.
+		result = Result{Limit: b.burst, Window: b.period}
+		tokens, last := float64(b.burst), now
+		if state != nil {
+			parts, err := splitState(state, 2)
+			if err != nil {
+				return nil, err
+			}
+			tokens, err = strconv.ParseFloat(parts[0], 64)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %q", errInvalidState, state)
+			}
+			nanos, err := strconv.ParseInt(parts[1], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %q", errInvalidState, state)
+			}
+			last = time.Unix(0, nanos)
+		}
+
+		if elapsed := now.Sub(last).Seconds(); elapsed > 0 {
+			tokens = math.Min(float64(b.burst), tokens+elapsed*rate)
+		}
+
+		result.Allowed = tokens >= 1
+		if result.Allowed {
+			tokens--
+		} else {
+			result.RetryAfter = seconds((1 - tokens) / rate)
+		}
+		result.Remaining = int(tokens)
+		result.Reset = seconds((float64(b.burst) - tokens) / rate)
+
+		return joinState(strconv.FormatFloat(tokens, 'f', -1, 64), strconv.FormatInt(now.UnixNano(), 10)), nil
+	})
+	return result, err
+}
+
+// ttl is how long an idle bucket is kept; by then it would be full again.
+func (b *TokenBucket) ttl() time.Duration {
+	return time.Duration(float64(b.period) * float64(b.burst) / float64(b.limit))
+}
+
+// SlidingWindow allows limit requests in any period, estimating the number of
+// requests in the sliding window from the counts of the current and previous
+// fixed windows.
+type SlidingWindow struct {
+	store  Store
+	limit  int
+	period time.Duration
+	now    func() time.Time
+}
+
+// NewSlidingWindow returns a sliding window limiter. It panics if limit or
+// period is not positive.
+func NewSlidingWindow(store Store, limit int, period time.Duration) *SlidingWindow {
+	checkLimit(limit, period)
+	return &SlidingWindow{store: store, limit: limit, period: period, now: time.Now}
+}
+
+func (s *SlidingWindow) Allow(ctx context.Context, key string) (Result, error) {
+	now := s.now()
+	result := Result{Limit: s.limit, Window: s.period}
+
+	err := s.store.Update(ctx, "sw:"+key, 2*s.period, func(state []byte) ([]byte, error) {
+		// The store may call fn again after a conflict.
+		result = Result{Limit: s.limit, Window: s.period}
+		start := now.Truncate(s.period)
+		var current, previous int64
+		if state != nil {
+			values, err := parseIntState(state, 3)
+			if err != nil {
+				return nil, err
+			}
+			switch stored := time.Unix(0, values[0]); {
+			case stored.Equal(start):
+				current, previous = values[1], values[2]
+			case stored.Equal(start.Add(-s.period)):
+				previous = values[1]
+			}
+		}
+
+		elapsed := now.Sub(start)
+		weight := 1 - elapsed.Seconds()/s.period.Seconds()
+		count := float64(previous)*weight + float64(current)
+
+		result.Allowed = count+1 <= float64(s.limit)
+		if result.Allowed {
+			current++
+			count++
+		} else {
+			result.RetryAfter = s.retryAfter(float64(previous), float64(current), elapsed)
+		}
+		result.Remaining = max(0, s.limit-int(math.Ceil(count)))
+		result.Reset = s.period - elapsed
+
+		return joinState(strconv.FormatInt(start.UnixNano(), 10), strconv.FormatInt(current, 10), strconv.FormatInt(previous, 10)), nil
+	})
+	return result, err
+}
+
+// retryAfter returns how long until the weighted count drops enough for one
+// more request, which happens at the latest when the window rolls over and
+// only a fraction of the current count is left.
+func (s *SlidingWindow) retryAfter(previous, current float64, elapsed time.Duration) time.Duration {
+	untilRollover := s.period - elapsed
+	excess := previous*(1-elapsed.Seconds()/s.period.Seconds()) + current - float64(s.limit-1)
+	if previous > 0 && current <= float64(s.limit-1) {
+		if wait := seconds(excess / previous * s.period.Seconds()); wait < untilRollover {
+			return wait
+		}
+	}
+	if current <= float64(s.limit-1) {
+		return untilRollover
+	}
+	// The whole current count carries over into the next window.
+	return untilRollover + seconds((current-float64(s.limit-1))/current*s.period.Seconds())
+}
+
+func checkLimit(limit int, period time.Duration) {
+	if limit <= 0 {
+		panic(fmt.Sprintf("ratelimit: limit must be positive, got %d", limit))
+	}
+	if period <= 0 {
+		panic(fmt.Sprintf("ratelimit: period must be positive, got %s", period))
+	}
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(math.Ceil(s * float64(time.Second)))
+}
+
+// The state of a key is stored as its fields separated by colons.
+func joinState(parts ...string) []byte {
+	return []byte(strings.Join(parts, ":"))
+}
+
+func splitState(state []byte, n int) ([]string, error) {
+	parts := strings.Split(string(state), ":")
+	if len(parts) != n {
+		return nil, fmt.Errorf("%w: %q", errInvalidState, state)
+	}
+	return parts, nil
+}
+
+func parseIntState(state []byte, n int) ([]int64, error) {
+	parts, err := splitState(state, n)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]int64, n)
+	for i, part := range parts {
+		if values[i], err = strconv.ParseInt(part, 10, 64); err != nil {
+			return nil, fmt.Errorf("%w: %q", errInvalidState, state)
+		}
+	}
+	return values, nil
+}
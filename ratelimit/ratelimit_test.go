@@ -0,0 +1,181 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2024, time.March, 5, 13, 55, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func allow(t *testing.T, limiter Limiter, key string) Result {
+	t.Helper()
+
+	result, err := limiter.Allow(context.Background(), key)
+	assert.NoError(t, err)
+	return result
+}
+
+func testTokenBucket(t *testing.T, store Store) {
+	clock := newFakeClock()
+	limiter := NewTokenBucket(store, 1, time.Second, 3)
+	limiter.now = clock.Now
+
+	for i := 2; i >= 0; i-- {
+		result := allow(t, limiter, "client")
+		assert.True(t, result.Allowed)
+		assert.Equal(t, 3, result.Limit)
+		assert.Equal(t, i, result.Remaining)
+	}
+
+	result := allow(t, limiter, "client")
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 0, result.Remaining)
+	assert.Equal(t, time.Second, result.RetryAfter)
+	assert.Equal(t, 3*time.Second, result.Reset)
+
+	assert.True(t, allow(t, limiter, "other").Allowed)
+
+	clock.Advance(500 * time.Millisecond)
+	result = allow(t, limiter, "client")
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 500*time.Millisecond, result.RetryAfter)
+
+	clock.Advance(500 * time.Millisecond)
+	assert.True(t, allow(t, limiter, "client").Allowed)
+
+	clock.Advance(time.Hour)
+	result = allow(t, limiter, "client")
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 2, result.Remaining)
+}
+
+func testSlidingWindow(t *testing.T, store Store) {
+	clock := newFakeClock()
+	limiter := NewSlidingWindow(store, 4, time.Minute)
+	limiter.now = clock.Now
+
+	for i := 3; i >= 0; i-- {
+		result := allow(t, limiter, "client")
+		assert.True(t, result.Allowed)
+		assert.Equal(t, i, result.Remaining)
+	}
+
+	result := allow(t, limiter, "client")
+	assert.False(t, result.Allowed)
+	assert.Equal(t, time.Minute, result.Reset)
+	assert.Equal(t, time.Minute+15*time.Second, result.RetryAfter)
+
+	// Halfway into the next window, half of the previous count still counts.
+	clock.Advance(90 * time.Second)
+	for i := 0; i < 2; i++ {
+		assert.True(t, allow(t, limiter, "client").Allowed)
+	}
+	result = allow(t, limiter, "client")
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 30*time.Second, result.Reset)
+	assert.Equal(t, 15*time.Second, result.RetryAfter)
+
+	clock.Advance(15 * time.Second)
+	assert.True(t, allow(t, limiter, "client").Allowed)
+
+	// Windows older than the previous one are forgotten.
+	clock.Advance(5 * time.Minute)
+	result = allow(t, limiter, "client")
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 3, result.Remaining)
+}
+
+func TestTokenBucket(t *testing.T) {
+	testTokenBucket(t, NewMemoryStore())
+}
+
+func TestSlidingWindow(t *testing.T) {
+	testSlidingWindow(t, NewMemoryStore())
+}
+
+type failingStore struct{}
+
+func (failingStore) Update(ctx context.Context, key string, ttl time.Duration, fn func(state []byte) ([]byte, error)) error {
+	return errors.New("store unavailable")
+}
+
+// conflictingStore calls fn with stale state before calling it with the
+// stored state, as a store does after a conflicting update.
+type conflictingStore struct {
+	stale []byte
+	store Store
+}
+
+func (s conflictingStore) Update(ctx context.Context, key string, ttl time.Duration, fn func(state []byte) ([]byte, error)) error {
+	if _, err := fn(s.stale); err != nil {
+		return err
+	}
+	return s.store.Update(ctx, key, ttl, fn)
+}
+
+func TestLimiterRetries(t *testing.T) {
+	clock := newFakeClock()
+
+	t.Run("token bucket", func(t *testing.T) {
+		now := strconv.FormatInt(clock.Now().UnixNano(), 10)
+		limiter := NewTokenBucket(conflictingStore{stale: joinState("0", now), store: NewMemoryStore()}, 2, time.Minute, 0)
+		limiter.now = clock.Now
+
+		assert.Equal(t, Result{Allowed: true, Limit: 2, Remaining: 1, Reset: 30 * time.Second, Window: time.Minute}, allow(t, limiter, "client"))
+	})
+
+	t.Run("sliding window", func(t *testing.T) {
+		start := strconv.FormatInt(clock.Now().Truncate(time.Minute).UnixNano(), 10)
+		limiter := NewSlidingWindow(conflictingStore{stale: joinState(start, "2", "0"), store: NewMemoryStore()}, 2, time.Minute)
+		limiter.now = clock.Now
+
+		assert.Equal(t, Result{Allowed: true, Limit: 2, Remaining: 1, Reset: time.Minute, Window: time.Minute}, allow(t, limiter, "client"))
+	})
+}
+
+func TestLimiterErrors(t *testing.T) {
+	t.Run("should reject invalid limits", func(t *testing.T) {
+		assert.PanicsWithValue(t, "ratelimit: limit must be positive, got 0", func() {
+			NewTokenBucket(NewMemoryStore(), 0, time.Second, 1)
+		})
+		assert.PanicsWithValue(t, "ratelimit: period must be positive, got 0s", func() {
+			NewSlidingWindow(NewMemoryStore(), 1, 0)
+		})
+		assert.Panics(t, func() { NewSlidingWindow(NewMemoryStore(), -1, time.Second) })
+		assert.Panics(t, func() { NewTokenBucket(NewMemoryStore(), 1, -time.Second, 0) })
+	})
+
+	t.Run("should return store errors", func(t *testing.T) {
+		_, err := NewTokenBucket(failingStore{}, 1, time.Second, 0).Allow(context.Background(), "client")
+		assert.EqualError(t, err, "store unavailable")
+	})
+
+	t.Run("should reject corrupt state", func(t *testing.T) {
+		store := NewMemoryStore()
+		store.Update(context.Background(), "sw:client", time.Minute, func(state []byte) ([]byte, error) {
+			return []byte("garbage"), nil
+		})
+
+		_, err := NewSlidingWindow(store, 1, time.Second).Allow(context.Background(), "client")
+		assert.ErrorIs(t, err, errInvalidState)
+	})
+}
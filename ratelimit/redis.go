@@ -0,0 +1,335 @@
+package ratelimit
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultRedisPoolSize    = 10
+	defaultRedisDialTimeout = 5 * time.Second
+	defaultRedisIOTimeout   = 3 * time.Second
+	defaultRedisKeyPrefix   = "ratelimit:"
+	maxRedisUpdateAttempts  = 10
+)
+
+// ErrConflict is returned by RedisStore.Update when the state of a key kept
+// changing while it was being updated.
+var ErrConflict = errors.New("ratelimit: too many concurrent updates")
+
+// RedisConfig configures a RedisStore.
+type RedisConfig struct {
+	Addr     string
+	Password string
+	DB       int
+	// KeyPrefix is prepended to every key. It defaults to "ratelimit:".
+	KeyPrefix string
+	// PoolSize is the number of idle connections kept open. It defaults to 10.
+	PoolSize int
+	// DialTimeout defaults to 5 seconds.
+	DialTimeout time.Duration
+	// ReadTimeout and WriteTimeout bound each command. They default to 3
+	// seconds. The deadline of the context applies if it is earlier.
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+}
+
+// RedisStore keeps state in Redis, so that all instances of a service share
+// the same limits. Updates use optimistic locking with WATCH, MULTI and EXEC.
+type RedisStore struct {
+	config RedisConfig
+	idle   chan *redisConn
+}
+
+func NewRedisStore(config RedisConfig) *RedisStore {
+	if config.KeyPrefix == "" {
+		config.KeyPrefix = defaultRedisKeyPrefix
+	}
+	if config.PoolSize <= 0 {
+		config.PoolSize = defaultRedisPoolSize
+	}
+	if config.DialTimeout <= 0 {
+		config.DialTimeout = defaultRedisDialTimeout
+	}
+	if config.ReadTimeout <= 0 {
+		config.ReadTimeout = defaultRedisIOTimeout
+	}
+	if config.WriteTimeout <= 0 {
+		config.WriteTimeout = defaultRedisIOTimeout
+	}
+	return &RedisStore{config: config, idle: make(chan *redisConn, config.PoolSize)}
+}
+
+func (s *RedisStore) Update(ctx context.Context, key string, ttl time.Duration, fn func(state []byte) ([]byte, error)) error {
+	conn, err := s.get(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = s.update(ctx, conn, s.config.KeyPrefix+key, ttl, fn)
+	s.put(conn)
+	return err
+}
+
+func (s *RedisStore) update(ctx context.Context, conn *redisConn, key string, ttl time.Duration, fn func(state []byte) ([]byte, error)) error {
+	ttlMillis := strconv.FormatInt(max(1, ttl.Milliseconds()), 10)
+
+	// unwatch clears the watch before an early return, so that the connection
+	// can go back to the pool.
+	unwatch := func(err error) error {
+		if !conn.broken {
+			conn.do("UNWATCH")
+		}
+		return err
+	}
+
+	for attempt := 0; attempt < maxRedisUpdateAttempts; attempt++ {
+		if _, err := conn.do("WATCH", key); err != nil {
+			return unwatch(err)
+		}
+		reply, err := conn.do("GET", key)
+		if err != nil {
+			return unwatch(err)
+		}
+		state, _ := reply.([]byte)
+
+		newState, err := fn(state)
+		if err != nil {
+			return unwatch(err)
+		}
+
+		if _, err := conn.do("MULTI"); err != nil {
+			return unwatch(err)
+		}
+		if _, err := conn.do("SET", key, string(newState), "PX", ttlMillis); err != nil {
+			conn.do("DISCARD")
+			return err
+		}
+		reply, err = conn.do("EXEC")
+		if err != nil {
+			return err
+		}
+		if items, ok := reply.([]any); ok {
+			for _, item := range items {
+				if err, ok := item.(redisError); ok {
+					return err
+				}
+			}
+			return nil
+		}
+		if reply != nil {
+			return nil
+		}
+		// The key changed after WATCH, so the transaction was aborted. Back
+		// off for a random moment so that competing updates spread out.
+		timer := time.NewTimer(time.Duration(rand.Int64N(int64(time.Millisecond) << attempt)))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+	return ErrConflict
+}
+
+// Close closes the idle connections.
+func (s *RedisStore) Close() error {
+	for {
+		select {
+		case conn := <-s.idle:
+			conn.Close()
+		default:
+			return nil
+		}
+	}
+}
+
+func (s *RedisStore) get(ctx context.Context) (*redisConn, error) {
+	var conn *redisConn
+	select {
+	case conn = <-s.idle:
+	default:
+		var err error
+		if conn, err = s.dial(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	conn.ctxDeadline, _ = ctx.Deadline()
+	return conn, nil
+}
+
+func (s *RedisStore) put(conn *redisConn) {
+	if conn.broken {
+		conn.Close()
+		return
+	}
+
+	select {
+	case s.idle <- conn:
+	default:
+		conn.Close()
+	}
+}
+
+func (s *RedisStore) dial(ctx context.Context) (*redisConn, error) {
+	dialer := net.Dialer{Timeout: s.config.DialTimeout}
+	netConn, err := dialer.DialContext(ctx, "tcp", s.config.Addr)
+	if err != nil {
+		return nil, err
+	}
+	conn := &redisConn{
+		conn:         netConn,
+		r:            bufio.NewReader(netConn),
+		readTimeout:  s.config.ReadTimeout,
+		writeTimeout: s.config.WriteTimeout,
+	}
+	conn.ctxDeadline, _ = ctx.Deadline()
+
+	if s.config.Password != "" {
+		if _, err := conn.do("AUTH", s.config.Password); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	if s.config.DB != 0 {
+		if _, err := conn.do("SELECT", strconv.Itoa(s.config.DB)); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+// redisError is an error reply from Redis.
+type redisError string
+
+func (e redisError) Error() string {
+	return "redis: " + string(e)
+}
+
+// redisConn speaks the Redis serialization protocol (RESP).
+type redisConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+	// broken is set when a command fails other than with an error reply,
+	// which leaves the connection in an unknown state.
+	broken bool
+
+	readTimeout  time.Duration
+	writeTimeout time.Duration
+	// ctxDeadline is the deadline of the context of the current update, or
+	// zero if it has none.
+	ctxDeadline time.Time
+}
+
+func (c *redisConn) Close() error {
+	return c.conn.Close()
+}
+
+// do sends a command and returns its reply: a string for simple strings, an
+// int64 for integers, []byte for bulk strings, []any for arrays and nil for
+// null replies. Error replies are returned as redisError.
+func (c *redisConn) do(args ...string) (any, error) {
+	reply, err := c.roundTrip(args)
+	var redisErr redisError
+	if err != nil && !errors.As(err, &redisErr) {
+		c.broken = true
+	}
+	return reply, err
+}
+
+func (c *redisConn) roundTrip(args []string) (any, error) {
+	buf := []byte("*" + strconv.Itoa(len(args)) + "\r\n")
+	for _, arg := range args {
+		buf = append(buf, "$"+strconv.Itoa(len(arg))+"\r\n"...)
+		buf = append(buf, arg...)
+		buf = append(buf, "\r\n"...)
+	}
+	if err := c.conn.SetWriteDeadline(c.deadline(c.writeTimeout)); err != nil {
+		return nil, err
+	}
+	if _, err := c.conn.Write(buf); err != nil {
+		return nil, err
+	}
+	if err := c.conn.SetReadDeadline(c.deadline(c.readTimeout)); err != nil {
+		return nil, err
+	}
+	return readReply(c.r)
+}
+
+// deadline returns the earlier of timeout from now and the deadline of the
+// context.
+func (c *redisConn) deadline(timeout time.Duration) time.Time {
+	deadline := time.Now().Add(timeout)
+	if !c.ctxDeadline.IsZero() && c.ctxDeadline.Before(deadline) {
+		return c.ctxDeadline
+	}
+	return deadline
+}
+
+func readReply(r *bufio.Reader) (any, error) {
+	line, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(line) == 0 {
+		return nil, errors.New("redis: empty reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		data := make([]byte, n+2)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+		return data[:n], nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		items := make([]any, n)
+		for i := range items {
+			if items[i], err = readReply(r); err != nil {
+				var redisErr redisError
+				if !errors.As(err, &redisErr) {
+					return nil, err
+				}
+				items[i] = redisErr
+			}
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("redis: unexpected reply %q", line)
+	}
+}
+
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	if len(line) < 2 || line[len(line)-2] != '\r' {
+		return "", fmt.Errorf("redis: malformed line %q", line)
+	}
+	return line[:len(line)-2], nil
+}
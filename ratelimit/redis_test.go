@@ -0,0 +1,405 @@
+package ratelimit
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRedis is a minimal in-process Redis server supporting the commands
+// used by RedisStore.
+type fakeRedis struct {
+	listener net.Listener
+	password string
+
+	mu       sync.Mutex
+	values   map[string]string
+	versions map[string]int
+	ttls     map[string]string
+	dbs      []string
+	commands []string
+	// conflicts is the number of EXEC calls that are made to fail as if the
+	// watched key had changed.
+	conflicts int
+	// failures maps command names to the error replies they are answered
+	// with.
+	failures map[string]string
+}
+
+func newFakeRedis(t *testing.T, password string) *fakeRedis {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	f := &fakeRedis{
+		listener: listener,
+		password: password,
+		values:   make(map[string]string),
+		versions: make(map[string]int),
+		ttls:     make(map[string]string),
+		failures: make(map[string]string),
+	}
+	go f.serve()
+	t.Cleanup(func() { listener.Close() })
+	return f
+}
+
+func (f *fakeRedis) Addr() string {
+	return f.listener.Addr().String()
+}
+
+func (f *fakeRedis) serve() {
+	for {
+		conn, err := f.listener.Accept()
+		if err != nil {
+			return
+		}
+		go f.handle(conn)
+	}
+}
+
+type fakeSession struct {
+	authed  bool
+	watched map[string]int
+	queued  [][]string
+	inMulti bool
+}
+
+func (f *fakeRedis) handle(conn net.Conn) {
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	s := &fakeSession{authed: f.password == ""}
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+		if _, err := io.WriteString(conn, f.exec(s, args)); err != nil {
+			return
+		}
+	}
+}
+
+func readCommand(r *bufio.Reader) ([]string, error) {
+	reply, err := readReply(r)
+	if err != nil {
+		return nil, err
+	}
+	items, ok := reply.([]any)
+	if !ok {
+		return nil, errors.New("expected an array")
+	}
+	args := make([]string, len(items))
+	for i, item := range items {
+		args[i] = string(item.([]byte))
+	}
+	return args, nil
+}
+
+func (f *fakeRedis) exec(s *fakeSession, args []string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	name := strings.ToUpper(args[0])
+	f.commands = append(f.commands, name)
+
+	if name == "AUTH" {
+		if args[1] != f.password {
+			return "-WRONGPASS invalid password\r\n"
+		}
+		s.authed = true
+		return "+OK\r\n"
+	}
+	if !s.authed {
+		return "-NOAUTH Authentication required.\r\n"
+	}
+
+	if s.inMulti && name != "EXEC" && name != "DISCARD" {
+		s.queued = append(s.queued, args)
+		return "+QUEUED\r\n"
+	}
+
+	switch name {
+	case "SELECT":
+		f.dbs = append(f.dbs, args[1])
+		return "+OK\r\n"
+	case "WATCH":
+		if s.watched == nil {
+			s.watched = make(map[string]int)
+		}
+		for _, key := range args[1:] {
+			s.watched[key] = f.versions[key]
+		}
+		return "+OK\r\n"
+	case "UNWATCH":
+		s.watched = nil
+		return "+OK\r\n"
+	case "MULTI":
+		s.inMulti = true
+		return "+OK\r\n"
+	case "DISCARD":
+		s.inMulti, s.queued, s.watched = false, nil, nil
+		return "+OK\r\n"
+	case "EXEC":
+		queued, watched := s.queued, s.watched
+		s.inMulti, s.queued, s.watched = false, nil, nil
+
+		conflict := f.conflicts > 0
+		for key, version := range watched {
+			conflict = conflict || f.versions[key] != version
+		}
+		if conflict {
+			if f.conflicts > 0 {
+				f.conflicts--
+			}
+			return "*-1\r\n"
+		}
+
+		reply := "*" + strconv.Itoa(len(queued)) + "\r\n"
+		for _, cmd := range queued {
+			reply += f.run(cmd)
+		}
+		return reply
+	default:
+		return f.run(args)
+	}
+}
+
+func (f *fakeRedis) run(args []string) string {
+	if failure, ok := f.failures[strings.ToUpper(args[0])]; ok {
+		return "-" + failure + "\r\n"
+	}
+
+	switch strings.ToUpper(args[0]) {
+	case "PING":
+		return "+PONG\r\n"
+	case "GET":
+		value, ok := f.values[args[1]]
+		if !ok {
+			return "$-1\r\n"
+		}
+		return "$" + strconv.Itoa(len(value)) + "\r\n" + value + "\r\n"
+	case "SET":
+		f.values[args[1]] = args[2]
+		f.versions[args[1]]++
+		if len(args) == 5 && strings.ToUpper(args[3]) == "PX" {
+			f.ttls[args[1]] = args[4]
+		}
+		return "+OK\r\n"
+	case "DEL":
+		var n int
+		for _, key := range args[1:] {
+			if _, ok := f.values[key]; ok {
+				delete(f.values, key)
+				f.versions[key]++
+				n++
+			}
+		}
+		return ":" + strconv.Itoa(n) + "\r\n"
+	default:
+		return "-ERR unknown command '" + args[0] + "'\r\n"
+	}
+}
+
+func TestRedisStore(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("should run the limiters", func(t *testing.T) {
+		store := NewRedisStore(RedisConfig{Addr: newFakeRedis(t, "").Addr()})
+		defer store.Close()
+
+		t.Run("token bucket", func(t *testing.T) { testTokenBucket(t, store) })
+		t.Run("sliding window", func(t *testing.T) { testSlidingWindow(t, store) })
+	})
+
+	t.Run("should prefix keys and set a ttl", func(t *testing.T) {
+		server := newFakeRedis(t, "")
+		store := NewRedisStore(RedisConfig{Addr: server.Addr(), KeyPrefix: "app:"})
+		defer store.Close()
+
+		_, err := NewSlidingWindow(store, 10, time.Minute).Allow(ctx, "client")
+		require.NoError(t, err)
+
+		server.mu.Lock()
+		defer server.mu.Unlock()
+		assert.Contains(t, server.values, "app:sw:client")
+		assert.Equal(t, "120000", server.ttls["app:sw:client"])
+	})
+
+	t.Run("should authenticate and select the database", func(t *testing.T) {
+		server := newFakeRedis(t, "secret")
+		store := NewRedisStore(RedisConfig{Addr: server.Addr(), Password: "secret", DB: 2})
+		defer store.Close()
+
+		_, err := NewTokenBucket(store, 10, time.Second, 0).Allow(ctx, "client")
+		require.NoError(t, err)
+
+		server.mu.Lock()
+		defer server.mu.Unlock()
+		assert.Equal(t, []string{"AUTH", "SELECT"}, server.commands[:2])
+		assert.Equal(t, []string{"2"}, server.dbs)
+	})
+
+	t.Run("should fail with a wrong password", func(t *testing.T) {
+		store := NewRedisStore(RedisConfig{Addr: newFakeRedis(t, "secret").Addr(), Password: "wrong"})
+		defer store.Close()
+
+		_, err := NewTokenBucket(store, 10, time.Second, 0).Allow(ctx, "client")
+		assert.EqualError(t, err, "redis: WRONGPASS invalid password")
+	})
+
+	t.Run("should retry aborted transactions", func(t *testing.T) {
+		server := newFakeRedis(t, "")
+		server.conflicts = 2
+		store := NewRedisStore(RedisConfig{Addr: server.Addr()})
+		defer store.Close()
+
+		var calls int
+		err := store.Update(ctx, "key", time.Second, func(state []byte) ([]byte, error) {
+			calls++
+			return []byte("1"), nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, 3, calls)
+	})
+
+	t.Run("should give up after too many conflicts", func(t *testing.T) {
+		server := newFakeRedis(t, "")
+		server.conflicts = maxRedisUpdateAttempts
+		store := NewRedisStore(RedisConfig{Addr: server.Addr()})
+		defer store.Close()
+
+		err := store.Update(ctx, "key", time.Second, func(state []byte) ([]byte, error) {
+			return []byte("1"), nil
+		})
+		assert.ErrorIs(t, err, ErrConflict)
+	})
+
+	t.Run("should stop retrying when the context is done", func(t *testing.T) {
+		server := newFakeRedis(t, "")
+		server.conflicts = maxRedisUpdateAttempts
+		store := NewRedisStore(RedisConfig{Addr: server.Addr()})
+		defer store.Close()
+
+		ctx, cancel := context.WithCancel(ctx)
+		var calls int
+		err := store.Update(ctx, "key", time.Second, func(state []byte) ([]byte, error) {
+			calls++
+			cancel()
+			return []byte("1"), nil
+		})
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("should unwatch and reuse the connection when fn fails", func(t *testing.T) {
+		server := newFakeRedis(t, "")
+		store := NewRedisStore(RedisConfig{Addr: server.Addr()})
+		defer store.Close()
+
+		err := store.Update(ctx, "key", time.Second, func(state []byte) ([]byte, error) {
+			return nil, errors.New("boom")
+		})
+		assert.EqualError(t, err, "boom")
+		assert.Len(t, store.idle, 1)
+
+		server.mu.Lock()
+		defer server.mu.Unlock()
+		assert.Equal(t, []string{"WATCH", "GET", "UNWATCH"}, server.commands)
+	})
+
+	t.Run("should unwatch and reuse the connection when GET fails", func(t *testing.T) {
+		server := newFakeRedis(t, "")
+		server.failures["GET"] = "WRONGTYPE Operation against a key holding the wrong kind of value"
+		store := NewRedisStore(RedisConfig{Addr: server.Addr()})
+		defer store.Close()
+
+		err := store.Update(ctx, "key", time.Second, func(state []byte) ([]byte, error) {
+			return state, nil
+		})
+		assert.EqualError(t, err, "redis: WRONGTYPE Operation against a key holding the wrong kind of value")
+		assert.Len(t, store.idle, 1)
+
+		server.mu.Lock()
+		defer server.mu.Unlock()
+		assert.Equal(t, []string{"WATCH", "GET", "UNWATCH"}, server.commands)
+	})
+
+	t.Run("should fail when a queued command fails", func(t *testing.T) {
+		server := newFakeRedis(t, "")
+		server.failures["SET"] = "OOM command not allowed when used memory > 'maxmemory'"
+		store := NewRedisStore(RedisConfig{Addr: server.Addr()})
+		defer store.Close()
+
+		err := store.Update(ctx, "key", time.Second, func(state []byte) ([]byte, error) {
+			return []byte("state"), nil
+		})
+		assert.EqualError(t, err, "redis: OOM command not allowed when used memory > 'maxmemory'")
+	})
+
+	t.Run("should count concurrent requests exactly once", func(t *testing.T) {
+		store := NewRedisStore(RedisConfig{Addr: newFakeRedis(t, "").Addr(), PoolSize: 4})
+		defer store.Close()
+		limiter := NewSlidingWindow(store, 1000, time.Hour)
+
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := limiter.Allow(ctx, "client")
+				assert.NoError(t, err)
+			}()
+		}
+		wg.Wait()
+
+		result := allow(t, limiter, "client")
+		assert.Equal(t, 1000-11, result.Remaining)
+	})
+
+	t.Run("should fail when redis is unreachable", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		addr := listener.Addr().String()
+		listener.Close()
+
+		store := NewRedisStore(RedisConfig{Addr: addr, DialTimeout: time.Second})
+		_, err = NewTokenBucket(store, 1, time.Second, 0).Allow(ctx, "client")
+		assert.Error(t, err)
+	})
+
+	t.Run("should time out when redis does not answer", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer listener.Close()
+		go func() {
+			conn, err := listener.Accept()
+			if err == nil {
+				defer conn.Close()
+				io.Copy(io.Discard, conn)
+			}
+		}()
+
+		store := NewRedisStore(RedisConfig{Addr: listener.Addr().String(), ReadTimeout: 50 * time.Millisecond})
+		defer store.Close()
+
+		start := time.Now()
+		_, err = NewTokenBucket(store, 1, time.Second, 0).Allow(ctx, "client")
+		var netErr net.Error
+		require.ErrorAs(t, err, &netErr)
+		assert.True(t, netErr.Timeout())
+		assert.Less(t, time.Since(start), time.Second)
+	})
+}
@@ -0,0 +1,78 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Store keeps the rate limit state of each key.
+type Store interface {
+	// Update atomically replaces the state of key with the state returned by
+	// fn, which receives the current state or nil if there is none. The new
+	// state expires after ttl. fn may be called more than once if the state
+	// changes concurrently.
+	Update(ctx context.Context, key string, ttl time.Duration, fn func(state []byte) ([]byte, error)) error
+}
+
+const sweepInterval = time.Minute
+
+// MemoryStore keeps state in memory, which limits each instance of a service
+// separately. It is safe for concurrent use.
+type MemoryStore struct {
+	mu        sync.Mutex
+	entries   map[string]memoryEntry
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type memoryEntry struct {
+	state   []byte
+	expires time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: make(map[string]memoryEntry), now: time.Now}
+}
+
+func (s *MemoryStore) Update(ctx context.Context, key string, ttl time.Duration, fn func(state []byte) ([]byte, error)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	var state []byte
+	if entry, ok := s.entries[key]; ok && now.Before(entry.expires) {
+		state = entry.state
+	}
+
+	state, err := fn(state)
+	if err != nil {
+		return err
+	}
+	s.entries[key] = memoryEntry{state: state, expires: now.Add(ttl)}
+	return nil
+}
+
+// Len returns the number of keys with state, including expired keys that
+// have not been removed yet.
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.entries)
+}
+
+// sweep removes expired entries at most once per sweepInterval.
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+
+	for key, entry := range s.entries {
+		if !now.Before(entry.expires) {
+			delete(s.entries, key)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("should expire and sweep state", func(t *testing.T) {
+		clock := newFakeClock()
+		store := NewMemoryStore()
+		store.now = clock.Now
+
+		set := func(key, value string) {
+			store.Update(ctx, key, time.Second, func(state []byte) ([]byte, error) {
+				return []byte(value), nil
+			})
+		}
+		set("a", "1")
+		set("b", "1")
+
+		clock.Advance(time.Second)
+		var seen []byte
+		store.Update(ctx, "a", time.Second, func(state []byte) ([]byte, error) {
+			seen = state
+			return []byte("2"), nil
+		})
+		assert.Nil(t, seen)
+		assert.Equal(t, 2, store.Len())
+
+		clock.Advance(time.Minute)
+		set("c", "1")
+		assert.Equal(t, 1, store.Len())
+	})
+
+	t.Run("should keep state when fn fails", func(t *testing.T) {
+		store := NewMemoryStore()
+		store.Update(ctx, "a", time.Minute, func(state []byte) ([]byte, error) {
+			return []byte("1"), nil
+		})
+
+		err := store.Update(ctx, "a", time.Minute, func(state []byte) ([]byte, error) {
+			return nil, errors.New("boom")
+		})
+		assert.EqualError(t, err, "boom")
+
+		store.Update(ctx, "a", time.Minute, func(state []byte) ([]byte, error) {
+			assert.Equal(t, "1", string(state))
+			return state, nil
+		})
+	})
+
+	t.Run("should serialize concurrent updates", func(t *testing.T) {
+		store := NewMemoryStore()
+		limiter := NewSlidingWindow(store, 1000, time.Hour)
+
+		var wg sync.WaitGroup
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				limiter.Allow(ctx, "client")
+			}()
+		}
+		wg.Wait()
+
+		result := allow(t, limiter, "client")
+		assert.Equal(t, 1000-51, result.Remaining)
+	})
+}
@@ -0,0 +1,92 @@
+package tracing
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+)
+
+// WriterExporter writes each span as a line of JSON, e.g. to stdout or a
+// file that a collector can pick up later.
+type WriterExporter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewWriterExporter(w io.Writer) *WriterExporter {
+	return &WriterExporter{w: w}
+}
+
+// NewStdoutExporter returns an exporter writing spans to standard output.
+func NewStdoutExporter() *WriterExporter {
+	return NewWriterExporter(os.Stdout)
+}
+
+// NewFileExporter returns an exporter appending spans to the file at path,
+// creating it if needed. Close closes the file.
+func NewFileExporter(path string) (*WriterExporter, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return NewWriterExporter(file), nil
+}
+
+func (e *WriterExporter) ExportSpan(span SpanData) error {
+	line, err := json.Marshal(span)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	_, err = e.w.Write(line)
+	return err
+}
+
+// Close closes the underlying writer if it is an io.Closer other than
+// standard output or standard error.
+func (e *WriterExporter) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.w == os.Stdout || e.w == os.Stderr {
+		return nil
+	}
+	if closer, ok := e.w.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// InMemoryExporter keeps exported spans in memory, which is useful in tests.
+type InMemoryExporter struct {
+	mu    sync.Mutex
+	spans []SpanData
+}
+
+func NewInMemoryExporter() *InMemoryExporter {
+	return &InMemoryExporter{}
+}
+
+func (e *InMemoryExporter) ExportSpan(span SpanData) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = append(e.spans, span)
+	return nil
+}
+
+// Spans returns the exported spans in the order they ended.
+func (e *InMemoryExporter) Spans() []SpanData {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]SpanData(nil), e.spans...)
+}
+
+func (e *InMemoryExporter) Reset() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = nil
+}
@@ -0,0 +1,57 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriterExporter(t *testing.T) {
+	t.Run("should write spans as JSON lines", func(t *testing.T) {
+		var buf bytes.Buffer
+		tracer := NewTracer(NewWriterExporter(&buf))
+
+		_, span := tracer.Start(context.Background(), "work", WithAttributes(map[string]any{"job": "emails"}))
+		span.SetStatus(StatusOK, "")
+		span.End()
+
+		var data map[string]any
+		assert.NoError(t, json.Unmarshal(buf.Bytes(), &data))
+		assert.Equal(t, "work", data["name"])
+		assert.Equal(t, "internal", data["kind"])
+		assert.Equal(t, span.SpanContext().TraceID.String(), data["traceId"])
+		assert.Equal(t, map[string]any{"job": "emails"}, data["attributes"])
+		assert.Equal(t, map[string]any{"code": "ok"}, data["status"])
+		assert.True(t, strings.HasSuffix(buf.String(), "}\n"))
+	})
+
+	t.Run("should append spans to a file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "spans.jsonl")
+		exporter, err := NewFileExporter(path)
+		assert.NoError(t, err)
+		tracer := NewTracer(exporter)
+
+		for _, name := range []string{"first", "second"} {
+			_, span := tracer.Start(context.Background(), name)
+			span.End()
+		}
+		assert.NoError(t, exporter.Close())
+
+		content, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+		assert.Len(t, lines, 2)
+		assert.Contains(t, lines[1], `"name":"second"`)
+	})
+
+	t.Run("should fail for an unwritable path", func(t *testing.T) {
+		_, err := NewFileExporter(filepath.Join(t.TempDir(), "missing", "spans.jsonl"))
+		assert.Error(t, err)
+	})
+}
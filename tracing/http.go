@@ -0,0 +1,49 @@
+package tracing
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// Transport is an http.RoundTripper that records a client span for each
+// request and propagates it to the server in the traceparent header.
+type Transport struct {
+	tracer *Tracer
+	base   http.RoundTripper
+}
+
+// NewTransport wraps base, or http.DefaultTransport if base is nil.
+func NewTransport(tracer *Tracer, base http.RoundTripper) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &Transport{tracer: tracer, base: base}
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, span := t.tracer.Start(req.Context(), req.Method,
+		WithSpanKind(SpanKindClient),
+		WithAttributes(map[string]any{
+			"http.request.method": req.Method,
+			"url.full":            req.URL.Redacted(),
+			"server.address":      req.URL.Hostname(),
+		}),
+	)
+	defer span.End()
+
+	// RoundTrippers must not modify the caller's request.
+	req = req.Clone(ctx)
+	Inject(ctx, req.Header)
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	span.SetAttribute("http.response.status_code", resp.StatusCode)
+	if resp.StatusCode >= http.StatusBadRequest {
+		span.SetStatus(StatusError, strconv.Itoa(resp.StatusCode)+" "+http.StatusText(resp.StatusCode))
+	}
+	return resp, nil
+}
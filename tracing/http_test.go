@@ -0,0 +1,41 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransport(t *testing.T) {
+	var received string
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Get(TraceparentHeader)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer backend.Close()
+
+	tracer, exporter := newTestTracer()
+	client := &http.Client{Transport: NewTransport(tracer, nil)}
+
+	ctx, parent := tracer.Start(context.Background(), "handle")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, backend.URL+"/stock", nil)
+	assert.NoError(t, err)
+	resp, err := client.Do(req)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	parent.End()
+
+	spans := exporter.Spans()
+	assert.Len(t, spans, 2)
+	clientSpan := spans[0]
+	assert.Equal(t, "GET", clientSpan.Name)
+	assert.Equal(t, SpanKindClient, clientSpan.Kind)
+	assert.Equal(t, parent.SpanContext().SpanID.String(), clientSpan.ParentSpanID)
+	assert.Equal(t, "00-"+clientSpan.TraceID+"-"+clientSpan.SpanID+"-01", received)
+	assert.Equal(t, http.StatusServiceUnavailable, clientSpan.Attributes["http.response.status_code"])
+	assert.Equal(t, StatusError, clientSpan.Status.Code)
+	assert.Empty(t, req.Header.Get(TraceparentHeader))
+}
@@ -0,0 +1,123 @@
+package tracing
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// TraceparentHeader is the W3C Trace Context header carrying the span context.
+const TraceparentHeader = "traceparent"
+
+const (
+	traceparentLength = 55
+	flagSampled       = 0x01
+)
+
+var ErrInvalidTraceparent = errors.New("invalid traceparent")
+
+type TraceID [16]byte
+
+func (id TraceID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+func (id TraceID) IsValid() bool {
+	return id != TraceID{}
+}
+
+type SpanID [8]byte
+
+func (id SpanID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+func (id SpanID) IsValid() bool {
+	return id != SpanID{}
+}
+
+// SpanContext identifies a span across process boundaries.
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Sampled bool
+}
+
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+// Traceparent formats sc as a version 00 traceparent header value.
+func (sc SpanContext) Traceparent() string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return "00-" + sc.TraceID.String() + "-" + sc.SpanID.String() + "-" + flags
+}
+
+// ParseTraceparent parses a traceparent header value. Values of future
+// versions are accepted as long as they start with the version 00 fields.
+func ParseTraceparent(value string) (SpanContext, error) {
+	var sc SpanContext
+	if len(value) < traceparentLength ||
+		value[2] != '-' || value[35] != '-' || value[52] != '-' {
+		return sc, ErrInvalidTraceparent
+	}
+
+	version, ok := decodeHex(value[0:2])
+	if !ok || version[0] == 0xff ||
+		(version[0] == 0 && len(value) != traceparentLength) ||
+		(len(value) > traceparentLength && value[traceparentLength] != '-') {
+		return sc, ErrInvalidTraceparent
+	}
+
+	traceID, ok := decodeHex(value[3:35])
+	if !ok {
+		return sc, ErrInvalidTraceparent
+	}
+	spanID, ok := decodeHex(value[36:52])
+	if !ok {
+		return sc, ErrInvalidTraceparent
+	}
+	flags, ok := decodeHex(value[53:55])
+	if !ok {
+		return sc, ErrInvalidTraceparent
+	}
+
+	copy(sc.TraceID[:], traceID)
+	copy(sc.SpanID[:], spanID)
+	sc.Sampled = flags[0]&flagSampled != 0
+	if !sc.IsValid() {
+		return SpanContext{}, ErrInvalidTraceparent
+	}
+	return sc, nil
+}
+
+// decodeHex decodes lowercase hex, as required by the traceparent format.
+func decodeHex(s string) ([]byte, bool) {
+	if strings.ToLower(s) != s {
+		return nil, false
+	}
+	b, err := hex.DecodeString(s)
+	return b, err == nil
+}
+
+// Extract returns a copy of ctx carrying the span context of the traceparent
+// header in header, if it is valid.
+func Extract(ctx context.Context, header http.Header) context.Context {
+	sc, err := ParseTraceparent(header.Get(TraceparentHeader))
+	if err != nil {
+		return ctx
+	}
+	return ContextWithRemoteSpanContext(ctx, sc)
+}
+
+// Inject sets the traceparent header to the span context in ctx, if any.
+func Inject(ctx context.Context, header http.Header) {
+	if sc := SpanContextFromContext(ctx); sc.IsValid() {
+		header.Set(TraceparentHeader, sc.Traceparent())
+	}
+}
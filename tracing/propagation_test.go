@@ -0,0 +1,76 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	t.Run("should parse a valid header", func(t *testing.T) {
+		sc, err := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", sc.TraceID.String())
+		assert.Equal(t, "00f067aa0ba902b7", sc.SpanID.String())
+		assert.True(t, sc.Sampled)
+		assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", sc.Traceparent())
+	})
+
+	t.Run("should accept future versions", func(t *testing.T) {
+		sc, err := ParseTraceparent("cc-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-03-extra")
+
+		assert.NoError(t, err)
+		assert.True(t, sc.Sampled)
+	})
+
+	invalid := map[string]string{
+		"empty":                "",
+		"too short":            "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-0",
+		"trailing data in v00": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"forbidden version":    "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"uppercase hex":        "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"zero trace ID":        "00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"zero span ID":         "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"bad separator":        "00_4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"not hex":              "00-4bf92f3577b34da6a3ce929d0e0e47zz-00f067aa0ba902b7-01",
+	}
+	for name, value := range invalid {
+		t.Run("should reject "+name, func(t *testing.T) {
+			_, err := ParseTraceparent(value)
+			assert.ErrorIs(t, err, ErrInvalidTraceparent)
+		})
+	}
+}
+
+func TestPropagation(t *testing.T) {
+	t.Run("should inject the current span", func(t *testing.T) {
+		tracer, _ := newTestTracer()
+		ctx, span := tracer.Start(context.Background(), "work")
+
+		header := http.Header{}
+		Inject(ctx, header)
+
+		assert.Equal(t, span.SpanContext().Traceparent(), header.Get(TraceparentHeader))
+	})
+
+	t.Run("should extract a remote span context", func(t *testing.T) {
+		header := http.Header{}
+		header.Set(TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+		ctx := Extract(context.Background(), header)
+
+		assert.Equal(t, "00f067aa0ba902b7", SpanContextFromContext(ctx).SpanID.String())
+	})
+
+	t.Run("should ignore a missing or invalid header", func(t *testing.T) {
+		header := http.Header{}
+		header.Set(TraceparentHeader, "garbage")
+
+		assert.False(t, SpanContextFromContext(Extract(context.Background(), header)).IsValid())
+		Inject(context.Background(), header)
+		assert.Equal(t, "garbage", header.Get(TraceparentHeader))
+	})
+}
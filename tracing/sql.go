@@ -0,0 +1,239 @@
+package tracing
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"strings"
+)
+
+// WrapSQLOpen wraps an sql.Open-like function, such as the one passed to
+// db.InitDB, so that the returned *sql.DB records a client span for every
+// statement it runs. Spans are children of the span in the context passed to
+// methods like QueryContext and ExecContext; methods without a context, like
+// Query, start a new trace.
+func (t *Tracer) WrapSQLOpen(sqlOpen func(driverName, dataSourceName string) (*sql.DB, error)) func(driverName, dataSourceName string) (*sql.DB, error) {
+	return func(driverName, dataSourceName string) (*sql.DB, error) {
+		probe, err := sqlOpen(driverName, dataSourceName)
+		if err != nil {
+			return nil, err
+		}
+		d := probe.Driver()
+		if err := probe.Close(); err != nil {
+			return nil, err
+		}
+
+		c := &tracedConnector{tracer: t, driver: d, dsn: dataSourceName, system: driverName}
+		if dc, ok := d.(driver.DriverContext); ok {
+			if c.connector, err = dc.OpenConnector(dataSourceName); err != nil {
+				return nil, err
+			}
+		}
+		return sql.OpenDB(c), nil
+	}
+}
+
+type tracedConnector struct {
+	tracer    *Tracer
+	driver    driver.Driver
+	connector driver.Connector
+	dsn       string
+	system    string
+}
+
+func (c *tracedConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	var conn driver.Conn
+	var err error
+	if c.connector != nil {
+		conn, err = c.connector.Connect(ctx)
+	} else {
+		conn, err = c.driver.Open(c.dsn)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &tracedConn{Conn: conn, connector: c}, nil
+}
+
+func (c *tracedConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// startSpan starts a client span for query, named after its operation, e.g.
+// "SELECT".
+func (c *tracedConnector) startSpan(ctx context.Context, query string) (context.Context, *Span) {
+	return c.tracer.Start(ctx, sqlOperation(query),
+		WithSpanKind(SpanKindClient),
+		WithAttributes(map[string]any{
+			"db.system":    c.system,
+			"db.statement": query,
+		}),
+	)
+}
+
+func endSQLSpan(span *Span, err error) {
+	if errors.Is(err, driver.ErrSkip) {
+		// database/sql retries the statement another way, which is traced.
+		span.discard()
+		return
+	}
+	if err != nil && !errors.Is(err, driver.ErrBadConn) {
+		span.RecordError(err)
+	}
+	span.End()
+}
+
+func sqlOperation(query string) string {
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return "SQL"
+	}
+	return strings.ToUpper(fields[0])
+}
+
+// tracedConn implements the optional driver interfaces by delegating to the
+// wrapped connection, falling back the way database/sql would when the
+// wrapped connection does not implement them.
+type tracedConn struct {
+	driver.Conn
+	connector *tracedConnector
+}
+
+func (c *tracedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+
+	ctx, span := c.connector.startSpan(ctx, query)
+	rows, err := queryer.QueryContext(ctx, query, args)
+	endSQLSpan(span, err)
+	return rows, err
+}
+
+func (c *tracedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+
+	ctx, span := c.connector.startSpan(ctx, query)
+	result, err := execer.ExecContext(ctx, query, args)
+	endSQLSpan(span, err)
+	return result, err
+}
+
+func (c *tracedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	var stmt driver.Stmt
+	var err error
+	if preparer, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		stmt, err = preparer.PrepareContext(ctx, query)
+	} else {
+		stmt, err = c.Conn.Prepare(query)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &tracedStmt{Stmt: stmt, query: query, connector: c.connector}, nil
+}
+
+func (c *tracedConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *tracedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if beginner, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return beginner.BeginTx(ctx, opts)
+	}
+	if opts.ReadOnly || opts.Isolation != driver.IsolationLevel(sql.LevelDefault) {
+		return nil, errors.New("tracing: driver does not support transaction options")
+	}
+	return c.Conn.Begin()
+}
+
+func (c *tracedConn) Ping(ctx context.Context) error {
+	if pinger, ok := c.Conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *tracedConn) ResetSession(ctx context.Context) error {
+	if resetter, ok := c.Conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *tracedConn) IsValid() bool {
+	if validator, ok := c.Conn.(driver.Validator); ok {
+		return validator.IsValid()
+	}
+	return true
+}
+
+func (c *tracedConn) CheckNamedValue(value *driver.NamedValue) error {
+	if checker, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(value)
+	}
+	return driver.ErrSkip
+}
+
+type tracedStmt struct {
+	driver.Stmt
+	query     string
+	connector *tracedConnector
+}
+
+func (s *tracedStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	ctx, span := s.connector.startSpan(ctx, s.query)
+
+	var rows driver.Rows
+	var err error
+	if queryer, ok := s.Stmt.(driver.StmtQueryContext); ok {
+		rows, err = queryer.QueryContext(ctx, args)
+	} else {
+		var values []driver.Value
+		if values, err = namedValuesToValues(args); err == nil {
+			rows, err = s.Stmt.Query(values)
+		}
+	}
+	endSQLSpan(span, err)
+	return rows, err
+}
+
+func (s *tracedStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	ctx, span := s.connector.startSpan(ctx, s.query)
+
+	var result driver.Result
+	var err error
+	if execer, ok := s.Stmt.(driver.StmtExecContext); ok {
+		result, err = execer.ExecContext(ctx, args)
+	} else {
+		var values []driver.Value
+		if values, err = namedValuesToValues(args); err == nil {
+			result, err = s.Stmt.Exec(values)
+		}
+	}
+	endSQLSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStmt) CheckNamedValue(value *driver.NamedValue) error {
+	if checker, ok := s.Stmt.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(value)
+	}
+	return driver.ErrSkip
+}
+
+func namedValuesToValues(args []driver.NamedValue) ([]driver.Value, error) {
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		if arg.Name != "" {
+			return nil, errors.New("tracing: driver does not support named parameters")
+		}
+		values[i] = arg.Value
+	}
+	return values, nil
+}
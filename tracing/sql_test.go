@@ -0,0 +1,116 @@
+package tracing
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/chlovec/rest-pack/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func openTracedDB(t *testing.T, tracer *Tracer) (*sql.DB, sqlmock.Sqlmock) {
+	t.Helper()
+
+	dsn := "tracing_" + t.Name()
+	mockDB, mock, err := sqlmock.NewWithDSN(dsn, sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(t, err)
+	t.Cleanup(func() { mockDB.Close() })
+
+	tracedDB, err := db.InitDB(tracer.WrapSQLOpen(sql.Open), "sqlmock", dsn, 0)
+	assert.NoError(t, err)
+	t.Cleanup(func() { tracedDB.Close() })
+	return tracedDB, mock
+}
+
+func TestWrapSQLOpen(t *testing.T) {
+	t.Run("should trace queries as children of the request span", func(t *testing.T) {
+		tracer, exporter := newTestTracer()
+		tracedDB, mock := openTracedDB(t, tracer)
+		mock.ExpectQuery("SELECT id FROM products WHERE id = ?").
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+
+		ctx, parent := tracer.Start(context.Background(), "GET /products/{id}")
+		var id int
+		assert.NoError(t, tracedDB.QueryRowContext(ctx, "SELECT id FROM products WHERE id = ?", 1).Scan(&id))
+		parent.End()
+
+		spans := exporter.Spans()
+		assert.Len(t, spans, 2)
+		assert.Equal(t, "SELECT", spans[0].Name)
+		assert.Equal(t, SpanKindClient, spans[0].Kind)
+		assert.Equal(t, parent.SpanContext().SpanID.String(), spans[0].ParentSpanID)
+		assert.Equal(t, map[string]any{
+			"db.system":    "sqlmock",
+			"db.statement": "SELECT id FROM products WHERE id = ?",
+		}, spans[0].Attributes)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("should record failed statements", func(t *testing.T) {
+		tracer, exporter := newTestTracer()
+		tracedDB, mock := openTracedDB(t, tracer)
+		mock.ExpectExec("DELETE FROM products WHERE id = ?").
+			WithArgs(1).
+			WillReturnError(errors.New("db error"))
+
+		_, err := tracedDB.Exec("DELETE FROM products WHERE id = ?", 1)
+
+		assert.EqualError(t, err, "db error")
+		spans := exporter.Spans()
+		assert.Len(t, spans, 1)
+		assert.Equal(t, "DELETE", spans[0].Name)
+		assert.Empty(t, spans[0].ParentSpanID)
+		assert.Equal(t, Status{Code: StatusError, Message: "db error"}, spans[0].Status)
+	})
+
+	t.Run("should trace prepared statements", func(t *testing.T) {
+		tracer, exporter := newTestTracer()
+		tracedDB, mock := openTracedDB(t, tracer)
+		mock.ExpectPrepare("INSERT INTO products(name) VALUES(?)").
+			ExpectExec().
+			WithArgs("Product A").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		stmt, err := tracedDB.Prepare("INSERT INTO products(name) VALUES(?)")
+		assert.NoError(t, err)
+		defer stmt.Close()
+		_, err = stmt.Exec("Product A")
+		assert.NoError(t, err)
+
+		spans := exporter.Spans()
+		assert.Len(t, spans, 1)
+		assert.Equal(t, "INSERT", spans[0].Name)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("should pass through transactions", func(t *testing.T) {
+		tracer, exporter := newTestTracer()
+		tracedDB, mock := openTracedDB(t, tracer)
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE products SET qty = 0").WillReturnResult(sqlmock.NewResult(0, 3))
+		mock.ExpectCommit()
+
+		tx, err := tracedDB.Begin()
+		assert.NoError(t, err)
+		_, err = tx.Exec("UPDATE products SET qty = 0")
+		assert.NoError(t, err)
+		assert.NoError(t, tx.Commit())
+
+		assert.Len(t, exporter.Spans(), 1)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("should return open errors", func(t *testing.T) {
+		tracer, _ := newTestTracer()
+		open := tracer.WrapSQLOpen(func(driverName, dataSourceName string) (*sql.DB, error) {
+			return nil, errors.New("unknown driver")
+		})
+
+		_, err := open("nope", "")
+		assert.EqualError(t, err, "unknown driver")
+	})
+}
@@ -0,0 +1,225 @@
+// Package tracing records spans for HTTP requests and SQL statements,
+// propagates them with the W3C traceparent header and exports finished spans
+// through an Exporter, such as one writing JSON lines to stdout or a file.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"sync"
+	"time"
+)
+
+// SpanKind describes the relationship between a span and its caller.
+type SpanKind string
+
+const (
+	SpanKindInternal SpanKind = "internal"
+	SpanKindServer   SpanKind = "server"
+	SpanKindClient   SpanKind = "client"
+)
+
+// StatusCode is the outcome of the operation a span represents.
+type StatusCode string
+
+const (
+	StatusUnset StatusCode = "unset"
+	StatusOK    StatusCode = "ok"
+	StatusError StatusCode = "error"
+)
+
+type Status struct {
+	Code    StatusCode `json:"code"`
+	Message string     `json:"message,omitempty"`
+}
+
+// SpanData is the exported form of a finished span.
+type SpanData struct {
+	TraceID      string         `json:"traceId"`
+	SpanID       string         `json:"spanId"`
+	ParentSpanID string         `json:"parentSpanId,omitempty"`
+	Name         string         `json:"name"`
+	Kind         SpanKind       `json:"kind"`
+	StartTime    time.Time      `json:"startTime"`
+	EndTime      time.Time      `json:"endTime"`
+	Attributes   map[string]any `json:"attributes,omitempty"`
+	Status       Status         `json:"status"`
+}
+
+// Exporter receives spans as they end.
+type Exporter interface {
+	ExportSpan(span SpanData) error
+}
+
+// Tracer starts spans and hands them to its exporter when they end. It is
+// safe for concurrent use.
+type Tracer struct {
+	exporter Exporter
+	now      func() time.Time
+}
+
+func NewTracer(exporter Exporter) *Tracer {
+	return &Tracer{exporter: exporter, now: time.Now}
+}
+
+type SpanOption func(*Span)
+
+// WithSpanKind sets the kind of the span. It defaults to SpanKindInternal.
+func WithSpanKind(kind SpanKind) SpanOption {
+	return func(s *Span) {
+		s.data.Kind = kind
+	}
+}
+
+// WithAttributes sets attributes on the span when it starts.
+func WithAttributes(attrs map[string]any) SpanOption {
+	return func(s *Span) {
+		for key, value := range attrs {
+			s.data.Attributes[key] = value
+		}
+	}
+}
+
+// Start starts a span that is a child of the span or remote span context in
+// ctx, or the root of a new trace if there is none. The returned context
+// carries the new span; End must be called on it.
+func (t *Tracer) Start(ctx context.Context, name string, opts ...SpanOption) (context.Context, *Span) {
+	parent := SpanContextFromContext(ctx)
+
+	sc := SpanContext{SpanID: newSpanID(), Sampled: true}
+	if parent.IsValid() {
+		sc.TraceID = parent.TraceID
+		sc.Sampled = parent.Sampled
+	} else {
+		sc.TraceID = newTraceID()
+	}
+
+	span := &Span{
+		tracer: t,
+		sc:     sc,
+		data: SpanData{
+			TraceID:    sc.TraceID.String(),
+			SpanID:     sc.SpanID.String(),
+			Name:       name,
+			Kind:       SpanKindInternal,
+			StartTime:  t.now(),
+			Attributes: make(map[string]any),
+			Status:     Status{Code: StatusUnset},
+		},
+	}
+	if parent.IsValid() {
+		span.data.ParentSpanID = parent.SpanID.String()
+	}
+	for _, opt := range opts {
+		opt(span)
+	}
+
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// Span is an operation being traced. Its methods are safe for concurrent use
+// and do nothing once the span has ended.
+type Span struct {
+	tracer *Tracer
+	sc     SpanContext
+
+	mu    sync.Mutex
+	data  SpanData
+	ended bool
+}
+
+func (s *Span) SpanContext() SpanContext {
+	return s.sc
+}
+
+func (s *Span) SetAttribute(key string, value any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.ended {
+		s.data.Attributes[key] = value
+	}
+}
+
+func (s *Span) SetStatus(code StatusCode, message string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.ended {
+		s.data.Status = Status{Code: code, Message: message}
+	}
+}
+
+// RecordError marks the span as failed with err. A nil err is ignored.
+func (s *Span) RecordError(err error) {
+	if err == nil {
+		return
+	}
+	s.SetStatus(StatusError, err.Error())
+}
+
+// End finishes the span and exports it if it is sampled. Only the first call
+// has an effect.
+func (s *Span) End() {
+	s.end(true)
+}
+
+// discard ends the span without exporting it.
+func (s *Span) discard() {
+	s.end(false)
+}
+
+func (s *Span) end(export bool) {
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.EndTime = s.tracer.now()
+	data := s.data
+	s.mu.Unlock()
+
+	if export && s.sc.Sampled && s.tracer.exporter != nil {
+		s.tracer.exporter.ExportSpan(data)
+	}
+}
+
+type spanKey struct{}
+type remoteSpanContextKey struct{}
+
+// SpanFromContext returns the span stored in ctx by Tracer.Start, or nil.
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// ContextWithRemoteSpanContext returns a copy of ctx carrying sc, a span
+// context received from another service, as the parent of the next span.
+func ContextWithRemoteSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, remoteSpanContextKey{}, sc)
+}
+
+// SpanContextFromContext returns the span context of the span in ctx, falling
+// back to a remote span context. The result is invalid if there is neither.
+func SpanContextFromContext(ctx context.Context) SpanContext {
+	if span := SpanFromContext(ctx); span != nil {
+		return span.sc
+	}
+	sc, _ := ctx.Value(remoteSpanContextKey{}).(SpanContext)
+	return sc
+}
+
+func newTraceID() TraceID {
+	var id TraceID
+	for !id.IsValid() {
+		rand.Read(id[:])
+	}
+	return id
+}
+
+func newSpanID() SpanID {
+	var id SpanID
+	for !id.IsValid() {
+		rand.Read(id[:])
+	}
+	return id
+}
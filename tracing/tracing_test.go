@@ -0,0 +1,100 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestTracer() (*Tracer, *InMemoryExporter) {
+	exporter := NewInMemoryExporter()
+	tracer := NewTracer(exporter)
+
+	now := time.Date(2024, time.March, 5, 13, 55, 36, 0, time.UTC)
+	tracer.now = func() time.Time {
+		now = now.Add(time.Millisecond)
+		return now
+	}
+	return tracer, exporter
+}
+
+func TestTracer(t *testing.T) {
+	t.Run("should start a new trace without a parent", func(t *testing.T) {
+		tracer, exporter := newTestTracer()
+
+		ctx, span := tracer.Start(context.Background(), "work", WithAttributes(map[string]any{"job": "emails"}))
+		span.SetAttribute("count", 3)
+		span.End()
+
+		assert.Same(t, span, SpanFromContext(ctx))
+		spans := exporter.Spans()
+		assert.Len(t, spans, 1)
+		assert.Equal(t, "work", spans[0].Name)
+		assert.Equal(t, SpanKindInternal, spans[0].Kind)
+		assert.Len(t, spans[0].TraceID, 32)
+		assert.Len(t, spans[0].SpanID, 16)
+		assert.Empty(t, spans[0].ParentSpanID)
+		assert.Equal(t, map[string]any{"job": "emails", "count": 3}, spans[0].Attributes)
+		assert.Equal(t, Status{Code: StatusUnset}, spans[0].Status)
+		assert.Equal(t, time.Millisecond, spans[0].EndTime.Sub(spans[0].StartTime))
+	})
+
+	t.Run("should create child spans in the same trace", func(t *testing.T) {
+		tracer, exporter := newTestTracer()
+
+		ctx, parent := tracer.Start(context.Background(), "parent")
+		_, child := tracer.Start(ctx, "child", WithSpanKind(SpanKindClient))
+		child.RecordError(errors.New("timeout"))
+		child.End()
+		parent.End()
+
+		spans := exporter.Spans()
+		assert.Len(t, spans, 2)
+		assert.Equal(t, "child", spans[0].Name)
+		assert.Equal(t, SpanKindClient, spans[0].Kind)
+		assert.Equal(t, spans[1].TraceID, spans[0].TraceID)
+		assert.Equal(t, spans[1].SpanID, spans[0].ParentSpanID)
+		assert.Equal(t, Status{Code: StatusError, Message: "timeout"}, spans[0].Status)
+	})
+
+	t.Run("should continue a remote trace", func(t *testing.T) {
+		tracer, exporter := newTestTracer()
+		remote, err := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+		assert.NoError(t, err)
+
+		_, span := tracer.Start(ContextWithRemoteSpanContext(context.Background(), remote), "handle")
+		span.End()
+
+		spans := exporter.Spans()
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", spans[0].TraceID)
+		assert.Equal(t, "00f067aa0ba902b7", spans[0].ParentSpanID)
+	})
+
+	t.Run("should not export spans of unsampled traces", func(t *testing.T) {
+		tracer, exporter := newTestTracer()
+		remote, err := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+		assert.NoError(t, err)
+
+		_, span := tracer.Start(ContextWithRemoteSpanContext(context.Background(), remote), "handle")
+		span.End()
+
+		assert.False(t, span.SpanContext().Sampled)
+		assert.Empty(t, exporter.Spans())
+	})
+
+	t.Run("should export a span once", func(t *testing.T) {
+		tracer, exporter := newTestTracer()
+
+		_, span := tracer.Start(context.Background(), "work")
+		span.End()
+		span.SetAttribute("late", true)
+		span.End()
+
+		spans := exporter.Spans()
+		assert.Len(t, spans, 1)
+		assert.Empty(t, spans[0].Attributes)
+	})
+}
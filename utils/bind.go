@@ -0,0 +1,276 @@
+package utils
+
+import (
+	"encoding"
+	"fmt"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+const defaultMaxMemory = 32 << 20
+
+var (
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	timeType            = reflect.TypeOf(time.Time{})
+	durationType        = reflect.TypeOf(time.Duration(0))
+)
+
+// FieldError is a value that could not be converted to the type of the
+// field it is bound to.
+type FieldError struct {
+	// Field is the name of the struct field.
+	Field string
+	// Source is where the value came from: path, query, header or form.
+	Source string
+	// Name is the name of the parameter, such as the query key.
+	Name  string
+	Value string
+	Err   error
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("invalid %s parameter '%s': %v", e.Source, e.Name, e.Err)
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// BindErrors are the conversion errors of every field that failed to bind.
+type BindErrors []*FieldError
+
+func (e BindErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Bind fills the struct pointed to by dst from r and validates it with
+// Validate. Fields are bound by tag:
+//
+//	path:"id"          the path variable, from gorilla/mux or http.ServeMux
+//	query:"pagesize"   the query parameter
+//	header:"X-Tenant"  the header
+//	form:"name"        the form field of a urlencoded or multipart body
+//
+// A default:"20" tag sets fields whose parameter is missing; slices take a
+// comma-separated default. Strings, bools, ints, uints, floats, time.Time
+// (RFC 3339 unless a layout:"2006-01-02" tag is set), time.Duration, types
+// implementing encoding.TextUnmarshaler, pointers to these and slices of
+// these are supported. Slices collect repeated parameters. Embedded structs
+// are bound too.
+//
+// Values that cannot be converted are returned as BindErrors, which
+// GetValidationError accepts like validator.ValidationErrors; otherwise the
+// result of Validate.Struct is returned.
+func Bind(r *http.Request, dst any) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("bind: destination must be a pointer to a struct, got %T", dst)
+	}
+
+	if err := parseForm(r, v.Elem().Type()); err != nil {
+		return err
+	}
+
+	var errs BindErrors
+	bindStruct(r, v.Elem(), &errs)
+	if len(errs) > 0 {
+		return errs
+	}
+	return Validate.Struct(dst)
+}
+
+// parseForm parses the body of form requests if t has form fields.
+func parseForm(r *http.Request, t reflect.Type) error {
+	if !hasTag(t, "form") {
+		return nil
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "multipart/form-data" {
+		return r.ParseMultipartForm(defaultMaxMemory)
+	}
+	return r.ParseForm()
+}
+
+func hasTag(t reflect.Type, tag string) bool {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct && hasTag(field.Type, tag) {
+			return true
+		}
+		if _, ok := field.Tag.Lookup(tag); ok {
+			return true
+		}
+	}
+	return false
+}
+
+var bindSources = []string{"path", "query", "header", "form"}
+
+func bindStruct(r *http.Request, v reflect.Value, errs *BindErrors) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			bindStruct(r, v.Field(i), errs)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+
+		bound := false
+		for _, source := range bindSources {
+			name, ok := field.Tag.Lookup(source)
+			if !ok || name == "" || name == "-" {
+				continue
+			}
+			values := lookupValues(r, source, name)
+			if len(values) == 0 {
+				continue
+			}
+
+			bound = true
+			if value, err := setField(v.Field(i), field, values); err != nil {
+				*errs = append(*errs, &FieldError{Field: field.Name, Source: source, Name: name, Value: value, Err: err})
+			}
+		}
+
+		if def, ok := field.Tag.Lookup("default"); ok && !bound {
+			values := []string{def}
+			if field.Type.Kind() == reflect.Slice && !field.Type.Implements(textUnmarshalerType) {
+				values = strings.Split(def, ",")
+			}
+			if value, err := setField(v.Field(i), field, values); err != nil {
+				*errs = append(*errs, &FieldError{Field: field.Name, Source: "default", Name: field.Name, Value: value, Err: err})
+			}
+		}
+	}
+}
+
+func lookupValues(r *http.Request, source, name string) []string {
+	switch source {
+	case "path":
+		if value, ok := mux.Vars(r)[name]; ok {
+			return []string{value}
+		}
+		if value := r.PathValue(name); value != "" {
+			return []string{value}
+		}
+	case "query":
+		return r.URL.Query()[name]
+	case "header":
+		return r.Header.Values(name)
+	case "form":
+		return r.PostForm[name]
+	}
+	return nil
+}
+
+// setField converts values and stores them in v. On failure it returns the
+// value that could not be converted.
+func setField(v reflect.Value, field reflect.StructField, values []string) (string, error) {
+	if v.Kind() == reflect.Slice && !isScalar(v.Type()) {
+		slice := reflect.MakeSlice(v.Type(), len(values), len(values))
+		for i, value := range values {
+			if err := setValue(slice.Index(i), field, value); err != nil {
+				return value, err
+			}
+		}
+		v.Set(slice)
+		return "", nil
+	}
+	return values[0], setValue(v, field, values[0])
+}
+
+// isScalar reports whether a slice type is bound from a single value.
+func isScalar(t reflect.Type) bool {
+	return reflect.PointerTo(t).Implements(textUnmarshalerType) || t.Elem().Kind() == reflect.Uint8
+}
+
+func setValue(v reflect.Value, field reflect.StructField, value string) error {
+	if v.Kind() == reflect.Pointer {
+		elem := reflect.New(v.Type().Elem())
+		if err := setValue(elem.Elem(), field, value); err != nil {
+			return err
+		}
+		v.Set(elem)
+		return nil
+	}
+
+	if v.CanAddr() && v.Addr().Type().Implements(textUnmarshalerType) && v.Type() != timeType {
+		if err := v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(value)); err != nil {
+			return fmt.Errorf("%q is not a valid %s", value, v.Type().Name())
+		}
+		return nil
+	}
+
+	switch {
+	case v.Type() == timeType:
+		layout := field.Tag.Get("layout")
+		if layout == "" {
+			layout = time.RFC3339
+		}
+		t, err := time.Parse(layout, value)
+		if err != nil {
+			return fmt.Errorf("%q is not a valid time in the format %s", value, layout)
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	case v.Type() == durationType:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("%q is not a valid duration", value)
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%q is not a boolean", value)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("%q is not a valid integer", value)
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("%q is not a valid unsigned integer", value)
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("%q is not a valid number", value)
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			v.SetBytes([]byte(value))
+			return nil
+		}
+		fallthrough
+	default:
+		return fmt.Errorf("unsupported field type %s", v.Type())
+	}
+	return nil
+}
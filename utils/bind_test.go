@@ -0,0 +1,183 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type sortOrder int
+
+const (
+	ascending sortOrder = iota
+	descending
+)
+
+func (o *sortOrder) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "asc":
+		*o = ascending
+	case "desc":
+		*o = descending
+	default:
+		return fmt.Errorf("unknown sort order %q", text)
+	}
+	return nil
+}
+
+type status string
+
+type page struct {
+	PageSize   int `query:"pagesize" default:"20" validate:"gt=0,max=100"`
+	PageNumber int `query:"pagenumber" default:"1"`
+}
+
+type searchRequest struct {
+	page
+	ID       uint64        `path:"id"`
+	Tenant   string        `header:"X-Tenant" validate:"required"`
+	Tags     []string      `query:"tag"`
+	IDs      []int         `query:"ids" default:"1,2"`
+	Archived bool          `query:"archived"`
+	Status   status        `query:"status" default:"active" validate:"oneof=active archived"`
+	Sort     sortOrder     `query:"sort"`
+	Since    time.Time     `query:"since"`
+	Day      time.Time     `query:"day" layout:"2006-01-02"`
+	Timeout  time.Duration `query:"timeout"`
+	Limit    *float64      `query:"limit"`
+	ignored  string        `query:"ignored"`
+}
+
+func TestBind(t *testing.T) {
+	newRequest := func(target string) *http.Request {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.Header.Set("X-Tenant", "acme")
+		return mux.SetURLVars(req, map[string]string{"id": "42"})
+	}
+
+	t.Run("should bind all sources", func(t *testing.T) {
+		req := newRequest("/search/42?pagesize=50&tag=a&tag=b&ids=7&archived=true&status=archived" +
+			"&sort=desc&since=2024-05-01T10:00:00Z&day=2024-05-02&timeout=1m30s&limit=2.5&ignored=x")
+
+		var got searchRequest
+		require.NoError(t, Bind(req, &got))
+
+		limit := 2.5
+		assert.Equal(t, searchRequest{
+			page:     page{PageSize: 50, PageNumber: 1},
+			ID:       42,
+			Tenant:   "acme",
+			Tags:     []string{"a", "b"},
+			IDs:      []int{7},
+			Archived: true,
+			Status:   "archived",
+			Sort:     descending,
+			Since:    time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+			Day:      time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC),
+			Timeout:  90 * time.Second,
+			Limit:    &limit,
+		}, got)
+	})
+
+	t.Run("should apply defaults", func(t *testing.T) {
+		var got searchRequest
+		require.NoError(t, Bind(newRequest("/search/42"), &got))
+
+		assert.Equal(t, 20, got.PageSize)
+		assert.Equal(t, 1, got.PageNumber)
+		assert.Equal(t, []int{1, 2}, got.IDs)
+		assert.Equal(t, status("active"), got.Status)
+		assert.Nil(t, got.Limit)
+	})
+
+	t.Run("should read path values of http.ServeMux", func(t *testing.T) {
+		var got struct {
+			ID int `path:"id"`
+		}
+		mux := http.NewServeMux()
+		mux.HandleFunc("GET /items/{id}", func(w http.ResponseWriter, r *http.Request) {
+			require.NoError(t, Bind(r, &got))
+		})
+		mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/items/9", nil))
+
+		assert.Equal(t, 9, got.ID)
+	})
+
+	t.Run("should return conversion errors of every field", func(t *testing.T) {
+		req := newRequest("/search/42?pagesize=ten&ids=1&ids=x&sort=up&since=yesterday&archived=maybe")
+
+		var got searchRequest
+		err := Bind(req, &got)
+
+		var bindErrs BindErrors
+		require.ErrorAs(t, err, &bindErrs)
+		assert.Len(t, bindErrs, 5)
+		assert.Equal(t, &FieldError{Field: "IDs", Source: "query", Name: "ids", Value: "x", Err: bindErrs[1].Err}, bindErrs[1])
+		assert.Equal(t, []ValidationError{
+			{Code: "type", Field: "pagesize", Message: `invalid query parameter 'pagesize': "ten" is not a valid integer`},
+			{Code: "type", Field: "ids", Message: `invalid query parameter 'ids': "x" is not a valid integer`},
+			{Code: "type", Field: "archived", Message: `invalid query parameter 'archived': "maybe" is not a boolean`},
+			{Code: "type", Field: "sort", Message: `invalid query parameter 'sort': "up" is not a valid sortOrder`},
+			{Code: "type", Field: "since", Message: `invalid query parameter 'since': "yesterday" is not a valid time in the format 2006-01-02T15:04:05Z07:00`},
+		}, GetValidationError(err))
+		assert.Equal(t, http.StatusBadRequest, ProblemFromError(err).Status)
+	})
+
+	t.Run("should validate the bound struct", func(t *testing.T) {
+		req := newRequest("/search/42?pagesize=500&status=deleted")
+		req.Header.Del("X-Tenant")
+
+		var got searchRequest
+		err := Bind(req, &got)
+
+		assert.Equal(t, []ValidationError{
+			{Code: "max", Field: "pagesize", Param: "100", Message: "'pagesize' must be at most 100"},
+			{Code: "required", Field: "X-Tenant", Message: "'X-Tenant' is required"},
+			{Code: "oneof", Field: "status", Param: "active archived", Message: "'status' must be one of active, archived"},
+		}, GetValidationError(err))
+	})
+
+	t.Run("should bind urlencoded forms", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/items?name=query", strings.NewReader("name=Widget&qty=3"))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+		var got struct {
+			Name string `form:"name"`
+			Qty  int    `form:"qty"`
+		}
+		require.NoError(t, Bind(req, &got))
+
+		assert.Equal(t, "Widget", got.Name)
+		assert.Equal(t, 3, got.Qty)
+	})
+
+	t.Run("should bind multipart forms", func(t *testing.T) {
+		var body bytes.Buffer
+		writer := multipart.NewWriter(&body)
+		require.NoError(t, writer.WriteField("name", "Widget"))
+		require.NoError(t, writer.Close())
+		req := httptest.NewRequest(http.MethodPost, "/items", &body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+
+		var got struct {
+			Name string `form:"name"`
+		}
+		require.NoError(t, Bind(req, &got))
+
+		assert.Equal(t, "Widget", got.Name)
+	})
+
+	t.Run("should reject destinations that are not struct pointers", func(t *testing.T) {
+		var got searchRequest
+		assert.EqualError(t, Bind(newRequest("/"), got), "bind: destination must be a pointer to a struct, got utils.searchRequest")
+	})
+}
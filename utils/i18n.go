@@ -0,0 +1,272 @@
+package utils
+
+import (
+	"bufio"
+	"context"
+	"embed"
+	"io"
+	"net"
+	"net/http"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/go-playground/locales"
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/es"
+	"github.com/go-playground/locales/fr"
+	"github.com/go-playground/locales/pt"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+	estranslations "github.com/go-playground/validator/v10/translations/es"
+	frtranslations "github.com/go-playground/validator/v10/translations/fr"
+	pttranslations "github.com/go-playground/validator/v10/translations/pt"
+)
+
+//go:embed i18n/*.json
+var builtinCatalogs embed.FS
+
+// Locale is a locale that a Catalog supports.
+type Locale struct {
+	Translator locales.Translator
+	// RegisterValidation registers the validation messages of the locale on
+	// Validate, like the RegisterDefaultTranslations functions of the
+	// validator translations packages. It is called once per locale name, by
+	// the first catalog that supports the locale. Without it, validation
+	// messages are in English.
+	RegisterValidation func(v *validator.Validate, trans ut.Translator) error
+}
+
+// Built-in locales. Their catalogs translate the messages of the error
+// helpers of this package.
+var (
+	LocaleEnglish    = Locale{Translator: en.New()}
+	LocaleFrench     = Locale{Translator: fr.New(), RegisterValidation: frtranslations.RegisterDefaultTranslations}
+	LocaleSpanish    = Locale{Translator: es.New(), RegisterValidation: estranslations.RegisterDefaultTranslations}
+	LocalePortuguese = Locale{Translator: pt.New(), RegisterValidation: pttranslations.RegisterDefaultTranslations}
+)
+
+// Catalog holds the message catalogs of the supported locales. Messages are
+// keyed by their English text, such as "Not Found", so that messages
+// without a translation are written as they are.
+type Catalog struct {
+	uni *ut.UniversalTranslator
+}
+
+// NewCatalog returns a catalog of fallback and supported. It registers
+// validation messages on Validate, so call it at start-up.
+func NewCatalog(fallback Locale, supported ...Locale) (*Catalog, error) {
+	all := []Locale{fallback}
+	for _, locale := range supported {
+		if !slices.ContainsFunc(all, func(l Locale) bool { return l.Translator.Locale() == locale.Translator.Locale() }) {
+			all = append(all, locale)
+		}
+	}
+	translators := make([]locales.Translator, len(all))
+	for i, locale := range all {
+		translators[i] = locale.Translator
+	}
+	c := &Catalog{uni: ut.New(fallback.Translator, translators...)}
+
+	for _, locale := range all {
+		if err := registerValidation(locale); err != nil {
+			return nil, err
+		}
+
+		trans, _ := c.uni.GetTranslator(locale.Translator.Locale())
+
+		f, err := builtinCatalogs.Open(path.Join("i18n", trans.Locale()+".json"))
+		if err != nil {
+			continue
+		}
+		err = c.Load(f)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// validationTranslators holds the translators registered on Validate, by
+// locale name.
+var (
+	validationMu          sync.Mutex
+	validationTranslators = make(map[string]ut.Translator)
+)
+
+func registerValidation(locale Locale) error {
+	if locale.RegisterValidation == nil {
+		return nil
+	}
+
+	validationMu.Lock()
+	defer validationMu.Unlock()
+	name := locale.Translator.Locale()
+	if _, ok := validationTranslators[name]; ok {
+		return nil
+	}
+	trans, _ := ut.New(locale.Translator, locale.Translator).GetTranslator(name)
+	if err := locale.RegisterValidation(Validate, trans); err != nil {
+		return err
+	}
+	validationTranslators[name] = trans
+	return nil
+}
+
+// validationTranslator returns the translator of the validation messages of
+// the locale of trans, or nil if none are registered.
+func validationTranslator(trans ut.Translator) ut.Translator {
+	validationMu.Lock()
+	defer validationMu.Unlock()
+	return validationTranslators[trans.Locale()]
+}
+
+// Load adds the translations of a JSON translation file in the format of
+// universal-translator, such as
+//
+//	[{"locale": "fr", "key": "Not Found", "trans": "Introuvable", "override": true}]
+//
+// Set override to replace built-in translations.
+func (c *Catalog) Load(r io.Reader) error {
+	return c.uni.ImportByReader(ut.FormatJSON, r)
+}
+
+// LoadFiles adds the translations of a JSON translation file, or of every
+// .json file under a directory.
+func (c *Catalog) LoadFiles(path string) error {
+	return c.uni.Import(ut.FormatJSON, path)
+}
+
+// Negotiate returns the translator of the supported locale that
+// acceptLanguage, an Accept-Language header, prefers. A region such as
+// fr-CA falls back to its language.
+func (c *Catalog) Negotiate(acceptLanguage string) ut.Translator {
+	var candidates []string
+	for _, tag := range parseAcceptLanguage(acceptLanguage) {
+		tag = strings.ReplaceAll(tag, "-", "_")
+		candidates = append(candidates, tag)
+		if language, _, ok := strings.Cut(tag, "_"); ok {
+			candidates = append(candidates, language)
+		}
+	}
+	trans, _ := c.uni.FindTranslator(candidates...)
+	return trans
+}
+
+// parseAcceptLanguage returns the language tags of header by decreasing
+// preference, leaving out "*" and tags with q=0.
+func parseAcceptLanguage(header string) []string {
+	type weightedTag struct {
+		tag string
+		q   float64
+	}
+
+	var tags []weightedTag
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		tag = strings.TrimSpace(tag)
+		if tag == "" || tag == "*" {
+			continue
+		}
+
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if q > 0 {
+			tags = append(tags, weightedTag{tag: tag, q: q})
+		}
+	}
+
+	slices.SortStableFunc(tags, func(a, b weightedTag) int {
+		switch {
+		case a.q > b.q:
+			return -1
+		case a.q < b.q:
+			return 1
+		}
+		return 0
+	})
+
+	result := make([]string, len(tags))
+	for i, t := range tags {
+		result[i] = t.tag
+	}
+	return result
+}
+
+type translatorKey struct{}
+
+// ContextWithTranslator returns a copy of ctx carrying trans.
+func ContextWithTranslator(ctx context.Context, trans ut.Translator) context.Context {
+	return context.WithValue(ctx, translatorKey{}, trans)
+}
+
+// TranslatorFromContext returns the translator stored in ctx, or nil if
+// there is none.
+func TranslatorFromContext(ctx context.Context) ut.Translator {
+	trans, _ := ctx.Value(translatorKey{}).(ut.Translator)
+	return trans
+}
+
+// translatorWriter carries the translator of a response.
+type translatorWriter struct {
+	http.ResponseWriter
+	trans ut.Translator
+}
+
+// WithTranslator returns a writer whose error messages are translated by
+// trans.
+func WithTranslator(w http.ResponseWriter, trans ut.Translator) http.ResponseWriter {
+	return &translatorWriter{ResponseWriter: w, trans: trans}
+}
+
+func (w *translatorWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (w *translatorWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
+
+func (w *translatorWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// TranslatorOf returns the translator of w, or nil unless the writer was
+// set up by WithTranslator. Middleware that wraps the writer must implement
+// Unwrap() http.ResponseWriter for it to be found.
+func TranslatorOf(w http.ResponseWriter) ut.Translator {
+	for {
+		if t, ok := w.(*translatorWriter); ok {
+			return t.trans
+		}
+		unwrapper, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return nil
+		}
+		w = unwrapper.Unwrap()
+	}
+}
+
+// translate returns the translation of message, or message if trans is nil
+// or has no translation for it.
+func translate(trans ut.Translator, message string, params ...string) string {
+	if trans == nil {
+		return message
+	}
+	translated, err := trans.T(message, params...)
+	if err != nil {
+		return message
+	}
+	return translated
+}
@@ -0,0 +1,165 @@
+package utils
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-playground/locales/it"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type signupPayload struct {
+	Name  string `json:"name" validate:"required"`
+	Email string `json:"email" validate:"omitempty,email"`
+	Code  string `json:"code" validate:"omitempty,startsnotwith=x"`
+}
+
+func newTestCatalog(t *testing.T) *Catalog {
+	t.Helper()
+	catalog, err := NewCatalog(LocaleEnglish, LocaleFrench, LocaleSpanish, LocalePortuguese)
+	require.NoError(t, err)
+	return catalog
+}
+
+func TestCatalogNegotiate(t *testing.T) {
+	catalog := newTestCatalog(t)
+
+	for header, want := range map[string]string{
+		"":                         "en",
+		"fr":                       "fr",
+		"FR-ca":                    "fr",
+		"pt-BR,pt;q=0.9":           "pt",
+		"de, es;q=0.5, fr;q=0.4":   "es",
+		"fr;q=0.2, es;q=0.8":       "es",
+		"fr;q=0, *":                "en",
+		"de-DE, ja;q=0.9, *;q=0.1": "en",
+		"es;q=abc, fr":             "fr",
+	} {
+		assert.Equal(t, want, catalog.Negotiate(header).Locale(), header)
+	}
+}
+
+func TestNewCatalogRegistersValidationOnce(t *testing.T) {
+	var calls int
+	italian := Locale{Translator: it.New(), RegisterValidation: func(v *validator.Validate, trans ut.Translator) error {
+		calls++
+		return v.RegisterTranslation("required", trans, func(trans ut.Translator) error {
+			return trans.Add("required", "{0} è obbligatorio", true)
+		}, func(trans ut.Translator, fe validator.FieldError) string {
+			message, _ := trans.T("required", fe.Field())
+			return message
+		})
+	}}
+	t.Cleanup(func() {
+		validationMu.Lock()
+		defer validationMu.Unlock()
+		delete(validationTranslators, "it")
+	})
+
+	for i := 0; i < 2; i++ {
+		catalog, err := NewCatalog(LocaleEnglish, italian)
+		require.NoError(t, err)
+
+		errs := GetLocalizedValidationError(Validate.Struct(signupPayload{}), catalog.Negotiate("it"))
+		assert.Equal(t, "name è obbligatorio", errs[0].Message)
+	}
+	assert.Equal(t, 1, calls)
+}
+
+func TestCatalogLoad(t *testing.T) {
+	catalog := newTestCatalog(t)
+
+	err := catalog.Load(strings.NewReader(`[
+		{"locale": "fr", "key": "Not Found", "trans": "Ressource introuvable", "override": true},
+		{"locale": "es", "key": "name taken", "trans": "nombre en uso"}
+	]`))
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "pt.json"), []byte(`[{"locale": "pt", "key": "name taken", "trans": "nome em uso"}]`), 0o600))
+	require.NoError(t, catalog.LoadFiles(dir))
+
+	assert.Equal(t, "Ressource introuvable", translate(catalog.Negotiate("fr"), "Not Found"))
+	assert.Equal(t, "nombre en uso", translate(catalog.Negotiate("es"), "name taken"))
+	assert.Equal(t, "nome em uso", translate(catalog.Negotiate("pt"), "name taken"))
+	assert.Equal(t, "name taken", translate(catalog.Negotiate("fr"), "name taken"))
+
+	assert.Error(t, catalog.Load(strings.NewReader(`[{"locale": "de", "key": "Not Found", "trans": "Nicht gefunden"}]`)))
+	assert.Error(t, catalog.Load(strings.NewReader(`[{"locale": "fr", "key": "Not Found", "trans": "Introuvable"}]`)))
+}
+
+func TestLocalizedErrors(t *testing.T) {
+	catalog := newTestCatalog(t)
+	french := catalog.Negotiate("fr")
+	req := httptest.NewRequest(http.MethodPost, "/signup", nil)
+
+	t.Run("should translate error helpers", func(t *testing.T) {
+		rw := httptest.NewRecorder()
+		WriteNotFound(WithTranslator(rw, french), "", nil)
+		assert.JSONEq(t, `{"error":"Introuvable"}`, rw.Body.String())
+
+		rw = httptest.NewRecorder()
+		WriteBadRequest(WithTranslator(rw, catalog.Negotiate("es")), "", nil)
+		assert.JSONEq(t, `{"error":"Solicitud incorrecta"}`, rw.Body.String())
+
+		rw = httptest.NewRecorder()
+		WriteInternalServerError(WithTranslator(rw, catalog.Negotiate("pt")), "", nil)
+		assert.JSONEq(t, `{"error":"Erro interno do servidor"}`, rw.Body.String())
+
+		rw = httptest.NewRecorder()
+		WriteBadRequest(WithTranslator(rw, french), "name taken", nil)
+		assert.JSONEq(t, `{"error":"name taken"}`, rw.Body.String())
+	})
+
+	t.Run("should translate validation errors", func(t *testing.T) {
+		err := Validate.Struct(signupPayload{Email: "jo@", Code: "xyz"})
+
+		rw := httptest.NewRecorder()
+		WriteError(WithTranslator(rw, french), err)
+
+		assert.Equal(t, http.StatusBadRequest, rw.Code)
+		assert.JSONEq(t, `{"error":"Erreur de validation","details":[
+			{"code":"required","field":"name","message":"name est un champ obligatoire"},
+			{"code":"email","field":"email","message":"email doit être une adresse email valide"},
+			{"code":"startsnotwith","field":"code","param":"x","message":"'code' must not start with 'x'"}
+		]}`, rw.Body.String())
+	})
+
+	t.Run("should translate bind errors", func(t *testing.T) {
+		err := BindErrors{{Field: "PageSize", Source: "query", Name: "pagesize", Value: "ten"}}
+
+		assert.Equal(t, []ValidationError{
+			{Code: "type", Field: "pagesize", Message: "paramètre query 'pagesize' invalide"},
+		}, GetLocalizedValidationError(err, french))
+	})
+
+	t.Run("should translate problems", func(t *testing.T) {
+		rw := httptest.NewRecorder()
+		w := WithTranslator(WithErrorFormat(rw, req, ErrorFormatProblem), french)
+		WriteError(w, NewAPIError(http.StatusNotFound, ""))
+
+		assert.JSONEq(t, `{"type":"about:blank","title":"Introuvable","status":404,"instance":"/signup"}`, rw.Body.String())
+	})
+
+	t.Run("should keep messages without a translator", func(t *testing.T) {
+		rw := httptest.NewRecorder()
+		WriteNotFound(rw, "", nil)
+		assert.JSONEq(t, `{"error":"Not Found"}`, rw.Body.String())
+		assert.Nil(t, TranslatorOf(rw))
+	})
+}
+
+func TestTranslatorFromContext(t *testing.T) {
+	french := newTestCatalog(t).Negotiate("fr")
+
+	assert.Nil(t, TranslatorFromContext(context.Background()))
+	assert.Equal(t, french, TranslatorFromContext(ContextWithTranslator(context.Background(), french)))
+}
@@ -0,0 +1,247 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// JSONErrorKind tells apart the ways decoding a JSON body can fail.
+type JSONErrorKind int
+
+const (
+	// JSONMissingBody is an absent or empty body.
+	JSONMissingBody JSONErrorKind = iota + 1
+	// JSONUnsupportedMediaType is a Content-Type other than application/json.
+	JSONUnsupportedMediaType
+	// JSONSyntax is malformed or truncated JSON.
+	JSONSyntax
+	// JSONTypeMismatch is a value of the wrong type for its field.
+	JSONTypeMismatch
+	// JSONUnknownField is a field that the payload does not have.
+	JSONUnknownField
+	// JSONTrailingData is data after the first top-level value.
+	JSONTrailingData
+	// JSONTooLarge is a body over the size limit.
+	JSONTooLarge
+)
+
+// JSONError is returned by ParseJSON and JSONDecoder.Decode.
+type JSONError struct {
+	Kind JSONErrorKind
+	// Offset is the byte offset of a syntax error or type mismatch.
+	Offset int64
+	// Field is the path of a mismatched field, such as "items.0.price", or the
+	// name of an unknown field.
+	Field string
+	// Expected and Got describe a type mismatch, such as "float64" and
+	// "string".
+	Expected string
+	Got      string
+	// MediaType is the rejected media type.
+	MediaType string
+	// Limit is the size limit of an oversized body.
+	Limit int64
+	Err   error
+}
+
+func (e *JSONError) Error() string {
+	switch e.Kind {
+	case JSONMissingBody:
+		return "missing request body"
+	case JSONUnsupportedMediaType:
+		return fmt.Sprintf("unsupported media type %q, expected application/json", e.MediaType)
+	case JSONSyntax:
+		return fmt.Sprintf("invalid JSON at offset %d: %v", e.Offset, e.Err)
+	case JSONTypeMismatch:
+		if e.Field == "" {
+			return fmt.Sprintf("invalid JSON: expected %s, got %s", e.Expected, e.Got)
+		}
+		return fmt.Sprintf("invalid JSON: field %q expects %s, got %s", e.Field, e.Expected, e.Got)
+	case JSONUnknownField:
+		return fmt.Sprintf("invalid JSON: unknown field %q", e.Field)
+	case JSONTrailingData:
+		return "invalid JSON: unexpected data after the top-level value"
+	case JSONTooLarge:
+		return fmt.Sprintf("request body exceeds %d bytes", e.Limit)
+	}
+	return fmt.Sprintf("invalid JSON: %v", e.Err)
+}
+
+func (e *JSONError) Unwrap() error {
+	return e.Err
+}
+
+// Status returns the HTTP status of the error: 415 for an unsupported media
+// type, 413 for an oversized body and 400 otherwise.
+func (e *JSONError) Status() int {
+	switch e.Kind {
+	case JSONUnsupportedMediaType:
+		return http.StatusUnsupportedMediaType
+	case JSONTooLarge:
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
+
+type JSONOption func(*JSONDecoder)
+
+// WithDisallowUnknownFields rejects objects with fields that the payload
+// does not have.
+func WithDisallowUnknownFields() JSONOption {
+	return func(d *JSONDecoder) {
+		d.disallowUnknownFields = true
+	}
+}
+
+// WithMaxBodyBytes rejects bodies larger than n bytes.
+func WithMaxBodyBytes(n int64) JSONOption {
+	return func(d *JSONDecoder) {
+		d.maxBodyBytes = n
+	}
+}
+
+// WithRequireContentType rejects requests whose Content-Type is not
+// application/json or a +json type such as application/merge-patch+json.
+func WithRequireContentType() JSONOption {
+	return func(d *JSONDecoder) {
+		d.requireContentType = true
+	}
+}
+
+// WithDisallowTrailingData rejects bodies with more than one top-level
+// value.
+func WithDisallowTrailingData() JSONOption {
+	return func(d *JSONDecoder) {
+		d.disallowTrailingData = true
+	}
+}
+
+// WithUseNumber decodes numbers into interface values as json.Number
+// instead of float64.
+func WithUseNumber() JSONOption {
+	return func(d *JSONDecoder) {
+		d.useNumber = true
+	}
+}
+
+// JSONDecoder decodes JSON request bodies. The zero options accept any
+// Content-Type, unknown fields, trailing data and bodies of any size.
+type JSONDecoder struct {
+	disallowUnknownFields bool
+	maxBodyBytes          int64
+	requireContentType    bool
+	disallowTrailingData  bool
+	useNumber             bool
+}
+
+func NewJSONDecoder(opts ...JSONOption) *JSONDecoder {
+	d := &JSONDecoder{}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
+
+// StrictJSONDecoder returns a decoder that requires application/json and
+// rejects unknown fields and trailing data, with maxBodyBytes as size limit.
+func StrictJSONDecoder(maxBodyBytes int64) *JSONDecoder {
+	return NewJSONDecoder(
+		WithRequireContentType(),
+		WithDisallowUnknownFields(),
+		WithDisallowTrailingData(),
+		WithMaxBodyBytes(maxBodyBytes),
+	)
+}
+
+// Decode decodes the body of r into payload and closes it. Errors are
+// *JSONError.
+func (d *JSONDecoder) Decode(r *http.Request, payload any) error {
+	if d.requireContentType {
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if mediaType != "application/json" && !(strings.HasPrefix(mediaType, "application/") && strings.HasSuffix(mediaType, "+json")) {
+			return &JSONError{Kind: JSONUnsupportedMediaType, MediaType: mediaType}
+		}
+	}
+	if r.Body == nil {
+		return &JSONError{Kind: JSONMissingBody}
+	}
+
+	defer r.Body.Close() // Always close the body to avoid resource leaks
+	body := r.Body
+	if d.maxBodyBytes > 0 {
+		body = http.MaxBytesReader(nil, body, d.maxBodyBytes)
+	}
+
+	counter := &countingReader{r: body}
+	dec := json.NewDecoder(counter)
+	if d.disallowUnknownFields {
+		dec.DisallowUnknownFields()
+	}
+	if d.useNumber {
+		dec.UseNumber()
+	}
+
+	if err := dec.Decode(payload); err != nil {
+		if errors.Is(err, io.EOF) {
+			return &JSONError{Kind: JSONMissingBody}
+		}
+		return decodeError(err, counter.n)
+	}
+
+	if d.disallowTrailingData {
+		if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				return &JSONError{Kind: JSONTooLarge, Limit: maxBytesErr.Limit, Err: err}
+			}
+			return &JSONError{Kind: JSONTrailingData, Offset: dec.InputOffset(), Err: err}
+		}
+	}
+	return nil
+}
+
+// decodeError converts an error of json.Decoder.Decode. read is the number of
+// bytes read, which is the offset of a truncated body.
+func decodeError(err error, read int64) *JSONError {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &syntaxErr):
+		return &JSONError{Kind: JSONSyntax, Offset: syntaxErr.Offset, Err: err}
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return &JSONError{Kind: JSONSyntax, Offset: read, Err: err}
+	case errors.As(err, &typeErr):
+		return &JSONError{
+			Kind:     JSONTypeMismatch,
+			Offset:   typeErr.Offset,
+			Field:    typeErr.Field,
+			Expected: typeErr.Type.String(),
+			Got:      typeErr.Value,
+			Err:      err,
+		}
+	case errors.As(err, &maxBytesErr):
+		return &JSONError{Kind: JSONTooLarge, Limit: maxBytesErr.Limit, Err: err}
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		// encoding/json has no type for this error.
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		return &JSONError{Kind: JSONUnknownField, Field: field, Err: err}
+	}
+	return &JSONError{Err: err}
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,154 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type orderPayload struct {
+	Name  string `json:"name"`
+	Items []struct {
+		Price float64 `json:"price"`
+	} `json:"items"`
+}
+
+func TestJSONDecoder(t *testing.T) {
+	newRequest := func(body, contentType string) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		if contentType != "" {
+			r.Header.Set("Content-Type", contentType)
+		}
+		return r
+	}
+
+	for _, tc := range []struct {
+		name        string
+		decoder     *JSONDecoder
+		body        string
+		contentType string
+		want        *JSONError
+		message     string
+		status      int
+	}{
+		{
+			name:    "empty body",
+			decoder: NewJSONDecoder(),
+			want:    &JSONError{Kind: JSONMissingBody},
+			message: "missing request body",
+			status:  http.StatusBadRequest,
+		},
+		{
+			name:    "syntax error",
+			decoder: NewJSONDecoder(),
+			body:    `{"name" "Widget"}`,
+			want:    &JSONError{Kind: JSONSyntax, Offset: 9},
+			message: "invalid JSON at offset 9: invalid character '\"' after object key",
+			status:  http.StatusBadRequest,
+		},
+		{
+			name:    "truncated body",
+			decoder: NewJSONDecoder(),
+			body:    `{"name":`,
+			want:    &JSONError{Kind: JSONSyntax, Offset: 8},
+			message: "invalid JSON at offset 8: unexpected EOF",
+			status:  http.StatusBadRequest,
+		},
+		{
+			name:    "type mismatch",
+			decoder: NewJSONDecoder(),
+			body:    `{"items": [{"price": "cheap"}]}`,
+			want:    &JSONError{Kind: JSONTypeMismatch, Offset: 28, Field: "items.0.price", Expected: "float64", Got: "string"},
+			message: `invalid JSON: field "items.0.price" expects float64, got string`,
+			status:  http.StatusBadRequest,
+		},
+		{
+			name:    "unknown field",
+			decoder: NewJSONDecoder(WithDisallowUnknownFields()),
+			body:    `{"name": "Widget", "colour": "red"}`,
+			want:    &JSONError{Kind: JSONUnknownField, Field: "colour"},
+			message: `invalid JSON: unknown field "colour"`,
+			status:  http.StatusBadRequest,
+		},
+		{
+			name:    "trailing data",
+			decoder: NewJSONDecoder(WithDisallowTrailingData()),
+			body:    `{"name": "Widget"} {"name": "Gadget"}`,
+			want:    &JSONError{Kind: JSONTrailingData, Offset: 20},
+			message: "invalid JSON: unexpected data after the top-level value",
+			status:  http.StatusBadRequest,
+		},
+		{
+			name:    "oversized body",
+			decoder: NewJSONDecoder(WithMaxBodyBytes(10)),
+			body:    `{"name": "Widget"}`,
+			want:    &JSONError{Kind: JSONTooLarge, Limit: 10},
+			message: "request body exceeds 10 bytes",
+			status:  http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:    "missing content type",
+			decoder: NewJSONDecoder(WithRequireContentType()),
+			body:    `{"name": "Widget"}`,
+			want:    &JSONError{Kind: JSONUnsupportedMediaType},
+			message: `unsupported media type "", expected application/json`,
+			status:  http.StatusUnsupportedMediaType,
+		},
+		{
+			name:        "wrong content type",
+			decoder:     StrictJSONDecoder(1 << 10),
+			body:        `{"name": "Widget"}`,
+			contentType: "text/plain",
+			want:        &JSONError{Kind: JSONUnsupportedMediaType, MediaType: "text/plain"},
+			message:     `unsupported media type "text/plain", expected application/json`,
+			status:      http.StatusUnsupportedMediaType,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var payload orderPayload
+			err := tc.decoder.Decode(newRequest(tc.body, tc.contentType), &payload)
+
+			var jsonErr *JSONError
+			require.ErrorAs(t, err, &jsonErr)
+			assert.EqualError(t, err, tc.message)
+			jsonErr.Err = nil
+			assert.Equal(t, tc.want, jsonErr)
+			assert.Equal(t, tc.status, jsonErr.Status())
+			assert.Equal(t, tc.status, ProblemFromError(err).Status)
+		})
+	}
+
+	t.Run("should accept strict requests", func(t *testing.T) {
+		var payload orderPayload
+		err := StrictJSONDecoder(1<<10).Decode(newRequest(`{"name": "Widget", "items": [{"price": 2.5}]}`+"\n", "application/json; charset=utf-8"), &payload)
+		assert.NoError(t, err)
+		assert.Equal(t, "Widget", payload.Name)
+		assert.Equal(t, 2.5, payload.Items[0].Price)
+
+		err = StrictJSONDecoder(1<<10).Decode(newRequest(`{"name": "Widget"}`, "application/merge-patch+json"), &payload)
+		assert.NoError(t, err)
+	})
+
+	t.Run("should use numbers", func(t *testing.T) {
+		var payload map[string]any
+		err := ParseJSON(newRequest(`{"id": 9007199254740993}`, ""), &payload, WithUseNumber())
+		assert.NoError(t, err)
+		assert.Equal(t, json.Number("9007199254740993"), payload["id"])
+	})
+
+	t.Run("should report oversized bodies capped by the server", func(t *testing.T) {
+		r := newRequest(`{"name": "Widget"}`, "")
+		r.Body = http.MaxBytesReader(httptest.NewRecorder(), r.Body, 4)
+
+		err := ParseJSON(r, &orderPayload{})
+		var maxBytesErr *http.MaxBytesError
+		assert.True(t, errors.As(err, &maxBytesErr))
+		assert.EqualError(t, err, "request body exceeds 4 bytes")
+	})
+}
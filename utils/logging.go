@@ -0,0 +1,101 @@
+package utils
+
+import (
+	"context"
+	"io"
+	"log"
+	"log/slog"
+)
+
+type LogFormat string
+
+const (
+	LogFormatJSON LogFormat = "json"
+	LogFormatText LogFormat = "text"
+)
+
+// NewLogger returns a leveled logger writing records to w in the given
+// format. Request-scoped attributes stored in the context, such as the
+// request ID, are added to every record logged with a context.
+func NewLogger(w io.Writer, format LogFormat, level slog.Leveler) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: level}
+
+	var handler slog.Handler
+	if format == LogFormatText {
+		handler = slog.NewTextHandler(w, opts)
+	} else {
+		handler = slog.NewJSONHandler(w, opts)
+	}
+	return slog.New(NewContextHandler(handler))
+}
+
+// FromStdLogger adapts a *log.Logger for APIs that take a *slog.Logger.
+// Records are written as text through logger, which keeps its own prefix
+// and timestamp flags.
+func FromStdLogger(logger *log.Logger) *slog.Logger {
+	handler := slog.NewTextHandler(stdLogWriter{logger}, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey && len(groups) == 0 {
+				return slog.Attr{}
+			}
+			return a
+		},
+	})
+	return slog.New(NewContextHandler(handler))
+}
+
+type stdLogWriter struct {
+	logger *log.Logger
+}
+
+func (w stdLogWriter) Write(p []byte) (int, error) {
+	if err := w.logger.Output(2, string(p)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+type logAttrsKey struct{}
+
+// WithLogAttrs returns a copy of ctx carrying attrs, which ContextHandler adds
+// to every record logged with the returned context.
+func WithLogAttrs(ctx context.Context, attrs ...slog.Attr) context.Context {
+	existing := LogAttrsFromContext(ctx)
+	merged := make([]slog.Attr, 0, len(existing)+len(attrs))
+	merged = append(merged, existing...)
+	merged = append(merged, attrs...)
+	return context.WithValue(ctx, logAttrsKey{}, merged)
+}
+
+func LogAttrsFromContext(ctx context.Context) []slog.Attr {
+	attrs, _ := ctx.Value(logAttrsKey{}).([]slog.Attr)
+	return attrs
+}
+
+// ContextHandler is a slog.Handler that adds the request ID and the
+// attributes stored with WithLogAttrs to each record before passing it on.
+type ContextHandler struct {
+	slog.Handler
+}
+
+func NewContextHandler(handler slog.Handler) *ContextHandler {
+	return &ContextHandler{Handler: handler}
+}
+
+func (h *ContextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if ctx != nil {
+		if requestID := RequestIDFromContext(ctx); requestID != "" {
+			r.AddAttrs(slog.String("requestId", requestID))
+		}
+		r.AddAttrs(LogAttrsFromContext(ctx)...)
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *ContextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &ContextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *ContextHandler) WithGroup(name string) slog.Handler {
+	return &ContextHandler{Handler: h.Handler.WithGroup(name)}
+}
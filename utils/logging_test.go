@@ -0,0 +1,73 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewLogger(t *testing.T) {
+	t.Run("should write JSON records", func(t *testing.T) {
+		var logBuffer bytes.Buffer
+		logger := NewLogger(&logBuffer, LogFormatJSON, slog.LevelInfo)
+
+		logger.Info("started", "port", 8080)
+
+		assert.JSONEq(t, `{"level": "INFO", "msg": "started", "port": 8080}`, withoutTime(t, logBuffer.Bytes()))
+	})
+
+	t.Run("should write text records", func(t *testing.T) {
+		var logBuffer bytes.Buffer
+		logger := NewLogger(&logBuffer, LogFormatText, slog.LevelInfo)
+
+		logger.Info("started", "port", 8080)
+
+		assert.Contains(t, logBuffer.String(), "level=INFO msg=started port=8080")
+	})
+
+	t.Run("should drop records below the level", func(t *testing.T) {
+		var logBuffer bytes.Buffer
+		level := new(slog.LevelVar)
+		level.Set(slog.LevelWarn)
+		logger := NewLogger(&logBuffer, LogFormatJSON, level)
+
+		logger.Info("hidden")
+		assert.Empty(t, logBuffer.String())
+
+		level.Set(slog.LevelDebug)
+		logger.Debug("shown")
+		assert.Contains(t, logBuffer.String(), `"msg":"shown"`)
+	})
+
+	t.Run("should add request attributes from the context", func(t *testing.T) {
+		var logBuffer bytes.Buffer
+		logger := NewLogger(&logBuffer, LogFormatJSON, slog.LevelInfo).With("service", "products")
+
+		ctx := WithRequestID(context.Background(), "req-1")
+		ctx = WithLogAttrs(ctx, slog.String("route", "/products/{id}"))
+		ctx = WithLogAttrs(ctx, slog.String("method", "GET"))
+		logger.InfoContext(ctx, "handled")
+
+		assert.JSONEq(t, `{
+			"level": "INFO",
+			"msg": "handled",
+			"service": "products",
+			"requestId": "req-1",
+			"route": "/products/{id}",
+			"method": "GET"
+		}`, withoutTime(t, logBuffer.Bytes()))
+	})
+}
+
+func TestFromStdLogger(t *testing.T) {
+	var logBuffer bytes.Buffer
+	logger := FromStdLogger(log.New(&logBuffer, "TEST: ", 0))
+
+	logger.InfoContext(WithRequestID(context.Background(), "req-1"), "Route registered", "path", "/products")
+
+	assert.Equal(t, "TEST: level=INFO msg=\"Route registered\" path=/products requestId=req-1\n", logBuffer.String())
+}
@@ -0,0 +1,280 @@
+package utils
+
+import (
+	"bufio"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// ProblemContentType is the media type of RFC 7807 problem details.
+const ProblemContentType = "application/problem+json"
+
+// Problem is an RFC 7807 problem details object. Extensions are written as
+// additional members next to the standard ones.
+type Problem struct {
+	// Type is a URI identifying the problem type. It defaults to
+	// "about:blank", in which case Title is the HTTP status text.
+	Type       string
+	Title      string
+	Status     int
+	Detail     string
+	Instance   string
+	Extensions map[string]any
+}
+
+func (p *Problem) MarshalJSON() ([]byte, error) {
+	members := make(map[string]any, len(p.Extensions)+5)
+	for k, v := range p.Extensions {
+		members[k] = v
+	}
+
+	members["type"] = p.Type
+	if p.Type == "" {
+		members["type"] = "about:blank"
+	}
+	members["title"] = p.Title
+	if p.Title == "" {
+		members["title"] = http.StatusText(p.Status)
+	}
+	members["status"] = p.Status
+	if p.Detail != "" {
+		members["detail"] = p.Detail
+	}
+	if p.Instance != "" {
+		members["instance"] = p.Instance
+	}
+	return json.Marshal(members)
+}
+
+// APIError is an error that handlers can return to control the response.
+type APIError struct {
+	Status     int
+	Type       string
+	Title      string
+	Detail     string
+	Extensions map[string]any
+	// Err is the underlying error. It is not exposed to clients.
+	Err error
+}
+
+func NewAPIError(status int, detail string) *APIError {
+	return &APIError{Status: status, Detail: detail}
+}
+
+func (e *APIError) Error() string {
+	if e.Detail != "" {
+		return e.Detail
+	}
+	if e.Title != "" {
+		return e.Title
+	}
+	return http.StatusText(e.Status)
+}
+
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+func (e *APIError) Problem() *Problem {
+	return &Problem{
+		Type:       e.Type,
+		Title:      e.Title,
+		Status:     e.Status,
+		Detail:     e.Detail,
+		Extensions: e.Extensions,
+	}
+}
+
+var (
+	errorMappersMu sync.RWMutex
+	errorMappers   []func(err error) *Problem
+)
+
+// RegisterErrorMapper adds a mapper that ProblemFromError consults before
+// the built-in mappings. It returns nil for errors it does not handle.
+func RegisterErrorMapper(mapper func(err error) *Problem) {
+	errorMappersMu.Lock()
+	defer errorMappersMu.Unlock()
+	errorMappers = append(errorMappers, mapper)
+}
+
+// ProblemFromError maps err to a problem. Unknown errors are 500s whose
+// details are not exposed.
+func ProblemFromError(err error) *Problem {
+	errorMappersMu.RLock()
+	mappers := errorMappers
+	errorMappersMu.RUnlock()
+	for _, mapper := range mappers {
+		if p := mapper(err); p != nil {
+			return p
+		}
+	}
+
+	var apiErr *APIError
+	var validationErrs validator.ValidationErrors
+	var bindErrs BindErrors
+	var jsonErr *JSONError
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &apiErr):
+		return apiErr.Problem()
+	case errors.As(err, &validationErrs):
+		return &Problem{
+			Status:     http.StatusBadRequest,
+			Detail:     "Validation Error",
+			Extensions: map[string]any{"errors": GetValidationError(validationErrs)},
+		}
+	case errors.As(err, &bindErrs):
+		return &Problem{
+			Status:     http.StatusBadRequest,
+			Detail:     "Validation Error",
+			Extensions: map[string]any{"errors": GetValidationError(bindErrs)},
+		}
+	case errors.As(err, &jsonErr):
+		return &Problem{Status: jsonErr.Status(), Detail: jsonErr.Error()}
+	case errors.Is(err, sql.ErrNoRows):
+		return &Problem{Status: http.StatusNotFound}
+	case errors.As(err, &maxBytesErr):
+		return &Problem{Status: http.StatusRequestEntityTooLarge}
+	default:
+		return &Problem{Status: http.StatusInternalServerError}
+	}
+}
+
+// WriteProblem writes p as application/problem+json.
+func WriteProblem(w http.ResponseWriter, p *Problem) {
+	if trans := TranslatorOf(w); trans != nil {
+		copied := *p
+		if copied.Title == "" && copied.Type == "" {
+			copied.Title = translate(trans, http.StatusText(p.Status))
+		}
+		copied.Detail = translate(trans, p.Detail)
+		p = &copied
+	}
+	if f := errorFormatWriterOf(w); f != nil && p.Instance == "" {
+		copied := *p
+		copied.Instance = f.instance
+		p = &copied
+	}
+	if requestID := w.Header().Get(RequestIDHeader); requestID != "" {
+		copied := *p
+		copied.Extensions = make(map[string]any, len(p.Extensions)+1)
+		for k, v := range p.Extensions {
+			copied.Extensions[k] = v
+		}
+		copied.Extensions["requestId"] = requestID
+		p = &copied
+	}
+
+	body, err := json.Marshal(p)
+	if err != nil {
+		p = &Problem{Status: http.StatusInternalServerError, Instance: p.Instance}
+		body, _ = json.Marshal(p)
+	}
+
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(p.Status)
+	w.Write(body)
+}
+
+// WriteError maps err with ProblemFromError and writes it in the error
+// format and language of w.
+func WriteError(w http.ResponseWriter, err error) {
+	p := ProblemFromError(err)
+	if trans := TranslatorOf(w); trans != nil && (errors.As(err, new(validator.ValidationErrors)) || errors.As(err, new(BindErrors))) {
+		if _, ok := p.Extensions["errors"]; ok {
+			copied := *p
+			copied.Extensions = map[string]any{"errors": GetLocalizedValidationError(err, trans)}
+			p = &copied
+		}
+	}
+	if ErrorFormatOf(w) == ErrorFormatProblem {
+		WriteProblem(w, p)
+		return
+	}
+
+	message := p.Detail
+	if message == "" {
+		message = p.Title
+	}
+	if message == "" {
+		message = http.StatusText(p.Status)
+	}
+	// Field errors are written as the details, as handlers write them.
+	var details any
+	if errs, ok := p.Extensions["errors"]; ok {
+		details = errs
+	} else if len(p.Extensions) > 0 {
+		details = p.Extensions
+	}
+	WriteErrorJSON(w, p.Status, errors.New(message), details)
+}
+
+// ErrorFormat selects how error responses are written.
+type ErrorFormat int
+
+const (
+	// ErrorFormatLegacy writes {"error": ..., "details": ...} bodies.
+	ErrorFormatLegacy ErrorFormat = iota
+	// ErrorFormatProblem writes RFC 7807 application/problem+json bodies.
+	ErrorFormatProblem
+)
+
+// errorFormatWriter carries the error format of a response.
+type errorFormatWriter struct {
+	http.ResponseWriter
+	format   ErrorFormat
+	instance string
+}
+
+// WithErrorFormat returns a writer whose error responses are written in
+// format.
+func WithErrorFormat(w http.ResponseWriter, r *http.Request, format ErrorFormat) http.ResponseWriter {
+	return &errorFormatWriter{ResponseWriter: w, format: format, instance: r.URL.Path}
+}
+
+func (w *errorFormatWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (w *errorFormatWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
+
+func (w *errorFormatWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// ErrorFormatOf returns the error format of w, which is legacy unless the
+// writer was set up by WithErrorFormat.
+func ErrorFormatOf(w http.ResponseWriter) ErrorFormat {
+	if f := errorFormatWriterOf(w); f != nil {
+		return f.format
+	}
+	return ErrorFormatLegacy
+}
+
+// errorFormatWriterOf finds the errorFormatWriter under w. Middleware that
+// wraps the writer must implement Unwrap() http.ResponseWriter for it to be
+// found, as for http.ResponseController.
+func errorFormatWriterOf(w http.ResponseWriter) *errorFormatWriter {
+	for {
+		if f, ok := w.(*errorFormatWriter); ok {
+			return f
+		}
+		unwrapper, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return nil
+		}
+		w = unwrapper.Unwrap()
+	}
+}
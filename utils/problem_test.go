@@ -0,0 +1,192 @@
+package utils
+
+import (
+	"bufio"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProblemFromError(t *testing.T) {
+	validationErr := Validate.Struct(TestPayload{Name: "Jo", Email: "alice.doe@example.com"})
+
+	for _, tc := range []struct {
+		name string
+		err  error
+		want *Problem
+	}{
+		{
+			name: "api error",
+			err:  fmt.Errorf("wrapped: %w", &APIError{Status: http.StatusConflict, Type: "https://example.com/conflict", Detail: "name taken", Err: errors.New("duplicate key")}),
+			want: &Problem{Status: http.StatusConflict, Type: "https://example.com/conflict", Detail: "name taken"},
+		},
+		{
+			name: "validation errors",
+			err:  validationErr,
+			want: &Problem{Status: http.StatusBadRequest, Detail: "Validation Error", Extensions: map[string]any{"errors": GetValidationError(validationErr)}},
+		},
+		{
+			name: "no rows",
+			err:  fmt.Errorf("get product: %w", sql.ErrNoRows),
+			want: &Problem{Status: http.StatusNotFound},
+		},
+		{
+			name: "oversized body",
+			err:  &http.MaxBytesError{Limit: 10},
+			want: &Problem{Status: http.StatusRequestEntityTooLarge},
+		},
+		{
+			name: "other errors",
+			err:  errors.New("connection refused"),
+			want: &Problem{Status: http.StatusInternalServerError},
+		},
+	} {
+		assert.Equal(t, tc.want, ProblemFromError(tc.err), tc.name)
+	}
+}
+
+func TestRegisterErrorMapper(t *testing.T) {
+	errTeapot := errors.New("teapot")
+	RegisterErrorMapper(func(err error) *Problem {
+		if errors.Is(err, errTeapot) {
+			return &Problem{Status: http.StatusTeapot}
+		}
+		return nil
+	})
+	t.Cleanup(func() { errorMappers = nil })
+
+	assert.Equal(t, http.StatusTeapot, ProblemFromError(errTeapot).Status)
+	assert.Equal(t, http.StatusNotFound, ProblemFromError(sql.ErrNoRows).Status)
+}
+
+func TestAPIError(t *testing.T) {
+	cause := errors.New("duplicate key")
+	err := &APIError{Status: http.StatusConflict, Err: cause}
+
+	assert.Equal(t, "Conflict", err.Error())
+	assert.ErrorIs(t, err, cause)
+	err.Title = "Duplicate"
+	assert.Equal(t, "Duplicate", err.Error())
+	assert.Equal(t, "name taken", NewAPIError(http.StatusConflict, "name taken").Error())
+}
+
+func TestWriteProblem(t *testing.T) {
+	t.Run("should write standard members and extensions", func(t *testing.T) {
+		rw := httptest.NewRecorder()
+		WriteProblem(rw, &Problem{
+			Type:       "https://example.com/out-of-stock",
+			Title:      "Out of stock",
+			Status:     http.StatusConflict,
+			Detail:     "Only 2 left",
+			Instance:   "/orders/1",
+			Extensions: map[string]any{"available": 2, "status": "ignored"},
+		})
+
+		assert.Equal(t, http.StatusConflict, rw.Code)
+		assert.Equal(t, ProblemContentType, rw.Header().Get("Content-Type"))
+		assert.JSONEq(t, `{
+			"type": "https://example.com/out-of-stock",
+			"title": "Out of stock",
+			"status": 409,
+			"detail": "Only 2 left",
+			"instance": "/orders/1",
+			"available": 2
+		}`, rw.Body.String())
+	})
+
+	t.Run("should fall back when extensions cannot be marshaled", func(t *testing.T) {
+		rw := httptest.NewRecorder()
+		WriteProblem(rw, &Problem{Status: http.StatusBadRequest, Extensions: map[string]any{"fn": func() {}}})
+
+		assert.Equal(t, http.StatusInternalServerError, rw.Code)
+		assert.JSONEq(t, `{"type":"about:blank","title":"Internal Server Error","status":500}`, rw.Body.String())
+	})
+}
+
+func TestWriteError(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/products/1", nil)
+	err := &APIError{Status: http.StatusConflict, Detail: "name taken", Extensions: map[string]any{"field": "name"}}
+
+	t.Run("should write legacy errors", func(t *testing.T) {
+		rw := httptest.NewRecorder()
+		WriteError(rw, err)
+
+		assert.Equal(t, http.StatusConflict, rw.Code)
+		assert.JSONEq(t, `{"error":"name taken","details":{"field":"name"}}`, rw.Body.String())
+
+		rw = httptest.NewRecorder()
+		WriteError(rw, errors.New("secret connection string"))
+		assert.JSONEq(t, `{"error":"Internal Server Error"}`, rw.Body.String())
+	})
+
+	t.Run("should write field errors as legacy details", func(t *testing.T) {
+		rw := httptest.NewRecorder()
+		WriteError(rw, BindErrors{{Field: "ID", Source: "path", Name: "id", Value: "abc", Err: errors.New("not a number")}})
+
+		assert.Equal(t, http.StatusBadRequest, rw.Code)
+		assert.JSONEq(t, `{"error":"Validation Error","details":[
+			{"code":"type","field":"id","message":"invalid path parameter 'id': not a number"}
+		]}`, rw.Body.String())
+	})
+
+	t.Run("should write problems", func(t *testing.T) {
+		rw := httptest.NewRecorder()
+		WriteError(WithErrorFormat(rw, req, ErrorFormatProblem), err)
+
+		assert.Equal(t, http.StatusConflict, rw.Code)
+		assert.JSONEq(t, `{"type":"about:blank","title":"Conflict","status":409,"detail":"name taken","instance":"/products/1","field":"name"}`, rw.Body.String())
+	})
+
+	t.Run("should write legacy calls as problems", func(t *testing.T) {
+		rw := httptest.NewRecorder()
+		WriteBadRequest(WithErrorFormat(rw, req, ErrorFormatProblem), "Validation Error", map[string]string{"name": "'name' is required"})
+
+		assert.Equal(t, ProblemContentType, rw.Header().Get("Content-Type"))
+		assert.JSONEq(t, `{"type":"about:blank","title":"Bad Request","status":400,"detail":"Validation Error","instance":"/products/1","details":{"name":"'name' is required"}}`, rw.Body.String())
+	})
+
+	t.Run("should leave out details repeating the title", func(t *testing.T) {
+		rw := httptest.NewRecorder()
+		WriteNotFound(WithErrorFormat(rw, req, ErrorFormatProblem), "", nil)
+
+		assert.JSONEq(t, `{"type":"about:blank","title":"Not Found","status":404,"instance":"/products/1"}`, rw.Body.String())
+	})
+}
+
+type hijackRecorder struct {
+	*httptest.ResponseRecorder
+	hijacked bool
+}
+
+func (r *hijackRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	r.hijacked = true
+	return nil, nil, nil
+}
+
+func TestErrorFormatWriter(t *testing.T) {
+	t.Run("should leave the query out of the instance", func(t *testing.T) {
+		rw := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/products/1?api_key=secret", nil)
+		WriteNotFound(WithErrorFormat(rw, req, ErrorFormatProblem), "", nil)
+
+		assert.JSONEq(t, `{"type":"about:blank","title":"Not Found","status":404,"instance":"/products/1"}`, rw.Body.String())
+	})
+
+	t.Run("should forward hijacking", func(t *testing.T) {
+		rw := &hijackRecorder{ResponseRecorder: httptest.NewRecorder()}
+		req := httptest.NewRequest(http.MethodGet, "/products/1", nil)
+		w := WithTranslator(WithErrorFormat(rw, req, ErrorFormatProblem), nil)
+
+		hijacker, ok := w.(http.Hijacker)
+		assert.True(t, ok)
+		_, _, err := hijacker.Hijack()
+		assert.NoError(t, err)
+		assert.True(t, rw.hijacked)
+	})
+}
@@ -1,54 +1,58 @@
 package utils
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
-	"fmt"
-	"io"
-	"log"
+	"log/slog"
 	"net/http"
-
-	"github.com/go-playground/validator/v10"
 )
 
-var Validate = validator.New()
-
-func GetValidationError(err error) map[string]string {
-	validationErrors := make(map[string]string)
-	for _, err := range err.(validator.ValidationErrors) {
-		validationErrors[err.Field()] = getValidationMessage(err)
-	}
-	return validationErrors
+// ParseJSON decodes the JSON body of r into payload with a JSONDecoder
+// configured by opts. Errors are *JSONError.
+func ParseJSON(r *http.Request, payload any, opts ...JSONOption) error {
+	return NewJSONDecoder(opts...).Decode(r, payload)
 }
 
-func ParseJSON(r *http.Request, payload any) error {
-	if r.Body == nil {
-		return fmt.Errorf("missing request body")
-	}
-
-	defer r.Body.Close() // Always close the body to avoid resource leaks
-	if err := json.NewDecoder(r.Body).Decode(payload); err != nil {
-		if errors.Is(err, io.EOF) {
-			return fmt.Errorf("missing request body")
+// WriteErrorJSON writes err and details as a JSON error response. If the
+// writer was set up with ErrorFormatProblem, they are written as an RFC 7807
+// problem whose detail is err and whose "details" member holds details.
+func WriteErrorJSON(w http.ResponseWriter, status int, err error, details any) {
+	if ErrorFormatOf(w) == ErrorFormatProblem {
+		p := &Problem{Status: status}
+		// The title already gives the status text.
+		if message := err.Error(); message != http.StatusText(status) {
+			p.Detail = message
 		}
-		return fmt.Errorf("invalid JSON: %w", err)
+		if details != nil {
+			p.Extensions = map[string]any{"details": details}
+		}
+		WriteProblem(w, p)
+		return
 	}
 
-	return nil
-}
+	errorResponse := map[string]any{
+		"error": translate(TranslatorOf(w), err.Error()),
+	}
+	if details != nil {
+		errorResponse["details"] = details
+	}
+	if requestID := w.Header().Get(RequestIDHeader); requestID != "" {
+		errorResponse["requestId"] = requestID
+	}
 
-func WriteErrorJSON(w http.ResponseWriter, status int, err error, details any) {
 	// Marshal the response to JSON
-	responseJSON, marshalErr := createJsonMessage("error", err.Error(), "details", details)
+	responseJSON, marshalErr := json.Marshal(errorResponse)
 	if marshalErr != nil {
-		// Fallback to http.Error if JSON marshaling fails
-		http.Error(w, `{"error": "internal server error"}`, http.StatusInternalServerError)
-		return
+		// Fall back to a fixed body if JSON marshaling fails
+		status = http.StatusInternalServerError
+		responseJSON = []byte(`{"error": "internal server error"}`)
 	}
 
-	// Use http.Error to send the response as JSON
 	w.Header().Set("Content-Type", "application/json")
-	http.Error(w, string(responseJSON), status)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	w.Write(responseJSON)
 }
 
 func WriteBadRequest(w http.ResponseWriter, errorMessage string, details any) {
@@ -78,46 +82,25 @@ func WriteNotFound(w http.ResponseWriter, errorMessage string, details any) {
 	writeError(w, http.StatusNotFound, errorMessage, details)
 }
 
-func WriteLog(logger *log.Logger, category string, details any) {
-	log, marshalErr := createJsonMessage("category", category, "message", details)
-	if marshalErr != nil {
-		logger.Printf("log error: %v", marshalErr)
-		return
-	}
-
-	logger.Println(log)
+// WriteLog logs details at info level under category.
+func WriteLog(logger *slog.Logger, category string, details any) {
+	WriteLogLevel(context.Background(), logger, slog.LevelInfo, category, details)
 }
 
-func createJsonMessage(messageKey string, message string, detailsKey string, details any) ([]byte, error) {
-	errorResponse := map[string]interface{}{
-		messageKey: message,
-	}
-
-	if details != nil {
-		errorResponse[detailsKey] = details
-	}
-
-	// Marshal the response to JSON
-	msg, marshalErr := json.Marshal(errorResponse)
-	if marshalErr != nil {
-		return nil, marshalErr
-	}
-
-	return msg, nil
+// WriteLogContext is like WriteLog but passes ctx to the logger, so
+// request-scoped attributes such as the request ID are recorded.
+func WriteLogContext(ctx context.Context, logger *slog.Logger, category string, details any) {
+	WriteLogLevel(ctx, logger, slog.LevelInfo, category, details)
 }
 
-func getValidationMessage(fe validator.FieldError) string {
-	switch fe.Tag() {
-	case "required":
-		return fmt.Sprintf("'%s' is required", fe.Field())
-	case "email":
-		return fmt.Sprintf("'%s' is invalid", fe.Field())
-	case "min":
-		return fmt.Sprintf("'%s' is less than the required minimum length", fe.Field())
-	case "max":
-		return fmt.Sprintf("'%s' is greater than the required maximum length", fe.Field())
+// WriteLogLevel logs details under category at the given level, using
+// category as the message.
+func WriteLogLevel(ctx context.Context, logger *slog.Logger, level slog.Level, category string, details any) {
+	if details == nil {
+		logger.LogAttrs(ctx, level, category)
+		return
 	}
-	return fmt.Sprintf("%v", fe.Error())
+	logger.LogAttrs(ctx, level, category, slog.Any("details", details))
 }
 
 func writeError(w http.ResponseWriter, httpErrorStatusCode int, errorMessage string, details any) {
@@ -1,7 +1,11 @@
 package utils
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -61,7 +65,18 @@ func TestWriteErrorJSON(t *testing.T) {
 		WriteErrorJSON(rw, http.StatusBadRequest, err, details)
 
 		assert.Equal(t, http.StatusBadRequest, rw.Code)
-		assert.JSONEq(t, `{"error": "test error", "details": {"field": "error detail"}}`, rw.Body.String())
+		assert.Equal(t, "application/json", rw.Header().Get("Content-Type"))
+		assert.Equal(t, `{"details":{"field":"error detail"},"error":"test error"}`, rw.Body.String())
+	})
+
+	t.Run("Request ID", func(t *testing.T) {
+		rw := httptest.NewRecorder()
+		rw.Header().Set(RequestIDHeader, "req-1")
+
+		WriteErrorJSON(rw, http.StatusNotFound, errors.New("not found"), nil)
+
+		assert.Equal(t, http.StatusNotFound, rw.Code)
+		assert.JSONEq(t, `{"error": "not found", "requestId": "req-1"}`, rw.Body.String())
 	})
 
 	t.Run("JSON Marshal Error", func(t *testing.T) {
@@ -88,56 +103,60 @@ func TestWriteJSON(t *testing.T) {
 	assert.JSONEq(t, `{"message": "success"}`, rw.Body.String())
 }
 
-func TestGetValidationError(t *testing.T) {
-	t.Run("Required", func(t *testing.T) {
-		testPayload := TestPayload{}
-		err := Validate.Struct(testPayload)
-		assert.Error(t, err)
+func TestWriteLog(t *testing.T) {
+	t.Run("should log details under category", func(t *testing.T) {
+		var logBuffer bytes.Buffer
+		logger := NewLogger(&logBuffer, LogFormatJSON, slog.LevelInfo)
+
+		WriteLog(logger, "db", map[string]string{"query": "SELECT 1"})
 
-		validationErrors := GetValidationError(err)
-		assert.Contains(t, validationErrors, "Name")
-		assert.Contains(t, validationErrors, "Email")
-		assert.Equal(t, "'Name' is required", validationErrors["Name"])
-		assert.Equal(t, "'Email' is required", validationErrors["Email"])
+		assert.JSONEq(t, `{"level": "INFO", "msg": "db", "details": {"query": "SELECT 1"}}`, withoutTime(t, logBuffer.Bytes()))
 	})
 
-	t.Run("Invalid", func(t *testing.T) {
-		testPayload := TestPayload{Name: "John", Email: "invalid-email"}
-		err := Validate.Struct(testPayload)
-		assert.Error(t, err)
+	t.Run("should log request ID from context", func(t *testing.T) {
+		var logBuffer bytes.Buffer
+		logger := NewLogger(&logBuffer, LogFormatJSON, slog.LevelInfo)
+		ctx := WithRequestID(context.Background(), "req-1")
 
-		msg := GetValidationError(err)
-		assert.Contains(t, msg, "Email")
-		assert.Equal(t, "'Email' is invalid", msg["Email"])
+		WriteLogContext(ctx, logger, "db", "query failed")
+
+		assert.JSONEq(t, `{"level": "INFO", "msg": "db", "details": "query failed", "requestId": "req-1"}`, withoutTime(t, logBuffer.Bytes()))
 	})
 
-	t.Run("Minimum Length", func(t *testing.T) {
-		testPayload := TestPayload{Name: "Jo", Email: "alice.doe@example.com"}
-		err := Validate.Struct(testPayload)
-		assert.Error(t, err)
+	t.Run("should log without details", func(t *testing.T) {
+		var logBuffer bytes.Buffer
+		logger := NewLogger(&logBuffer, LogFormatJSON, slog.LevelInfo)
 
-		msg := GetValidationError(err)
-		assert.Contains(t, msg, "Name")
-		assert.Equal(t, "'Name' is less than the required minimum length", msg["Name"])
+		WriteLogContext(context.Background(), logger, "db", nil)
+
+		assert.JSONEq(t, `{"level": "INFO", "msg": "db"}`, withoutTime(t, logBuffer.Bytes()))
 	})
 
-	t.Run("Maximum Length", func(t *testing.T) {
-		testPayload := TestPayload{Name: "TestJohnDoe", Email: "alice.doe@example.com"}
-		err := Validate.Struct(testPayload)
-		assert.Error(t, err)
+	t.Run("should log at the given level", func(t *testing.T) {
+		var logBuffer bytes.Buffer
+		logger := NewLogger(&logBuffer, LogFormatJSON, slog.LevelWarn)
+
+		WriteLog(logger, "db", "ignored")
+		WriteLogLevel(context.Background(), logger, slog.LevelError, "db", "connection lost")
 
-		msg := GetValidationError(err)
-		assert.Contains(t, msg, "Name")
-		assert.Equal(t, "'Name' is greater than the required maximum length", msg["Name"])
+		assert.JSONEq(t, `{"level": "ERROR", "msg": "db", "details": "connection lost"}`, withoutTime(t, logBuffer.Bytes()))
 	})
+}
 
-	t.Run("Unknown Error", func(t *testing.T) {
-		testPayload := TestPayload{Name: "John", Email: "john.doe@example.com", Age: 4}
-		err := Validate.Struct(testPayload)
-		assert.Error(t, err)
+// withoutTime decodes a single JSON log record and drops its timestamp.
+func withoutTime(t *testing.T, record []byte) string {
+	t.Helper()
 
-		msg := GetValidationError(err)
-		assert.Contains(t, msg, "Age")
-		assert.Equal(t, "Key: 'TestPayload.Age' Error:Field validation for 'Age' failed on the 'gte' tag", msg["Age"])
-	})
+	var fields map[string]any
+	assert.NoError(t, json.Unmarshal(record, &fields))
+	delete(fields, slog.TimeKey)
+
+	out, err := json.Marshal(fields)
+	assert.NoError(t, err)
+	return string(out)
+}
+
+func TestRequestIDFromContext(t *testing.T) {
+	assert.Equal(t, "", RequestIDFromContext(context.Background()))
+	assert.Equal(t, "req-1", RequestIDFromContext(WithRequestID(context.Background(), "req-1")))
 }
@@ -0,0 +1,320 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+)
+
+// embeddedField names embedded structs in validation namespaces, so that
+// they can be left out of field paths as encoding/json flattens them.
+const embeddedField = "<embedded>"
+
+// bindErrorKey is the catalog key of the message of BindErrors. Its
+// parameters are the source and the name of the parameter.
+const bindErrorKey = "invalid {0} parameter '{1}'"
+
+// Validate validates payloads. Field errors are named by the json tag of
+// the field, or by its query, path, header or form tag for Bind.
+var Validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New()
+	v.RegisterTagNameFunc(fieldName)
+	return v
+}
+
+func fieldName(field reflect.StructField) string {
+	for _, tag := range []string{"json", "query", "path", "header", "form"} {
+		name, _, _ := strings.Cut(field.Tag.Get(tag), ",")
+		if name == "-" {
+			return ""
+		}
+		if name != "" {
+			return name
+		}
+	}
+	if field.Anonymous {
+		return embeddedField
+	}
+	return ""
+}
+
+// ValidationError describes a field that failed validation.
+type ValidationError struct {
+	// Code is the failed validator tag, such as "min", or "type" for values
+	// that Bind could not convert.
+	Code string `json:"code"`
+	// Field is the path of the field as clients send it, such as
+	// "items[2].price".
+	Field   string `json:"field"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+// GetValidationError describes the fields of validator.ValidationErrors or
+// BindErrors. It returns nil for other errors.
+func GetValidationError(err error) []ValidationError {
+	return GetLocalizedValidationError(err, nil)
+}
+
+// GetLocalizedValidationError is like GetValidationError but with messages
+// translated by trans, as set up by a Catalog. Messages that trans cannot
+// translate are in English.
+func GetLocalizedValidationError(err error, trans ut.Translator) []ValidationError {
+	var bindErrs BindErrors
+	if errors.As(err, &bindErrs) {
+		validationErrors := make([]ValidationError, 0, len(bindErrs))
+		for _, fe := range bindErrs {
+			message := fe.Error()
+			if trans != nil {
+				if translated, err := trans.T(bindErrorKey, fe.Source, fe.Name); err == nil {
+					message = translated
+				}
+			}
+			validationErrors = append(validationErrors, ValidationError{
+				Code:    "type",
+				Field:   fe.Name,
+				Message: message,
+			})
+		}
+		return validationErrors
+	}
+
+	var validationErrs validator.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		return nil
+	}
+	validationErrors := make([]ValidationError, 0, len(validationErrs))
+	for _, fe := range validationErrs {
+		field := fieldPath(fe)
+		message := getValidationMessage(fe, field)
+		if trans != nil {
+			if vt := validationTranslator(trans); vt != nil {
+				// Translate returns fe.Error() for tags without a translation.
+				if translated := fe.Translate(vt); translated != fe.Error() {
+					message = translated
+				}
+			}
+		}
+		validationErrors = append(validationErrors, ValidationError{
+			Code:    fe.Tag(),
+			Field:   field,
+			Param:   fe.Param(),
+			Message: message,
+		})
+	}
+	return validationErrors
+}
+
+// fieldPath returns the namespace of fe without the name of the validated
+// type and without embedded structs.
+func fieldPath(fe validator.FieldError) string {
+	segments := splitNamespace(fe.Namespace())
+	if len(segments) > 1 {
+		segments = segments[1:]
+	}
+
+	path := make([]string, 0, len(segments))
+	for _, segment := range segments {
+		if segment != embeddedField {
+			path = append(path, segment)
+		}
+	}
+	return strings.Join(path, ".")
+}
+
+// splitNamespace splits ns at the dots outside of brackets, which keeps map
+// keys containing dots in one segment.
+func splitNamespace(ns string) []string {
+	var segments []string
+	depth, start := 0, 0
+	for i := 0; i < len(ns); i++ {
+		switch ns[i] {
+		case '[':
+			depth++
+		case ']':
+			depth = max(0, depth-1)
+		case '.':
+			if depth == 0 {
+				segments = append(segments, ns[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(segments, ns[start:])
+}
+
+func getValidationMessage(fe validator.FieldError, field string) string {
+	if rule := validationRule(fe); rule != "" {
+		return fmt.Sprintf("'%s' %s", field, rule)
+	}
+	if fe.Param() != "" {
+		return fmt.Sprintf("'%s' failed the '%s=%s' validation", field, fe.Tag(), fe.Param())
+	}
+	return fmt.Sprintf("'%s' failed the '%s' validation", field, fe.Tag())
+}
+
+// validationRule describes the rule of a validator tag, or returns "" for
+// tags without a description.
+func validationRule(fe validator.FieldError) string {
+	param := fe.Param()
+	switch fe.Tag() {
+	case "required", "required_if", "required_unless", "required_with", "required_with_all",
+		"required_without", "required_without_all":
+		return "is required"
+	case "excluded_if", "excluded_unless", "excluded_with", "excluded_with_all",
+		"excluded_without", "excluded_without_all":
+		return "must not be set"
+	case "min", "gte":
+		if param == "" {
+			break
+		}
+		return "must be at least " + quantity(fe, param)
+	case "max", "lte":
+		if param == "" {
+			break
+		}
+		return "must be at most " + quantity(fe, param)
+	case "len":
+		return "must be exactly " + quantity(fe, param)
+	case "gt":
+		if param == "" {
+			break
+		}
+		if kind := fe.Kind(); kind == reflect.String || kind == reflect.Slice || kind == reflect.Array || kind == reflect.Map {
+			return "must be longer than " + quantity(fe, param)
+		}
+		return "must be greater than " + param
+	case "lt":
+		if param == "" {
+			break
+		}
+		if kind := fe.Kind(); kind == reflect.String || kind == reflect.Slice || kind == reflect.Array || kind == reflect.Map {
+			return "must be shorter than " + quantity(fe, param)
+		}
+		return "must be less than " + param
+	case "eq":
+		return "must be equal to " + param
+	case "ne":
+		return "must not be equal to " + param
+	case "oneof":
+		return "must be one of " + strings.Join(strings.Fields(param), ", ")
+	case "eqfield":
+		return "must be equal to " + param
+	case "nefield":
+		return "must not be equal to " + param
+	case "gtfield":
+		return "must be greater than " + param
+	case "gtefield":
+		return "must be greater than or equal to " + param
+	case "ltfield":
+		return "must be less than " + param
+	case "ltefield":
+		return "must be less than or equal to " + param
+	case "unique":
+		return "must contain unique values"
+	case "email":
+		return "must be a valid email address"
+	case "url", "http_url", "uri":
+		return "must be a valid URL"
+	case "uuid", "uuid3", "uuid4", "uuid5":
+		return "must be a valid UUID"
+	case "ip", "ipv4", "ipv6":
+		return "must be a valid IP address"
+	case "cidr":
+		return "must be a valid CIDR notation"
+	case "hostname", "hostname_rfc1123", "fqdn":
+		return "must be a valid hostname"
+	case "e164":
+		return "must be a valid E.164 phone number"
+	case "datetime":
+		return "must be a date in the format " + param
+	case "timezone":
+		return "must be a valid time zone"
+	case "iso3166_1_alpha2", "iso3166_1_alpha3":
+		return "must be a valid country code"
+	case "iso4217":
+		return "must be a valid currency code"
+	case "bcp47_language_tag":
+		return "must be a valid language tag"
+	case "latitude":
+		return "must be a valid latitude"
+	case "longitude":
+		return "must be a valid longitude"
+	case "alpha":
+		return "must contain only letters"
+	case "alphanum":
+		return "must contain only letters and numbers"
+	case "alphaunicode":
+		return "must contain only unicode letters"
+	case "alphanumunicode":
+		return "must contain only unicode letters and numbers"
+	case "ascii":
+		return "must contain only ASCII characters"
+	case "numeric":
+		return "must be a numeric value"
+	case "number":
+		return "must be a number"
+	case "boolean":
+		return "must be a boolean"
+	case "hexadecimal":
+		return "must be a hexadecimal value"
+	case "hexcolor", "rgb", "rgba", "hsl", "hsla", "iscolor":
+		return "must be a valid color"
+	case "lowercase":
+		return "must be lowercase"
+	case "uppercase":
+		return "must be uppercase"
+	case "json":
+		return "must be valid JSON"
+	case "jwt":
+		return "must be a valid JWT"
+	case "base64", "base64url", "base64rawurl":
+		return "must be valid base64"
+	case "semver":
+		return "must be a valid semantic version"
+	case "contains":
+		return fmt.Sprintf("must contain '%s'", param)
+	case "containsany":
+		return fmt.Sprintf("must contain at least one of the characters '%s'", param)
+	case "excludes":
+		return fmt.Sprintf("must not contain '%s'", param)
+	case "excludesall":
+		return fmt.Sprintf("must not contain any of the characters '%s'", param)
+	case "startswith":
+		return fmt.Sprintf("must start with '%s'", param)
+	case "endswith":
+		return fmt.Sprintf("must end with '%s'", param)
+	case "startsnotwith":
+		return fmt.Sprintf("must not start with '%s'", param)
+	case "endsnotwith":
+		return fmt.Sprintf("must not end with '%s'", param)
+	}
+	return ""
+}
+
+// quantity describes param as a length for strings and collections, and as
+// a value otherwise.
+func quantity(fe validator.FieldError, param string) string {
+	unit := ""
+	switch fe.Kind() {
+	case reflect.String:
+		unit = "characters"
+		if param == "1" {
+			unit = "character"
+		}
+	case reflect.Slice, reflect.Array, reflect.Map:
+		unit = "items"
+		if param == "1" {
+			unit = "item"
+		}
+	default:
+		return param
+	}
+	return param + " " + unit
+}
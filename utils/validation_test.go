@@ -0,0 +1,117 @@
+package utils
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type orderItem struct {
+	SKU   string  `json:"sku" validate:"required,len=8"`
+	Price float64 `json:"price" validate:"gt=0"`
+}
+
+type audit struct {
+	CreatedBy string `json:"createdBy" validate:"required"`
+}
+
+type orderRequest struct {
+	audit
+	ID       int               `path:"id" validate:"gte=1"`
+	Customer string            `json:"customer" validate:"required,min=3,max=10"`
+	Email    string            `json:"email,omitempty" validate:"omitempty,email"`
+	Status   string            `json:"status" validate:"oneof=open closed"`
+	Tags     []string          `json:"tags" validate:"max=2,dive,alphanum"`
+	Items    []orderItem       `json:"items" validate:"min=1,dive"`
+	Notes    map[string]string `json:"notes" validate:"dive,keys,uppercase,endkeys,required"`
+	Internal string            `json:"-" validate:"required"`
+	Country  string            `validate:"omitempty,iso3166_1_alpha2"`
+	Code     string            `json:"code" validate:"omitempty,startswith=ord_"`
+	Ref      string            `json:"ref" validate:"omitempty,isbn"`
+}
+
+func TestGetValidationError(t *testing.T) {
+	t.Run("should describe fields by their json paths", func(t *testing.T) {
+		err := Validate.Struct(orderRequest{
+			Customer: "Jo",
+			Email:    "jo@",
+			Status:   "lost",
+			Tags:     []string{"a", "b", "c!"},
+			Items:    []orderItem{{SKU: "ABCDEFGH", Price: 1}, {SKU: "ABCDEFGH", Price: 2}, {SKU: "ABC", Price: 0}},
+			Notes:    map[string]string{"A": ""},
+			Country:  "Germany",
+			Code:     "x",
+			Ref:      "123",
+		})
+
+		assert.Equal(t, []ValidationError{
+			{Code: "required", Field: "createdBy", Message: "'createdBy' is required"},
+			{Code: "gte", Field: "id", Param: "1", Message: "'id' must be at least 1"},
+			{Code: "min", Field: "customer", Param: "3", Message: "'customer' must be at least 3 characters"},
+			{Code: "email", Field: "email", Message: "'email' must be a valid email address"},
+			{Code: "oneof", Field: "status", Param: "open closed", Message: "'status' must be one of open, closed"},
+			{Code: "max", Field: "tags", Param: "2", Message: "'tags' must be at most 2 items"},
+			{Code: "len", Field: "items[2].sku", Param: "8", Message: "'items[2].sku' must be exactly 8 characters"},
+			{Code: "gt", Field: "items[2].price", Param: "0", Message: "'items[2].price' must be greater than 0"},
+			{Code: "required", Field: "notes[A]", Message: "'notes[A]' is required"},
+			{Code: "required", Field: "Internal", Message: "'Internal' is required"},
+			{Code: "iso3166_1_alpha2", Field: "Country", Message: "'Country' must be a valid country code"},
+			{Code: "startswith", Field: "code", Param: "ord_", Message: "'code' must start with 'ord_'"},
+			{Code: "isbn", Field: "ref", Message: "'ref' failed the 'isbn' validation"},
+		}, GetValidationError(err))
+	})
+
+	t.Run("should describe slice elements", func(t *testing.T) {
+		err := Validate.Struct(orderRequest{
+			audit:    audit{CreatedBy: "admin"},
+			ID:       1,
+			Customer: "Jonathan",
+			Status:   "open",
+			Tags:     []string{"ok", "no!"},
+			Items:    []orderItem{{SKU: "ABCDEFGH", Price: 1}},
+			Internal: "x",
+		})
+
+		assert.Equal(t, []ValidationError{
+			{Code: "alphanum", Field: "tags[1]", Message: "'tags[1]' must contain only letters and numbers"},
+		}, GetValidationError(err))
+	})
+
+	t.Run("should keep dots in names and map keys", func(t *testing.T) {
+		type settings struct {
+			Limits map[string]int `json:"limits" validate:"dive,gte=0"`
+			Locale string         `json:"ui.locale" validate:"required"`
+		}
+		type request struct {
+			Settings settings `json:"app.settings"`
+		}
+
+		err := Validate.Struct(request{Settings: settings{Limits: map[string]int{"api.calls": -1}}})
+
+		assert.ElementsMatch(t, []ValidationError{
+			{Code: "gte", Field: "app.settings.limits[api.calls]", Param: "0", Message: "'app.settings.limits[api.calls]' must be at least 0"},
+			{Code: "required", Field: "app.settings.ui.locale", Message: "'app.settings.ui.locale' is required"},
+		}, GetValidationError(err))
+
+		err = Validate.Var(map[string]int{"api.calls": -1}, "dive,gte=0")
+
+		assert.Equal(t, []ValidationError{
+			{Code: "gte", Field: "[api.calls]", Param: "0", Message: "'[api.calls]' must be at least 0"},
+		}, GetValidationError(err))
+	})
+
+	t.Run("should describe variables", func(t *testing.T) {
+		err := Validate.Var("ab", "min=3")
+
+		assert.Equal(t, []ValidationError{
+			{Code: "min", Param: "3", Message: "'' must be at least 3 characters"},
+		}, GetValidationError(err))
+	})
+
+	t.Run("should return nil for other errors", func(t *testing.T) {
+		assert.Nil(t, GetValidationError(errors.New("boom")))
+		assert.Nil(t, GetValidationError(Validate.Struct(nil)))
+		assert.Nil(t, GetValidationError(nil))
+	})
+}